@@ -0,0 +1,320 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// --------------- ENUMERATIONS ---------------
+type Kind int
+
+const (
+	KindNotFound Kind = iota + 1
+	KindInvalidArgument
+	KindConflict
+	KindUnauthorized
+	KindInternal
+	UnknownKind
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindNotFound:
+		return "NOT_FOUND"
+	case KindInvalidArgument:
+		return "INVALID_ARGUMENT"
+	case KindConflict:
+		return "CONFLICT"
+	case KindUnauthorized:
+		return "UNAUTHORIZED"
+	case KindInternal:
+		return "INTERNAL"
+	default:
+		return ""
+	}
+}
+
+// GRPCCode returns the gRPC status code the kind is reported with.
+func (k Kind) GRPCCode() codes.Code {
+	switch k {
+	case KindNotFound:
+		return codes.NotFound
+	case KindInvalidArgument:
+		return codes.InvalidArgument
+	case KindConflict:
+		return codes.AlreadyExists
+	case KindUnauthorized:
+		return codes.Unauthenticated
+	default:
+		return codes.Internal
+	}
+}
+
+// IsClientError reports whether the kind is caused by the caller rather than the service.
+func (k Kind) IsClientError() bool {
+	switch k {
+	case KindNotFound, KindInvalidArgument, KindConflict, KindUnauthorized:
+		return true
+	default:
+		return false
+	}
+}
+
+// Domain is reported in the ErrorInfo detail of every error. Services may override it on startup.
+var Domain = "atlas"
+
+type FieldViolation struct {
+	Field       string `json:"field"`
+	Description string `json:"description"`
+}
+
+// Error is a typed domain error. Message is safe to show to end users, while the
+// wrapped cause is only used for logging and never leaves the service.
+type Error struct {
+	kind       Kind
+	code       string
+	message    string
+	details    map[string]string
+	violations []FieldViolation
+	retryAfter time.Duration
+	cause      error
+}
+
+func newError(kind Kind, code, msg string, args ...any) *Error {
+	if len(args) > 0 {
+		msg = fmt.Sprintf(msg, args...)
+	}
+	return &Error{
+		kind:    kind,
+		code:    code,
+		message: msg,
+	}
+}
+
+func NotFound(code, msg string, args ...any) *Error {
+	return newError(KindNotFound, code, msg, args...)
+}
+
+func InvalidArgument(code, msg string, args ...any) *Error {
+	return newError(KindInvalidArgument, code, msg, args...)
+}
+
+func Conflict(code, msg string, args ...any) *Error {
+	return newError(KindConflict, code, msg, args...)
+}
+
+func Unauthorized(code, msg string, args ...any) *Error {
+	return newError(KindUnauthorized, code, msg, args...)
+}
+
+func Internal(code, msg string, args ...any) *Error {
+	return newError(KindInternal, code, msg, args...)
+}
+
+func (e *Error) Kind() Kind {
+	return e.kind
+}
+
+func (e *Error) Code() string {
+	return e.code
+}
+
+func (e *Error) Message() string {
+	return e.message
+}
+
+func (e *Error) Details() map[string]string {
+	return maps.Clone(e.details)
+}
+
+func (e *Error) Violations() []FieldViolation {
+	return append([]FieldViolation(nil), e.violations...)
+}
+
+func (e *Error) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
+// clone returns a copy of e, so the builders leave sentinel errors untouched.
+func (e *Error) clone() *Error {
+	c := *e
+	c.details = maps.Clone(e.details)
+	c.violations = slices.Clone(e.violations)
+	return &c
+}
+
+// WithDetail returns a copy of the error with the detail added, as the other builders do.
+func (e *Error) WithDetail(key, value string) *Error {
+	c := e.clone()
+	if c.details == nil {
+		c.details = make(map[string]string)
+	}
+	c.details[key] = value
+	return c
+}
+
+func (e *Error) WithDetails(details map[string]string) *Error {
+	c := e.clone()
+	if c.details == nil {
+		c.details = make(map[string]string, len(details))
+	}
+	maps.Copy(c.details, details)
+	return c
+}
+
+func (e *Error) WithViolation(field, description string) *Error {
+	c := e.clone()
+	c.violations = append(c.violations, FieldViolation{Field: field, Description: description})
+	return c
+}
+
+func (e *Error) WithRetryAfter(d time.Duration) *Error {
+	c := e.clone()
+	c.retryAfter = d
+	return c
+}
+
+// Wrap returns a copy of the error with the underlying cause. The cause is part of Error()
+// for logs but is never sent to clients.
+func (e *Error) Wrap(cause error) *Error {
+	c := e.clone()
+	c.cause = cause
+	return c
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%s: %s", e.kind.String(), e.message)
+	if e.code != "" {
+		msg = fmt.Sprintf("%s [%s]: %s", e.kind.String(), e.code, e.message)
+	}
+	if e.cause != nil {
+		msg = fmt.Sprintf("%s: %s", msg, e.cause.Error())
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is matches target itself, or errors of its kind and code when it has a code, so sentinel
+// errors can be compared with errors.Is after being extended by the builders.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	if e == t {
+		return true
+	}
+	return t.code != "" && e.kind == t.kind && e.code == t.code
+}
+
+// GRPCStatus makes the error understood by status.FromError and therefore by grpc
+// servers, which send it to clients together with its error details.
+func (e *Error) GRPCStatus() *status.Status {
+	st := status.New(e.kind.GRPCCode(), e.message)
+
+	var details []protoadapt.MessageV1
+	if e.code != "" || len(e.details) > 0 {
+		details = append(details, &errdetails.ErrorInfo{
+			Reason:   e.code,
+			Domain:   Domain,
+			Metadata: maps.Clone(e.details),
+		})
+	}
+	if len(e.violations) > 0 {
+		badRequest := &errdetails.BadRequest{}
+		for _, v := range e.violations {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       v.Field,
+				Description: v.Description,
+			})
+		}
+		details = append(details, badRequest)
+	}
+	if e.retryAfter > 0 {
+		details = append(details, &errdetails.RetryInfo{
+			RetryDelay: durationpb.New(e.retryAfter),
+		})
+	}
+
+	if len(details) == 0 {
+		return st
+	}
+
+	withDetails, err := st.WithDetails(details...)
+	if err != nil {
+		return st
+	}
+	return withDetails
+}
+
+// As returns the first *Error in err's chain.
+func As(err error) (*Error, bool) {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr, true
+	}
+	return nil, false
+}
+
+// KindOf returns the kind of the first *Error in err's chain, or KindInternal when there is none.
+func KindOf(err error) Kind {
+	if appErr, ok := As(err); ok {
+		return appErr.kind
+	}
+	return KindInternal
+}
+
+// FromStatus rebuilds an *Error from a gRPC status, reading back the error details
+// written by GRPCStatus. It is used on the client side of a gRPC call, e.g. by the
+// HTTP gateway.
+func FromStatus(st *status.Status) *Error {
+	e := &Error{
+		kind:    kindFromGRPCCode(st.Code()),
+		message: st.Message(),
+	}
+
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.ErrorInfo:
+			e.code = d.GetReason()
+			if len(d.GetMetadata()) > 0 {
+				e.details = maps.Clone(d.GetMetadata())
+			}
+		case *errdetails.BadRequest:
+			for _, v := range d.GetFieldViolations() {
+				e.violations = append(e.violations, FieldViolation{Field: v.GetField(), Description: v.GetDescription()})
+			}
+		case *errdetails.RetryInfo:
+			e.retryAfter = d.GetRetryDelay().AsDuration()
+		}
+	}
+
+	return e
+}
+
+func kindFromGRPCCode(code codes.Code) Kind {
+	switch code {
+	case codes.NotFound:
+		return KindNotFound
+	case codes.InvalidArgument, codes.OutOfRange, codes.FailedPrecondition:
+		return KindInvalidArgument
+	case codes.AlreadyExists, codes.Aborted:
+		return KindConflict
+	case codes.Unauthenticated, codes.PermissionDenied:
+		return KindUnauthorized
+	default:
+		return KindInternal
+	}
+}
@@ -0,0 +1,201 @@
+package apperror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestError_GRPCStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      *Error
+		wantCode codes.Code
+	}{
+		{name: "not found", err: NotFound("USER_NOT_FOUND", "user %d not found", 1), wantCode: codes.NotFound},
+		{name: "invalid argument", err: InvalidArgument("INVALID_EMAIL", "email is invalid"), wantCode: codes.InvalidArgument},
+		{name: "conflict", err: Conflict("USER_EXISTS", "user already exists"), wantCode: codes.AlreadyExists},
+		{name: "unauthorized", err: Unauthorized("TOKEN_EXPIRED", "token expired"), wantCode: codes.Unauthenticated},
+		{name: "internal", err: Internal("DB_DOWN", "something went wrong"), wantCode: codes.Internal},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			st, ok := status.FromError(tc.err)
+			require.True(t, ok)
+			assert.Equal(t, tc.wantCode, st.Code())
+			assert.Equal(t, tc.err.Message(), st.Message())
+
+			wrapped, ok := status.FromError(fmt.Errorf("handler failed: %w", tc.err))
+			require.True(t, ok)
+			assert.Equal(t, tc.wantCode, wrapped.Code())
+		})
+	}
+}
+
+func TestError_Details(t *testing.T) {
+	err := InvalidArgument("INVALID_USER", "user is invalid").
+		WithDetail("user_id", "42").
+		WithViolation("email", "must be a valid email").
+		WithViolation("age", "must be positive").
+		WithRetryAfter(2 * time.Second)
+
+	st := err.GRPCStatus()
+	require.Len(t, st.Details(), 3)
+
+	info, ok := st.Details()[0].(*errdetails.ErrorInfo)
+	require.True(t, ok)
+	assert.Equal(t, "INVALID_USER", info.GetReason())
+	assert.Equal(t, Domain, info.GetDomain())
+	assert.Equal(t, map[string]string{"user_id": "42"}, info.GetMetadata())
+
+	badRequest, ok := st.Details()[1].(*errdetails.BadRequest)
+	require.True(t, ok)
+	assert.Len(t, badRequest.GetFieldViolations(), 2)
+
+	retry, ok := st.Details()[2].(*errdetails.RetryInfo)
+	require.True(t, ok)
+	assert.Equal(t, 2*time.Second, retry.GetRetryDelay().AsDuration())
+
+	rebuilt := FromStatus(st)
+	assert.Equal(t, err.Kind(), rebuilt.Kind())
+	assert.Equal(t, err.Code(), rebuilt.Code())
+	assert.Equal(t, err.Message(), rebuilt.Message())
+	assert.Equal(t, err.Details(), rebuilt.Details())
+	assert.Equal(t, err.Violations(), rebuilt.Violations())
+	assert.Equal(t, err.RetryAfter(), rebuilt.RetryAfter())
+}
+
+func TestError_Unwrap(t *testing.T) {
+	errUserNotFound := NotFound("USER_NOT_FOUND", "user not found")
+	cause := assert.AnError
+
+	err := fmt.Errorf("get user: %w", NotFound("USER_NOT_FOUND", "user not found").Wrap(cause))
+
+	assert.ErrorIs(t, err, errUserNotFound)
+	assert.ErrorIs(t, err, cause)
+	assert.Equal(t, KindNotFound, KindOf(err))
+	assert.Equal(t, KindInternal, KindOf(cause))
+	assert.Contains(t, err.Error(), cause.Error())
+}
+
+func TestError_BuildersCopy(t *testing.T) {
+	errUserNotFound := NotFound("USER_NOT_FOUND", "user not found").WithDetail("source", "db")
+
+	err := errUserNotFound.
+		WithDetail("user_id", "42").
+		WithDetails(map[string]string{"tenant": "acme"}).
+		WithViolation("id", "must exist").
+		WithRetryAfter(time.Second).
+		Wrap(assert.AnError)
+
+	assert.Equal(t, map[string]string{"source": "db"}, errUserNotFound.Details())
+	assert.Empty(t, errUserNotFound.Violations())
+	assert.Zero(t, errUserNotFound.RetryAfter())
+	assert.NoError(t, errUserNotFound.Unwrap())
+
+	assert.Equal(t, map[string]string{"source": "db", "user_id": "42", "tenant": "acme"}, err.Details())
+	assert.Len(t, err.Violations(), 1)
+	assert.ErrorIs(t, err, errUserNotFound)
+}
+
+func TestError_Is(t *testing.T) {
+	errUnexpected := Internal("", "unexpected error")
+
+	testCases := []struct {
+		name   string
+		err    error
+		target error
+		is     bool
+	}{
+		{name: "same code", err: NotFound("USER_NOT_FOUND", "user 42 not found"), target: NotFound("USER_NOT_FOUND", "user not found"), is: true},
+		{name: "other code", err: NotFound("ORDER_NOT_FOUND", "order not found"), target: NotFound("USER_NOT_FOUND", "user not found")},
+		{name: "other kind", err: Conflict("USER_NOT_FOUND", "user not found"), target: NotFound("USER_NOT_FOUND", "user not found")},
+		{name: "same instance without code", err: errUnexpected, target: errUnexpected, is: true},
+		{name: "other instance without code", err: Internal("", "database is down"), target: errUnexpected},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.is, errors.Is(tc.err, tc.target))
+		})
+	}
+}
+
+func TestGatewayErrorHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantBody   HTTPError
+		wantHeader map[string]string
+	}{
+		{
+			name:       "app error",
+			err:        InvalidArgument("INVALID_EMAIL", "email is invalid").WithViolation("email", "must be a valid email"),
+			wantStatus: http.StatusBadRequest,
+			wantBody: HTTPError{
+				Status:     http.StatusBadRequest,
+				Kind:       "INVALID_ARGUMENT",
+				Code:       "INVALID_EMAIL",
+				Message:    "email is invalid",
+				Violations: []FieldViolation{{Field: "email", Description: "must be a valid email"}},
+			},
+		},
+		{
+			name:       "status from grpc backend",
+			err:        NotFound("USER_NOT_FOUND", "user not found").WithRetryAfter(1500 * time.Millisecond).GRPCStatus().Err(),
+			wantStatus: http.StatusNotFound,
+			wantBody: HTTPError{
+				Status:  http.StatusNotFound,
+				Kind:    "NOT_FOUND",
+				Code:    "USER_NOT_FOUND",
+				Message: "user not found",
+			},
+			wantHeader: map[string]string{"Retry-After": "2"},
+		},
+		{
+			name:       "plain grpc status",
+			err:        status.Error(codes.Unavailable, "backend unavailable"),
+			wantStatus: http.StatusServiceUnavailable,
+			wantBody: HTTPError{
+				Status:  http.StatusServiceUnavailable,
+				Kind:    "INTERNAL",
+				Message: "backend unavailable",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/v1/users/1", nil)
+
+			GatewayErrorHandler(context.Background(), runtime.NewServeMux(), &runtime.JSONPb{}, rec, req, tc.err)
+
+			assert.Equal(t, tc.wantStatus, rec.Code)
+			for key, value := range tc.wantHeader {
+				assert.Equal(t, value, rec.Header().Get(key))
+			}
+
+			var body HTTPErrorBody
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+			assert.Equal(t, tc.wantBody, body.Error)
+		})
+	}
+}
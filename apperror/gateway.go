@@ -0,0 +1,100 @@
+package apperror
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/grpclog"
+	"google.golang.org/grpc/status"
+)
+
+type HTTPErrorBody struct {
+	Error HTTPError `json:"error"`
+}
+
+type HTTPError struct {
+	Status     int               `json:"status"`
+	Kind       string            `json:"kind"`
+	Code       string            `json:"code,omitempty"`
+	Message    string            `json:"message"`
+	Details    map[string]string `json:"details,omitempty"`
+	Violations []FieldViolation  `json:"violations,omitempty"`
+}
+
+// HTTPStatus returns the HTTP status code the error is reported with by the gateway.
+func (e *Error) HTTPStatus() int {
+	return runtime.HTTPStatusFromCode(e.kind.GRPCCode())
+}
+
+// HTTPBody returns the JSON body written by GatewayErrorHandler.
+func (e *Error) HTTPBody() HTTPErrorBody {
+	return HTTPErrorBody{
+		Error: HTTPError{
+			Status:     e.HTTPStatus(),
+			Kind:       e.kind.String(),
+			Code:       e.code,
+			Message:    e.message,
+			Details:    e.Details(),
+			Violations: e.Violations(),
+		},
+	}
+}
+
+// GatewayErrorHandler is a runtime.ErrorHandlerFunc that renders errors returned by the
+// gRPC backend as HTTPErrorBody, using the error details attached by Error.GRPCStatus.
+func GatewayErrorHandler(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	const fallback = `{"error": {"status": 500, "kind": "INTERNAL", "message": "failed to marshal error message"}}`
+
+	var customStatus *runtime.HTTPStatusError
+	if errors.As(err, &customStatus) {
+		err = customStatus.Err
+	}
+
+	appErr, ok := As(err)
+	var httpStatus int
+	if ok {
+		httpStatus = appErr.HTTPStatus()
+	} else {
+		st := status.Convert(err)
+		appErr = FromStatus(st)
+		httpStatus = runtime.HTTPStatusFromCode(st.Code())
+	}
+
+	if customStatus != nil {
+		httpStatus = customStatus.HTTPStatus
+	}
+
+	body := appErr.HTTPBody()
+	body.Error.Status = httpStatus
+
+	w.Header().Del("Trailer")
+	w.Header().Del("Transfer-Encoding")
+	w.Header().Set("Content-Type", marshaler.ContentType(body))
+
+	if httpStatus == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", appErr.message)
+	}
+	if appErr.retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(appErr.retryAfter.Seconds()))))
+	}
+
+	buf, merr := marshaler.Marshal(body)
+	if merr != nil {
+		grpclog.Errorf("Failed to marshal error message %q: %v", appErr.message, merr)
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := io.WriteString(w, fallback); err != nil {
+			grpclog.Errorf("Failed to write response: %v", err)
+		}
+		return
+	}
+
+	w.WriteHeader(httpStatus)
+	if _, err := w.Write(buf); err != nil {
+		grpclog.Errorf("Failed to write response: %v", err)
+	}
+}
@@ -4,12 +4,15 @@ import (
 	"errors"
 	"fmt"
 	"time"
+
+	"github.com/NusaCrew/atlas-go/log"
 )
 
 type AppConfig struct {
 	Environment         string `env:"ENVIRONMENT,required"`
 	ServiceName         string `env:"SERVICE_NAME" envDefault:""`
 	EnableLoadingSecret bool   `env:"ENABLE_LOADING_SECRET" envDefault:"false"`
+	LogProfile          string `env:"LOG_PROFILE" envDefault:""`
 
 	HTTPPort int    `env:"HTTP_PORT" envDefault:"8080"`
 	GRPCPort int    `env:"GRPC_PORT" envDefault:"8081"`
@@ -24,6 +27,17 @@ type AppConfig struct {
 	SecretManagerConfig
 }
 
+// LogOptions returns the options of the service logger, see log.InitializeWith.
+func (c *AppConfig) LogOptions(level log.Level) log.Options {
+	return log.Options{
+		Level:       level,
+		ServiceName: c.ServiceName,
+		Environment: c.Environment,
+		Profile:     log.Profile(c.LogProfile),
+		ProjectID:   c.Project.ID,
+	}
+}
+
 func (c *AppConfig) IsEnableLoadingSecret() bool {
 	return c.EnableLoadingSecret
 }
@@ -0,0 +1,671 @@
+package event_observer
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/NusaCrew/atlas-go/log"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// --------------- ENUMERATIONS ---------------
+
+// CloudEventMode is how a CloudEvent is carried by a message, see the protocol bindings of
+// the CloudEvents specification.
+type CloudEventMode int
+
+const (
+	CloudEventStructured CloudEventMode = iota + 1 // the whole event as JSON in the payload
+	CloudEventBinary                               // the data in the payload, the attributes in headers
+)
+
+func (m CloudEventMode) String() string {
+	switch m {
+	case CloudEventStructured:
+		return "STRUCTURED"
+	case CloudEventBinary:
+		return "BINARY"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// --------------- CLOUDEVENTS ---------------
+
+const (
+	CloudEventsSpecVersion = "1.0"
+	// CloudEventsContentType is the content type of events in structured mode.
+	CloudEventsContentType = "application/cloudevents+json"
+
+	// Metadata keys of the optional CloudEvents attributes Event has no field for.
+	CloudEventSubjectKey         = "subject"
+	CloudEventDataSchemaKey      = "dataschema"
+	CloudEventDataContentTypeKey = "datacontenttype"
+
+	// Extensions carrying Event fields.
+	correlationIDExtension = "correlationid"
+	partitionKeyExtension  = "partitionkey"
+
+	httpHeaderPrefix  = "ce-"
+	kafkaHeaderPrefix = "ce_"
+)
+
+var ErrInvalidCloudEvent = errors.New("invalid cloud event")
+
+// contextAttributes are the attributes defined by the specification, which extensions
+// cannot be named after.
+var contextAttributes = map[string]bool{
+	"specversion":     true,
+	"id":              true,
+	"source":          true,
+	"type":            true,
+	"datacontenttype": true,
+	"dataschema":      true,
+	"subject":         true,
+	"time":            true,
+	"data":            true,
+	"data_base64":     true,
+}
+
+// CloudEvent is an event in the CloudEvents 1.0 format. It marshals to and from the JSON
+// format of structured mode.
+type CloudEvent struct {
+	ID              string
+	Source          string
+	Type            string
+	Subject         string
+	Time            time.Time
+	DataContentType string
+	DataSchema      string
+	Data            []byte // encoded in DataContentType
+	// Extensions hold strings, booleans and integers as int64.
+	Extensions map[string]any
+}
+
+// isJSON reports whether data of contentType is JSON, which an empty one defaults to.
+func isJSON(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || mediaType == "text/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func validExtensionName(name string) bool {
+	if name == "" || contextAttributes[name] {
+		return false
+	}
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// extensionName lowercases key and strips the characters extension names cannot have, like
+// the underscores of snake case keys.
+func extensionName(key string) string {
+	return strings.Map(func(r rune) rune {
+		r = unicode.ToLower(r)
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return -1
+		}
+		return r
+	}, key)
+}
+
+// extensionValue converts v to a CloudEvents type, integral float64 being the integers of
+// decoded JSON metadata.
+func extensionValue(v any) (any, bool) {
+	switch v := v.(type) {
+	case string, bool, int64:
+		return v, true
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case float64:
+		if v != math.Trunc(v) || math.Abs(v) > math.MaxInt64 {
+			return nil, false
+		}
+		return int64(v), true
+	case time.Time:
+		return v.Format(time.RFC3339Nano), true
+	default:
+		return nil, false
+	}
+}
+
+func (ce *CloudEvent) validate() error {
+	if ce.ID == "" || ce.Source == "" || ce.Type == "" {
+		return fmt.Errorf("%w: id, source and type are required", ErrInvalidCloudEvent)
+	}
+	for name := range ce.Extensions {
+		if !validExtensionName(name) {
+			return fmt.Errorf("%w: extension name %q must be lowercase alphanumeric and not an attribute name", ErrInvalidCloudEvent, name)
+		}
+	}
+	return nil
+}
+
+// ToCloudEvent converts event, with its Topic as type. Key and CorrelationID become the
+// partitionkey and correlationid extensions, and Metadata the other extensions, except for
+// the keys of the optional attributes. Metadata keys are lowercased and stripped of other
+// characters than letters and digits, and the metadata CloudEvents cannot carry is skipped.
+// Data is encoded as Publish does, []byte being kept as is with the application/octet-stream
+// content type.
+func (e *Event) ToCloudEvent() (*CloudEvent, error) {
+	ce := &CloudEvent{
+		ID:         e.ID,
+		Source:     e.Source,
+		Type:       e.Topic,
+		Time:       e.Timestamp,
+		Extensions: make(map[string]any),
+	}
+
+	switch data := e.Data.(type) {
+	case nil:
+	case []byte:
+		ce.Data = data
+		ce.DataContentType = "application/octet-stream"
+	case json.RawMessage:
+		ce.Data = data
+		ce.DataContentType = "application/json"
+	case proto.Message:
+		raw, err := protojson.Marshal(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode data of event %s: %w", e.Topic, err)
+		}
+		ce.Data = raw
+		ce.DataContentType = "application/json"
+	default:
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode data of event %s: %w", e.Topic, err)
+		}
+		ce.Data = raw
+		ce.DataContentType = "application/json"
+	}
+
+	// sorted, so keys sanitized to the same name always keep the same value
+	keys := slices.Sorted(maps.Keys(e.Metadata))
+	for _, key := range keys {
+		value := e.Metadata[key]
+		var ok bool
+		switch key {
+		case CloudEventSubjectKey:
+			ce.Subject, ok = value.(string)
+		case CloudEventDataSchemaKey:
+			ce.DataSchema, ok = value.(string)
+		case CloudEventDataContentTypeKey:
+			var contentType string
+			if contentType, ok = value.(string); ok {
+				ce.DataContentType = contentType
+			}
+		default:
+			name := extensionName(key)
+			if _, taken := ce.Extensions[name]; !validExtensionName(name) || taken {
+				log.Warning("skipped metadata %s of event %s, not a valid cloud event extension name", key, e.Topic)
+				continue
+			}
+			if ce.Extensions[name], ok = extensionValue(value); !ok {
+				delete(ce.Extensions, name)
+			}
+		}
+		if !ok {
+			log.Warning("skipped metadata %s of event %s, cloud events cannot carry %T", key, e.Topic, value)
+		}
+	}
+	if e.Key != "" {
+		ce.Extensions[partitionKeyExtension] = e.Key
+	}
+	if e.CorrelationID != "" {
+		ce.Extensions[correlationIDExtension] = e.CorrelationID
+	}
+
+	if err := ce.validate(); err != nil {
+		return nil, fmt.Errorf("failed to convert event %s: %w", e.Topic, err)
+	}
+	return ce, nil
+}
+
+// FromCloudEvent converts ce back to an Event, the reverse of ToCloudEvent. Data is a
+// json.RawMessage when its content type is JSON, the []byte otherwise. The content type is
+// kept in Metadata only when it is not the one ToCloudEvent gives to such Data.
+func FromCloudEvent(ce *CloudEvent) (*Event, error) {
+	if err := ce.validate(); err != nil {
+		return nil, err
+	}
+
+	event := &Event{
+		ID:        ce.ID,
+		Topic:     ce.Type,
+		Timestamp: ce.Time,
+		Source:    ce.Source,
+	}
+	if ce.Data != nil {
+		if isJSON(ce.DataContentType) {
+			event.Data = json.RawMessage(ce.Data)
+		} else {
+			event.Data = ce.Data
+		}
+	}
+
+	metadata := make(map[string]any)
+	for name, value := range ce.Extensions {
+		switch name {
+		case partitionKeyExtension:
+			event.Key = fmt.Sprint(value)
+		case correlationIDExtension:
+			event.CorrelationID = fmt.Sprint(value)
+		default:
+			metadata[name] = value
+		}
+	}
+	if ce.Subject != "" {
+		metadata[CloudEventSubjectKey] = ce.Subject
+	}
+	if ce.DataSchema != "" {
+		metadata[CloudEventDataSchemaKey] = ce.DataSchema
+	}
+	if ce.DataContentType != "" && ce.DataContentType != defaultDataContentType(event.Data) {
+		metadata[CloudEventDataContentTypeKey] = ce.DataContentType
+	}
+	if len(metadata) > 0 {
+		event.Metadata = metadata
+	}
+	return event, nil
+}
+
+func defaultDataContentType(data any) string {
+	switch data.(type) {
+	case json.RawMessage:
+		return "application/json"
+	case []byte:
+		return "application/octet-stream"
+	default:
+		return ""
+	}
+}
+
+func (ce CloudEvent) MarshalJSON() ([]byte, error) {
+	doc := map[string]any{
+		"specversion": CloudEventsSpecVersion,
+		"id":          ce.ID,
+		"source":      ce.Source,
+		"type":        ce.Type,
+	}
+	for name, value := range ce.Extensions {
+		doc[name] = value
+	}
+	if ce.Subject != "" {
+		doc["subject"] = ce.Subject
+	}
+	if !ce.Time.IsZero() {
+		doc["time"] = ce.Time.Format(time.RFC3339Nano)
+	}
+	if ce.DataContentType != "" {
+		doc["datacontenttype"] = ce.DataContentType
+	}
+	if ce.DataSchema != "" {
+		doc["dataschema"] = ce.DataSchema
+	}
+	if ce.Data != nil {
+		if isJSON(ce.DataContentType) && json.Valid(ce.Data) {
+			doc["data"] = json.RawMessage(ce.Data)
+		} else {
+			doc["data_base64"] = base64.StdEncoding.EncodeToString(ce.Data)
+		}
+	}
+	return json.Marshal(doc)
+}
+
+func (ce *CloudEvent) UnmarshalJSON(payload []byte) error {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &doc); err != nil {
+		return fmt.Errorf("failed to decode cloud event: %w", err)
+	}
+
+	attributes := make(map[string]string)
+	for _, name := range []string{"specversion", "id", "source", "type", "subject", "time", "datacontenttype", "dataschema", "data_base64"} {
+		raw, ok := doc[name]
+		if !ok || string(raw) == "null" {
+			continue
+		}
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return fmt.Errorf("%w: %s is not a string", ErrInvalidCloudEvent, name)
+		}
+		attributes[name] = value
+	}
+
+	*ce = CloudEvent{
+		ID:              attributes["id"],
+		Source:          attributes["source"],
+		Type:            attributes["type"],
+		Subject:         attributes["subject"],
+		DataContentType: attributes["datacontenttype"],
+		DataSchema:      attributes["dataschema"],
+		Extensions:      make(map[string]any),
+	}
+	if err := ce.setAttributes(attributes["specversion"], attributes["time"]); err != nil {
+		return err
+	}
+
+	if encoded, ok := attributes["data_base64"]; ok {
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return fmt.Errorf("%w: data_base64: %w", ErrInvalidCloudEvent, err)
+		}
+		ce.Data = data
+	} else if raw, ok := doc["data"]; ok && string(raw) != "null" {
+		var text string
+		if !isJSON(ce.DataContentType) && json.Unmarshal(raw, &text) == nil {
+			ce.Data = []byte(text)
+		} else {
+			ce.Data = raw
+		}
+	}
+
+	for name, raw := range doc {
+		if contextAttributes[name] {
+			continue
+		}
+		value, err := decodeExtension(raw)
+		if err != nil {
+			return fmt.Errorf("%w: extension %s: %w", ErrInvalidCloudEvent, name, err)
+		}
+		ce.Extensions[name] = value
+	}
+	return nil
+}
+
+func decodeExtension(raw json.RawMessage) (any, error) {
+	var value any
+	decoder := json.NewDecoder(strings.NewReader(string(raw)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	switch value := value.(type) {
+	case string, bool:
+		return value, nil
+	case json.Number:
+		return value.Int64()
+	default:
+		return nil, fmt.Errorf("unsupported type %T", value)
+	}
+}
+
+func (ce *CloudEvent) setAttributes(specVersion, timestamp string) error {
+	if specVersion != CloudEventsSpecVersion {
+		return fmt.Errorf("%w: unsupported specversion %q", ErrInvalidCloudEvent, specVersion)
+	}
+	if timestamp != "" {
+		t, err := time.Parse(time.RFC3339Nano, timestamp)
+		if err != nil {
+			return fmt.Errorf("%w: time: %w", ErrInvalidCloudEvent, err)
+		}
+		ce.Time = t
+	}
+	return nil
+}
+
+// headers returns the attributes of binary mode, the data content type being left to the
+// content type header of the binding.
+func (ce *CloudEvent) headers() map[string]string {
+	headers := map[string]string{
+		"specversion": CloudEventsSpecVersion,
+		"id":          ce.ID,
+		"source":      ce.Source,
+		"type":        ce.Type,
+	}
+	if ce.Subject != "" {
+		headers["subject"] = ce.Subject
+	}
+	if !ce.Time.IsZero() {
+		headers["time"] = ce.Time.Format(time.RFC3339Nano)
+	}
+	if ce.DataSchema != "" {
+		headers["dataschema"] = ce.DataSchema
+	}
+	for name, value := range ce.Extensions {
+		headers[name] = fmt.Sprint(value)
+	}
+	return headers
+}
+
+// fromHeaders reads the attributes of binary mode. Extensions are strings, their type being
+// lost in headers.
+func (ce *CloudEvent) fromHeaders(headers map[string]string, contentType string, data []byte) error {
+	*ce = CloudEvent{
+		ID:              headers["id"],
+		Source:          headers["source"],
+		Type:            headers["type"],
+		Subject:         headers["subject"],
+		DataSchema:      headers["dataschema"],
+		DataContentType: contentType,
+		Extensions:      make(map[string]any),
+	}
+	if len(data) > 0 {
+		ce.Data = data
+	}
+	if err := ce.setAttributes(headers["specversion"], headers["time"]); err != nil {
+		return err
+	}
+	for name, value := range headers {
+		if !contextAttributes[name] {
+			ce.Extensions[name] = value
+		}
+	}
+	return ce.validate()
+}
+
+// --------------- HTTP BINDING ---------------
+
+// EncodeCloudEventHTTP returns the headers and body of an HTTP request or response carrying
+// event in mode.
+func EncodeCloudEventHTTP(event *Event, mode CloudEventMode) (http.Header, []byte, error) {
+	ce, err := event.ToCloudEvent()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header := make(http.Header)
+	if mode == CloudEventStructured {
+		body, err := json.Marshal(ce)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to encode event %s: %w", event.Topic, err)
+		}
+		header.Set("Content-Type", CloudEventsContentType)
+		return header, body, nil
+	}
+
+	for name, value := range ce.headers() {
+		header.Set(httpHeaderPrefix+name, percentEncode(value))
+	}
+	if ce.DataContentType != "" {
+		header.Set("Content-Type", ce.DataContentType)
+	}
+	return header, ce.Data, nil
+}
+
+// DecodeCloudEventHTTP returns the event carried by an HTTP request or response, in the mode
+// given by its content type.
+func DecodeCloudEventHTTP(header http.Header, body []byte) (*Event, error) {
+	contentType := header.Get("Content-Type")
+	if strings.HasPrefix(contentType, CloudEventsContentType) {
+		return decodeStructured(body)
+	}
+
+	headers := make(map[string]string)
+	for name, values := range header {
+		name = strings.ToLower(name)
+		if !strings.HasPrefix(name, httpHeaderPrefix) || len(values) == 0 {
+			continue
+		}
+		value, err := url.PathUnescape(values[0])
+		if err != nil {
+			return nil, fmt.Errorf("%w: header %s: %w", ErrInvalidCloudEvent, name, err)
+		}
+		headers[strings.TrimPrefix(name, httpHeaderPrefix)] = value
+	}
+
+	var ce CloudEvent
+	if err := ce.fromHeaders(headers, contentType, body); err != nil {
+		return nil, err
+	}
+	return FromCloudEvent(&ce)
+}
+
+// percentEncode encodes the characters HTTP header values of binary mode cannot hold: space,
+// double quote, percent and the ones out of printable ASCII.
+func percentEncode(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c <= ' ' || c > '~' || c == '"' || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func decodeStructured(payload []byte) (*Event, error) {
+	var ce CloudEvent
+	if err := json.Unmarshal(payload, &ce); err != nil {
+		return nil, err
+	}
+	return FromCloudEvent(&ce)
+}
+
+// --------------- KAFKA BINDING ---------------
+
+// MessageCodec is a Codec encoding events into whole messages, e.g. with attributes in
+// headers. The EventObserver uses EncodeMessage and DecodeMessage instead of Encode and
+// Decode when its codec implements it.
+type MessageCodec interface {
+	Codec
+	EncodeMessage(event *Event) (*Message, error)
+	DecodeMessage(msg *Message) (*Event, error)
+}
+
+type cloudEventsCodec struct {
+	mode CloudEventMode
+}
+
+var (
+	// CloudEventsCodec encodes events as CloudEvents in structured mode, for consumers of
+	// other stacks.
+	CloudEventsCodec Codec = cloudEventsCodec{mode: CloudEventStructured}
+	// CloudEventsBinaryCodec encodes events as CloudEvents in binary mode, following the
+	// Kafka protocol binding: attributes in ce_ headers and the data as payload. Both codecs
+	// decode messages in both modes.
+	CloudEventsBinaryCodec MessageCodec = cloudEventsCodec{mode: CloudEventBinary}
+)
+
+func (cloudEventsCodec) ContentType() string {
+	return CloudEventsContentType
+}
+
+func (cloudEventsCodec) Encode(event *Event) ([]byte, error) {
+	ce, err := event.ToCloudEvent()
+	if err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(ce)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event %s: %w", event.Topic, err)
+	}
+	return payload, nil
+}
+
+func (cloudEventsCodec) Decode(payload []byte) (*Event, error) {
+	return decodeStructured(payload)
+}
+
+func (c cloudEventsCodec) EncodeMessage(event *Event) (*Message, error) {
+	msg := &Message{
+		Topic:   event.Topic,
+		Key:     event.Key,
+		Headers: make(map[string]string),
+	}
+	if c.mode == CloudEventStructured {
+		payload, err := c.Encode(event)
+		if err != nil {
+			return nil, err
+		}
+		msg.Payload = payload
+		msg.Headers[ContentTypeHeader] = CloudEventsContentType
+		return msg, nil
+	}
+
+	ce, err := event.ToCloudEvent()
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range ce.headers() {
+		msg.Headers[kafkaHeaderPrefix+name] = value
+	}
+	if ce.DataContentType != "" {
+		msg.Headers[ContentTypeHeader] = ce.DataContentType
+	}
+	msg.Payload = ce.Data
+	return msg, nil
+}
+
+func (c cloudEventsCodec) DecodeMessage(msg *Message) (*Event, error) {
+	contentType := msg.Headers[ContentTypeHeader]
+	if _, binary := msg.Headers[kafkaHeaderPrefix+"specversion"]; !binary || strings.HasPrefix(contentType, CloudEventsContentType) {
+		return c.Decode(msg.Payload)
+	}
+
+	headers := make(map[string]string)
+	for name, value := range msg.Headers {
+		if strings.HasPrefix(name, kafkaHeaderPrefix) {
+			headers[strings.TrimPrefix(name, kafkaHeaderPrefix)] = value
+		}
+	}
+
+	var ce CloudEvent
+	if err := ce.fromHeaders(headers, contentType, msg.Payload); err != nil {
+		return nil, err
+	}
+	event, err := FromCloudEvent(&ce)
+	if err != nil {
+		return nil, err
+	}
+	if event.Key == "" {
+		event.Key = msg.Key
+	}
+	return event, nil
+}
@@ -0,0 +1,274 @@
+package event_observer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testCloudEvent() *Event {
+	return &Event{
+		ID:            "event-1",
+		Topic:         "order.created",
+		Data:          map[string]any{"id": "order-1"},
+		Metadata:      map[string]any{"tenant": "acme", "attempt": float64(2), CloudEventSubjectKey: "order-1"},
+		Key:           "order-1",
+		Timestamp:     time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		Source:        "order-service",
+		CorrelationID: "request-1",
+	}
+}
+
+func TestCloudEventRoundTrip(t *testing.T) {
+	expected := &Event{
+		ID:    "event-1",
+		Topic: "order.created",
+		Data:  json.RawMessage(`{"id":"order-1"}`),
+		Metadata: map[string]any{
+			"tenant":             "acme",
+			"attempt":            int64(2),
+			CloudEventSubjectKey: "order-1",
+		},
+		Key:           "order-1",
+		Timestamp:     time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		Source:        "order-service",
+		CorrelationID: "request-1",
+	}
+	// extensions are strings in headers
+	binaryExpected := *expected
+	binaryExpected.Metadata = map[string]any{
+		"tenant":             "acme",
+		"attempt":            "2",
+		CloudEventSubjectKey: "order-1",
+	}
+
+	testCases := []struct {
+		name     string
+		encode   func(event *Event) (*Event, error)
+		expected *Event
+	}{
+		{
+			name: "structured json",
+			encode: func(event *Event) (*Event, error) {
+				return decodeStructured(mustEncode(t, CloudEventsCodec, event))
+			},
+			expected: expected,
+		},
+		{
+			name: "http structured",
+			encode: func(event *Event) (*Event, error) {
+				header, body, err := EncodeCloudEventHTTP(event, CloudEventStructured)
+				assert.NoError(t, err)
+				assert.Equal(t, CloudEventsContentType, header.Get("Content-Type"))
+				return DecodeCloudEventHTTP(header, body)
+			},
+			expected: expected,
+		},
+		{
+			name: "http binary",
+			encode: func(event *Event) (*Event, error) {
+				header, body, err := EncodeCloudEventHTTP(event, CloudEventBinary)
+				assert.NoError(t, err)
+				assert.Equal(t, "application/json", header.Get("Content-Type"))
+				assert.Equal(t, "1.0", header.Get("ce-specversion"))
+				assert.Equal(t, "order.created", header.Get("ce-type"))
+				assert.JSONEq(t, `{"id":"order-1"}`, string(body))
+				return DecodeCloudEventHTTP(header, body)
+			},
+			expected: &binaryExpected,
+		},
+		{
+			name: "kafka binary",
+			encode: func(event *Event) (*Event, error) {
+				msg, err := CloudEventsBinaryCodec.EncodeMessage(event)
+				assert.NoError(t, err)
+				assert.Equal(t, "order-1", msg.Key)
+				assert.Equal(t, "application/json", msg.Headers[ContentTypeHeader])
+				assert.Equal(t, "order-service", msg.Headers["ce_source"])
+				assert.Equal(t, "request-1", msg.Headers["ce_correlationid"])
+				return CloudEventsBinaryCodec.DecodeMessage(msg)
+			},
+			expected: &binaryExpected,
+		},
+		{
+			name: "kafka structured",
+			encode: func(event *Event) (*Event, error) {
+				msg, err := CloudEventsCodec.(MessageCodec).EncodeMessage(event)
+				assert.NoError(t, err)
+				assert.Equal(t, CloudEventsContentType, msg.Headers[ContentTypeHeader])
+				return CloudEventsBinaryCodec.DecodeMessage(msg)
+			},
+			expected: expected,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			event, err := tc.encode(testCloudEvent())
+			assert.NoError(t, err)
+			if data, ok := event.Data.(json.RawMessage); ok {
+				assert.JSONEq(t, string(tc.expected.Data.(json.RawMessage)), string(data))
+				event.Data = tc.expected.Data
+			}
+			assert.Equal(t, tc.expected, event)
+		})
+	}
+}
+
+func mustEncode(t *testing.T, codec Codec, event *Event) []byte {
+	payload, err := codec.Encode(event)
+	assert.NoError(t, err)
+	return payload
+}
+
+func TestCloudEventStructuredFormat(t *testing.T) {
+	payload := mustEncode(t, CloudEventsCodec, testCloudEvent())
+	assert.JSONEq(t, `{
+		"specversion": "1.0",
+		"id": "event-1",
+		"source": "order-service",
+		"type": "order.created",
+		"subject": "order-1",
+		"time": "2024-05-01T10:00:00Z",
+		"datacontenttype": "application/json",
+		"data": {"id": "order-1"},
+		"tenant": "acme",
+		"attempt": 2,
+		"partitionkey": "order-1",
+		"correlationid": "request-1"
+	}`, string(payload))
+
+	binary := &Event{ID: "event-2", Topic: "image.uploaded", Source: "upload-service", Data: []byte{0xff, 0x00}}
+	payload = mustEncode(t, CloudEventsCodec, binary)
+	assert.JSONEq(t, `{
+		"specversion": "1.0",
+		"id": "event-2",
+		"source": "upload-service",
+		"type": "image.uploaded",
+		"datacontenttype": "application/octet-stream",
+		"data_base64": "/wA="
+	}`, string(payload))
+
+	decoded, err := CloudEventsCodec.Decode(payload)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xff, 0x00}, decoded.Data)
+}
+
+func TestCloudEventDecodeForeign(t *testing.T) {
+	// as published by another stack, with text data
+	payload := []byte(`{
+		"specversion": "1.0",
+		"id": "A234-1234-1234",
+		"source": "https://github.com/cloudevents/spec/pull",
+		"type": "com.github.pull_request.opened",
+		"datacontenttype": "text/plain",
+		"data": "hello",
+		"comexampleextension1": "value",
+		"comexampleothervalue": 5
+	}`)
+
+	event, err := CloudEventsCodec.Decode(payload)
+	assert.NoError(t, err)
+	assert.Equal(t, "com.github.pull_request.opened", event.Topic)
+	assert.Equal(t, []byte("hello"), event.Data)
+	assert.Equal(t, "value", event.Metadata["comexampleextension1"])
+	assert.Equal(t, int64(5), event.Metadata["comexampleothervalue"])
+	assert.True(t, event.Timestamp.IsZero())
+
+	header := http.Header{}
+	header.Set("Content-Type", "text/plain")
+	header.Set("ce-specversion", "1.0")
+	header.Set("ce-id", "A234-1234-1234")
+	header.Set("ce-source", "https://github.com/cloudevents/spec/pull")
+	header.Set("ce-type", "com.github.pull_request.opened")
+	header.Set("ce-comment", "Euro%20%E2%82%AC")
+	event, err = DecodeCloudEventHTTP(header, []byte("hello"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("hello"), event.Data)
+	assert.Equal(t, "Euro €", event.Metadata["comment"])
+}
+
+func TestCloudEventInvalid(t *testing.T) {
+	testCases := []struct {
+		name  string
+		event *Event
+	}{
+		{name: "missing source", event: &Event{ID: "event-1", Topic: "order.created"}},
+		{name: "missing id", event: &Event{Topic: "order.created", Source: "svc"}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.event.ToCloudEvent()
+			assert.ErrorIs(t, err, ErrInvalidCloudEvent)
+		})
+	}
+
+	_, err := CloudEventsCodec.Decode([]byte(`{"specversion":"0.3","id":"1","source":"svc","type":"order.created"}`))
+	assert.ErrorIs(t, err, ErrInvalidCloudEvent)
+	_, err = CloudEventsCodec.Decode([]byte(`{"specversion":"1.0","id":"1","type":"order.created"}`))
+	assert.ErrorIs(t, err, ErrInvalidCloudEvent)
+}
+
+func TestCloudEventMetadata(t *testing.T) {
+	event := &Event{
+		ID:     "event-1",
+		Topic:  "order.created",
+		Source: "svc",
+		Metadata: map[string]any{
+			"order_id":                   "order-1",
+			"Tenant-ID":                  "acme",
+			"tenantid":                   "other",
+			"time":                       "now",
+			"_":                          "empty",
+			"ratio":                      0.5,
+			CloudEventSubjectKey:         42,
+			CloudEventDataContentTypeKey: "application/xml",
+		},
+	}
+
+	ce, err := event.ToCloudEvent()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"orderid": "order-1", "tenantid": "acme"}, ce.Extensions)
+	assert.Empty(t, ce.Subject)
+	assert.Equal(t, "application/xml", ce.DataContentType)
+
+	decoded, err := FromCloudEvent(ce)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"orderid": "order-1", "tenantid": "acme", CloudEventDataContentTypeKey: "application/xml"}, decoded.Metadata)
+}
+
+func TestCloudEventsTransport(t *testing.T) {
+	transport := NewMemoryTransport()
+	eo := NewEventObserver("order-service", WithTransport(transport, CloudEventsBinaryCodec))
+
+	received := make(chan *Event, 1)
+	assert.NoError(t, eo.Subscribe("order.created", Subscriber{
+		SubscriberName: "subscriber",
+		HandlerFunc: func(ctx context.Context, event *Event) error {
+			received <- event
+			return nil
+		},
+	}))
+	time.Sleep(10 * time.Millisecond)
+
+	assert.NoError(t, eo.Publish(context.Background(), &Event{Topic: "order.created", Key: "order-1", Data: map[string]any{"id": "order-1"}}))
+	select {
+	case event := <-received:
+		assert.Equal(t, "order-service", event.Source)
+		assert.Equal(t, "order-1", event.Key)
+		assert.JSONEq(t, `{"id":"order-1"}`, string(event.Data.(json.RawMessage)))
+	case <-time.After(time.Second):
+		t.Fatal("event not received")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, eo.Close(ctx))
+}
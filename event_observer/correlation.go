@@ -0,0 +1,48 @@
+package event_observer
+
+import (
+	"context"
+	"time"
+
+	"github.com/NusaCrew/atlas-go/log"
+
+	"github.com/google/uuid"
+)
+
+type correlationIDContextKey struct{}
+
+// ContextWithCorrelationID returns a copy of ctx whose published events are correlated by id.
+// Handlers run with the correlation ID of their event, so the events they publish share it.
+func ContextWithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDContextKey{}, id)
+}
+
+// CorrelationIDFromContext returns the correlation ID set with ContextWithCorrelationID, or
+// else the trace ID of the incoming request.
+func CorrelationIDFromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(correlationIDContextKey{}).(string); ok && id != "" {
+		return id
+	}
+	return log.TraceIDFromContext(ctx)
+}
+
+// Stamp sets the fields of event left empty: a random ID, the current Timestamp, source as
+// Source and the correlation ID of ctx, or the event's own ID when ctx has none. Publish
+// stamps events, so it is only needed to store them before, e.g. in an outbox.
+func (e *Event) Stamp(ctx context.Context, source string) {
+	if e.ID == "" {
+		e.ID = uuid.NewString()
+	}
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now().UTC()
+	}
+	if e.Source == "" {
+		e.Source = source
+	}
+	if e.CorrelationID == "" {
+		e.CorrelationID = CorrelationIDFromContext(ctx)
+	}
+	if e.CorrelationID == "" {
+		e.CorrelationID = e.ID
+	}
+}
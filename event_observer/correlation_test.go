@@ -0,0 +1,74 @@
+package event_observer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestEventStamp(t *testing.T) {
+	event := &Event{Topic: "test-topic"}
+	event.Stamp(context.Background(), "some-service-name")
+	assert.NotEmpty(t, event.ID)
+	assert.WithinDuration(t, time.Now(), event.Timestamp, time.Second)
+	assert.Equal(t, "some-service-name", event.Source)
+	assert.Equal(t, event.ID, event.CorrelationID, "events without correlation ID start a new one")
+
+	stamped := *event
+	event.Stamp(ContextWithCorrelationID(context.Background(), "other"), "other-service")
+	assert.Equal(t, stamped, *event, "set fields are kept")
+
+	event = &Event{Topic: "test-topic"}
+	event.Stamp(ContextWithCorrelationID(context.Background(), "correlation-id"), "")
+	assert.Equal(t, "correlation-id", event.CorrelationID)
+	assert.Empty(t, event.Source)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-correlation-id", "request-id"))
+	assert.Equal(t, "request-id", CorrelationIDFromContext(ctx))
+}
+
+func TestCorrelationIDPropagation(t *testing.T) {
+	eo := NewEventObserver("some-service-name", WithDeliveryMode(DeliverySync))
+
+	var shipped *Event
+	assert.NoError(t, eo.Subscribe("order.paid", Subscriber{
+		SubscriberName: "ship",
+		HandlerFunc: func(ctx context.Context, event *Event) error {
+			return eo.Publish(ctx, &Event{Topic: "order.shipped"})
+		},
+	}))
+	assert.NoError(t, eo.Subscribe("order.shipped", Subscriber{
+		SubscriberName: "notify",
+		HandlerFunc: func(ctx context.Context, event *Event) error {
+			shipped = event
+			return nil
+		},
+	}))
+
+	paid := &Event{Topic: "order.paid"}
+	assert.NoError(t, eo.Publish(ContextWithCorrelationID(context.Background(), "request-id"), paid))
+	if assert.NotNil(t, shipped) {
+		assert.Equal(t, "request-id", paid.CorrelationID)
+		assert.Equal(t, "request-id", shipped.CorrelationID)
+		assert.NotEqual(t, paid.ID, shipped.ID)
+	}
+}
+
+func TestCodecEventIdentity(t *testing.T) {
+	event := &Event{Topic: "test-topic"}
+	event.Stamp(context.Background(), "some-service-name")
+
+	for _, codec := range []Codec{JSONCodec, ProtoCodec} {
+		payload, err := codec.Encode(event)
+		assert.NoError(t, err)
+		decoded, err := codec.Decode(payload)
+		assert.NoError(t, err)
+		assert.Equal(t, event.ID, decoded.ID)
+		assert.True(t, event.Timestamp.Equal(decoded.Timestamp))
+		assert.Equal(t, event.Source, decoded.Source)
+		assert.Equal(t, event.CorrelationID, decoded.CorrelationID)
+	}
+}
@@ -0,0 +1,110 @@
+package event_observer
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// DeadLetter is an event a subscriber failed to handle after exhausting its retry policy.
+type DeadLetter struct {
+	ID             string
+	Topic          string
+	SubscriberName string
+	Event          *Event
+	Error          string
+	Attempts       int
+	FailedAt       time.Time
+}
+
+type DeadLetterFilter struct {
+	Topic          string
+	SubscriberName string
+	Limit          int
+}
+
+func (f DeadLetterFilter) matches(dl *DeadLetter) bool {
+	return (f.Topic == "" || f.Topic == dl.Topic) &&
+		(f.SubscriberName == "" || f.SubscriberName == dl.SubscriberName)
+}
+
+// DeadLetterStore keeps dead lettered events so they can be inspected and replayed.
+// Save inserts the dead letter, or replaces it when one with the same ID exists.
+type DeadLetterStore interface {
+	Save(ctx context.Context, deadLetter *DeadLetter) error
+	List(ctx context.Context, filter DeadLetterFilter) ([]*DeadLetter, error)
+	Get(ctx context.Context, id string) (*DeadLetter, error)
+	Delete(ctx context.Context, id string) error
+}
+
+type inMemoryDeadLetterStore struct {
+	mu          sync.RWMutex
+	deadLetters []*DeadLetter
+}
+
+// NewInMemoryDeadLetterStore returns a DeadLetterStore losing its content on restart,
+// meant for tests and non critical events.
+func NewInMemoryDeadLetterStore() DeadLetterStore {
+	return &inMemoryDeadLetterStore{}
+}
+
+func (s *inMemoryDeadLetterStore) Save(ctx context.Context, deadLetter *DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := *deadLetter
+	idx := slices.IndexFunc(s.deadLetters, func(dl *DeadLetter) bool { return dl.ID == deadLetter.ID })
+	if idx >= 0 {
+		s.deadLetters[idx] = &stored
+		return nil
+	}
+	s.deadLetters = append(s.deadLetters, &stored)
+	return nil
+}
+
+func (s *inMemoryDeadLetterStore) List(ctx context.Context, filter DeadLetterFilter) ([]*DeadLetter, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []*DeadLetter
+	for _, dl := range s.deadLetters {
+		if !filter.matches(dl) {
+			continue
+		}
+		stored := *dl
+		result = append(result, &stored)
+		if filter.Limit > 0 && len(result) == filter.Limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+func (s *inMemoryDeadLetterStore) Get(ctx context.Context, id string) (*DeadLetter, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, dl := range s.deadLetters {
+		if dl.ID == id {
+			stored := *dl
+			return &stored, nil
+		}
+	}
+	return nil, ErrDeadLetterNotFound
+}
+
+func (s *inMemoryDeadLetterStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx := slices.IndexFunc(s.deadLetters, func(dl *DeadLetter) bool { return dl.ID == id })
+	if idx < 0 {
+		return ErrDeadLetterNotFound
+	}
+	s.deadLetters = slices.Delete(s.deadLetters, idx, idx+1)
+	return nil
+}
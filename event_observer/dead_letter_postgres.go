@@ -0,0 +1,127 @@
+package event_observer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/NusaCrew/atlas-go/storage/postgres"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+const DefaultDeadLetterTable = "event_dead_letters"
+
+type postgresDeadLetterStore struct {
+	postgres.CommonRepository
+	table string
+}
+
+// NewPostgresDeadLetterStore returns a DeadLetterStore backed by the given table, with the
+// schema of migrations/000001_create_event_dead_letters.up.sql, which creates
+// DefaultDeadLetterTable: copy it with the table renamed to use another one. Data of the
+// stored events is returned as its type when registered by RegisterDataType or NewTopic, and
+// as json.RawMessage otherwise.
+func NewPostgresDeadLetterStore(storage postgres.Storage, table string) DeadLetterStore {
+	if table == "" {
+		table = DefaultDeadLetterTable
+	}
+	return &postgresDeadLetterStore{
+		CommonRepository: postgres.CommonRepository{Storage: storage},
+		table:            table,
+	}
+}
+
+func (s *postgresDeadLetterStore) Save(ctx context.Context, deadLetter *DeadLetter) error {
+	payload, dataType, err := encodeStoredEvent(deadLetter.Event)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.Builder(nil).
+		Insert(s.table).
+		Columns("id", "topic", "subscriber_name", "event", "data_type", "error", "attempts", "failed_at").
+		Values(deadLetter.ID, deadLetter.Topic, deadLetter.SubscriberName, payload, dataType, deadLetter.Error, deadLetter.Attempts, deadLetter.FailedAt).
+		Suffix("ON CONFLICT (id) DO UPDATE SET error = EXCLUDED.error, attempts = EXCLUDED.attempts, failed_at = EXCLUDED.failed_at").
+		ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to save dead letter %s: %w", deadLetter.ID, err)
+	}
+	return nil
+}
+
+func (s *postgresDeadLetterStore) selectBuilder() sq.SelectBuilder {
+	return s.Builder(nil).
+		Select("id", "topic", "subscriber_name", "event", "data_type", "error", "attempts", "failed_at").
+		From(s.table)
+}
+
+func (s *postgresDeadLetterStore) List(ctx context.Context, filter DeadLetterFilter) ([]*DeadLetter, error) {
+	builder := s.selectBuilder().OrderBy("failed_at")
+	if filter.Topic != "" {
+		builder = builder.Where(sq.Eq{"topic": filter.Topic})
+	}
+	if filter.SubscriberName != "" {
+		builder = builder.Where(sq.Eq{"subscriber_name": filter.SubscriberName})
+	}
+	if filter.Limit > 0 {
+		builder = builder.Limit(uint64(filter.Limit))
+	}
+
+	rows, err := builder.QueryContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	var result []*DeadLetter
+	for rows.Next() {
+		dl, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, dl)
+	}
+	return result, rows.Err()
+}
+
+func (s *postgresDeadLetterStore) Get(ctx context.Context, id string) (*DeadLetter, error) {
+	dl, err := scanDeadLetter(s.selectBuilder().Where(sq.Eq{"id": id}).QueryRowContext(ctx))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDeadLetterNotFound
+	}
+	return dl, err
+}
+
+func (s *postgresDeadLetterStore) Delete(ctx context.Context, id string) error {
+	result, err := s.Builder(nil).Delete(s.table).Where(sq.Eq{"id": id}).ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete dead letter %s: %w", id, err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrDeadLetterNotFound
+	}
+	return nil
+}
+
+func scanDeadLetter(row sq.RowScanner) (*DeadLetter, error) {
+	var (
+		dl       DeadLetter
+		payload  []byte
+		dataType string
+		failedAt time.Time
+	)
+	if err := row.Scan(&dl.ID, &dl.Topic, &dl.SubscriberName, &payload, &dataType, &dl.Error, &dl.Attempts, &failedAt); err != nil {
+		return nil, err
+	}
+
+	event, err := decodeStoredEvent(payload, dataType)
+	if err != nil {
+		return nil, err
+	}
+	dl.Event = event
+	dl.FailedAt = failedAt
+	return &dl, nil
+}
@@ -0,0 +1,63 @@
+package event_observer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// --------------- ENUMERATIONS ---------------
+
+// DeliveryMode decides how events published within the process reach a subscriber. With a
+// transport, consumers handle their messages one at a time whatever the mode.
+type DeliveryMode int
+
+const (
+	DeliveryAsync   DeliveryMode = iota + 1 // queue the event in the pool of the subscriber, handled concurrently
+	DeliverySync                            // handle the event within Publish, which returns the handler errors
+	DeliveryOrdered                         // queue the event by Event.Key, handling events sharing a key one at a time in order
+)
+
+func (m DeliveryMode) String() string {
+	switch m {
+	case DeliveryAsync:
+		return "ASYNC"
+	case DeliverySync:
+		return "SYNC"
+	case DeliveryOrdered:
+		return "ORDERED"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// --------------- DELIVERY ---------------
+
+// WithDeliveryMode sets the delivery mode of subscribers without one of their own, DeliveryAsync
+// by default.
+func WithDeliveryMode(mode DeliveryMode) Option {
+	return func(eo *EventObserver) {
+		eo.deliveryMode = mode
+	}
+}
+
+func (eo *EventObserver) deliveryModeOf(s Subscriber) DeliveryMode {
+	if s.DeliveryMode != 0 {
+		return s.DeliveryMode
+	}
+	return eo.deliveryMode
+}
+
+// deliverSync delivers event to subscribers one after the other, with their retry policy,
+// returning an error joining the handlers which failed.
+func (eo *EventObserver) deliverSync(ctx context.Context, subscribers []Subscriber, event *Event) error {
+	defer eo.syncDeliveries.Done()
+
+	var errs []error
+	for _, s := range subscribers {
+		if err := eo.deliver(ctx, s, event); err != nil {
+			errs = append(errs, fmt.Errorf("subscriber %s of topic %s failed: %w", s.SubscriberName, event.Topic, err))
+		}
+	}
+	return errors.Join(errs...)
+}
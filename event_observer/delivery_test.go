@@ -0,0 +1,123 @@
+package event_observer
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeliverySync(t *testing.T) {
+	eo := NewEventObserver("some-service-name", WithRetryPolicy(RetryPolicy{MaxAttempts: 2}))
+
+	var handled []string
+	assert.NoError(t, eo.Subscribe("test-topic", Subscriber{
+		SubscriberName: "succeeding",
+		DeliveryMode:   DeliverySync,
+		HandlerFunc: func(ctx context.Context, event *Event) error {
+			handled = append(handled, "succeeding")
+			return nil
+		},
+	}))
+	assert.NoError(t, eo.Subscribe("test-topic", Subscriber{
+		SubscriberName: "failing",
+		DeliveryMode:   DeliverySync,
+		HandlerFunc: func(ctx context.Context, event *Event) error {
+			handled = append(handled, "failing")
+			return assert.AnError
+		},
+	}))
+	assert.Nil(t, eo.subscribers["test-topic"][0].pool, "sync subscribers have no pool")
+
+	err := eo.Publish(context.Background(), &Event{Topic: "test-topic"})
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.ErrorContains(t, err, "subscriber failing of topic test-topic failed")
+	assert.Equal(t, []string{"succeeding", "failing", "failing"}, handled, "handled within Publish, with retries")
+}
+
+func TestDeliverySyncPublishFromHandler(t *testing.T) {
+	eo := NewEventObserver("some-service-name", WithDeliveryMode(DeliverySync))
+
+	var handled []string
+	assert.NoError(t, eo.Subscribe("order.created", Subscriber{
+		SubscriberName: "reserve-stock",
+		HandlerFunc: func(ctx context.Context, event *Event) error {
+			handled = append(handled, event.Topic)
+			return eo.Publish(ctx, &Event{Topic: "stock.reserved"})
+		},
+	}))
+	assert.NoError(t, eo.Subscribe("stock.reserved", Subscriber{
+		SubscriberName: "notify",
+		HandlerFunc: func(ctx context.Context, event *Event) error {
+			handled = append(handled, event.Topic)
+			return nil
+		},
+	}))
+
+	assert.NoError(t, eo.Publish(context.Background(), &Event{Topic: "order.created"}))
+	assert.Equal(t, []string{"order.created", "stock.reserved"}, handled)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, eo.Close(ctx))
+}
+
+func TestDeliveryOrdered(t *testing.T) {
+	eo := NewEventObserver("some-service-name")
+
+	var mu sync.Mutex
+	handled := make(map[string][]int)
+	assert.NoError(t, eo.Subscribe("test-topic", Subscriber{
+		SubscriberName: "subscriber",
+		DeliveryMode:   DeliveryOrdered,
+		Pool:           &PoolConfig{Workers: 4, QueueSize: 400},
+		HandlerFunc: func(ctx context.Context, event *Event) error {
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			defer mu.Unlock()
+			handled[event.Key] = append(handled[event.Key], event.Data.(int))
+			return nil
+		},
+	}))
+	assert.Len(t, eo.subscribers["test-topic"][0].pool.queues, 4)
+
+	keys := []string{"a", "b", "c", "d", "e"}
+	for i := 0; i < 20; i++ {
+		for _, key := range keys {
+			assert.NoError(t, eo.Publish(context.Background(), &Event{Topic: "test-topic", Key: key, Data: i}))
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, eo.Close(ctx))
+
+	for _, key := range keys {
+		expected := make([]int, 20)
+		for i := range expected {
+			expected[i] = i
+		}
+		assert.Equal(t, expected, handled[key], "events of key %s are handled in order", key)
+	}
+}
+
+func TestDeliveryOrderedOwnPool(t *testing.T) {
+	eo := NewEventObserver("some-service-name", WithTopicPool("test-topic", PoolConfig{Workers: 2, QueueSize: 10}))
+	handler := func(ctx context.Context, event *Event) error { return nil }
+	assert.NoError(t, eo.Subscribe("test-topic", Subscriber{SubscriberName: "async", HandlerFunc: handler}))
+	assert.NoError(t, eo.Subscribe("test-topic", Subscriber{SubscriberName: "ordered", DeliveryMode: DeliveryOrdered, HandlerFunc: handler}))
+
+	assert.Len(t, eo.pools["test-topic"].queues, 1)
+	if assert.Contains(t, eo.pools, "test-topic/ordered") {
+		assert.Len(t, eo.pools["test-topic/ordered"].queues, 2, "ordered pool uses the config of the topic pool")
+	}
+}
+
+func TestDeliveryModeString(t *testing.T) {
+	assert.Equal(t, "ASYNC", DeliveryAsync.String())
+	assert.Equal(t, "SYNC", DeliverySync.String())
+	assert.Equal(t, "ORDERED", DeliveryOrdered.String())
+	assert.Equal(t, "UNKNOWN", DeliveryMode(0).String())
+}
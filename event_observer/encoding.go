@@ -0,0 +1,275 @@
+package event_observer
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec serializes events into message payloads.
+type Codec interface {
+	ContentType() string
+	Encode(event *Event) ([]byte, error)
+	Decode(payload []byte) (*Event, error)
+}
+
+type jsonCodec struct{}
+
+// JSONCodec encodes events as JSON, with protojson for data being a proto.Message. Data of
+// decoded events is a json.RawMessage for handlers to unmarshal into their own type, which
+// Topic does.
+var JSONCodec Codec = jsonCodec{}
+
+func (jsonCodec) ContentType() string {
+	return "application/json"
+}
+
+func (jsonCodec) Encode(event *Event) ([]byte, error) {
+	return encodeEvent(event)
+}
+
+func (jsonCodec) Decode(payload []byte) (*Event, error) {
+	return decodeEvent(payload)
+}
+
+type protoCodec struct{}
+
+// ProtoCodec encodes the data of events, which must be a proto.Message, in the protobuf
+// binary format within a JSON envelope. Data of decoded events is the []byte of the message
+// for handlers to unmarshal, which Topic does.
+var ProtoCodec Codec = protoCodec{}
+
+// protoEnvelope is eventEnvelope with Data in the protobuf binary format.
+type protoEnvelope struct {
+	ID            string         `json:"id,omitempty"`
+	Topic         string         `json:"topic"`
+	Key           string         `json:"key,omitempty"`
+	Data          []byte         `json:"data,omitempty"`
+	Metadata      map[string]any `json:"metadata,omitempty"`
+	Timestamp     time.Time      `json:"timestamp,omitzero"`
+	Source        string         `json:"source,omitempty"`
+	CorrelationID string         `json:"correlation_id,omitempty"`
+}
+
+func (protoCodec) ContentType() string {
+	return "application/x-protobuf"
+}
+
+func (protoCodec) Encode(event *Event) ([]byte, error) {
+	envelope := protoEnvelope{
+		ID:            event.ID,
+		Topic:         event.Topic,
+		Key:           event.Key,
+		Metadata:      event.Metadata,
+		Timestamp:     event.Timestamp,
+		Source:        event.Source,
+		CorrelationID: event.CorrelationID,
+	}
+
+	if event.Data != nil {
+		msg, ok := event.Data.(proto.Message)
+		if !ok {
+			return nil, fmt.Errorf("failed to encode data of event %s: %T is not a proto.Message", event.Topic, event.Data)
+		}
+		data, err := proto.Marshal(msg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode data of event %s: %w", event.Topic, err)
+		}
+		envelope.Data = data
+	}
+
+	return json.Marshal(envelope)
+}
+
+func (protoCodec) Decode(payload []byte) (*Event, error) {
+	var envelope protoEnvelope
+	if err := json.Unmarshal(payload, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to decode event: %w", err)
+	}
+
+	event := &Event{
+		ID:            envelope.ID,
+		Topic:         envelope.Topic,
+		Key:           envelope.Key,
+		Metadata:      envelope.Metadata,
+		Timestamp:     envelope.Timestamp,
+		Source:        envelope.Source,
+		CorrelationID: envelope.CorrelationID,
+	}
+	if len(envelope.Data) > 0 {
+		event.Data = envelope.Data
+	}
+	return event, nil
+}
+
+// eventEnvelope is the JSON representation of an Event persisted by the stores of this
+// package. Data is kept raw, so a decoded Event carries a json.RawMessage for handlers
+// to unmarshal into their own type.
+type eventEnvelope struct {
+	ID            string          `json:"id,omitempty"`
+	Topic         string          `json:"topic"`
+	Key           string          `json:"key,omitempty"`
+	Data          json.RawMessage `json:"data,omitempty"`
+	Metadata      map[string]any  `json:"metadata,omitempty"`
+	Timestamp     time.Time       `json:"timestamp,omitzero"`
+	Source        string          `json:"source,omitempty"`
+	CorrelationID string          `json:"correlation_id,omitempty"`
+}
+
+func encodeEvent(event *Event) ([]byte, error) {
+	envelope := eventEnvelope{
+		ID:            event.ID,
+		Topic:         event.Topic,
+		Key:           event.Key,
+		Metadata:      event.Metadata,
+		Timestamp:     event.Timestamp,
+		Source:        event.Source,
+		CorrelationID: event.CorrelationID,
+	}
+
+	if event.Data != nil {
+		var (
+			data []byte
+			err  error
+		)
+		if msg, ok := event.Data.(proto.Message); ok {
+			data, err = protojson.Marshal(msg)
+		} else {
+			data, err = json.Marshal(event.Data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode data of event %s: %w", event.Topic, err)
+		}
+		envelope.Data = data
+	}
+
+	return json.Marshal(envelope)
+}
+
+func decodeEvent(payload []byte) (*Event, error) {
+	var envelope eventEnvelope
+	if err := json.Unmarshal(payload, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to decode event: %w", err)
+	}
+
+	event := &Event{
+		ID:            envelope.ID,
+		Topic:         envelope.Topic,
+		Key:           envelope.Key,
+		Metadata:      envelope.Metadata,
+		Timestamp:     envelope.Timestamp,
+		Source:        envelope.Source,
+		CorrelationID: envelope.CorrelationID,
+	}
+	if len(envelope.Data) > 0 {
+		event.Data = envelope.Data
+	}
+	return event, nil
+}
+
+// dataTypes are the types of RegisterDataType by dataTypeName.
+var dataTypes sync.Map
+
+// RegisterDataType lets the stores keeping events as JSON, like the PostgreSQL dead letter
+// and schedule stores, return data of type T as a T instead of a json.RawMessage. NewTopic
+// registers the data type of its topic.
+func RegisterDataType[T any]() {
+	t := reflect.TypeFor[T]()
+	if name := dataTypeName(t); name != "" {
+		dataTypes.Store(name, t)
+	}
+}
+
+// dataTypeName names t by its package path, empty for unnamed types, which cannot be
+// registered.
+func dataTypeName(t reflect.Type) string {
+	if t == nil {
+		return ""
+	}
+	if t.Kind() == reflect.Pointer {
+		if name := dataTypeName(t.Elem()); name != "" {
+			return "*" + name
+		}
+		return ""
+	}
+	if t.Name() == "" || t.PkgPath() == "" {
+		return ""
+	}
+	return t.PkgPath() + "." + t.Name()
+}
+
+// encodeStoredEvent encodes event with encodeEvent, along the name of the type of its data
+// for decodeStoredEvent.
+func encodeStoredEvent(event *Event) ([]byte, string, error) {
+	payload, err := encodeEvent(event)
+	if err != nil {
+		return nil, "", err
+	}
+	return payload, dataTypeName(reflect.TypeOf(event.Data)), nil
+}
+
+// decodeStoredEvent decodes an event of encodeStoredEvent, its data being unmarshalled into
+// the registered type named dataType, and left as a json.RawMessage for other types.
+func decodeStoredEvent(payload []byte, dataType string) (*Event, error) {
+	event, err := decodeEvent(payload)
+	if err != nil {
+		return nil, err
+	}
+	if err := decodeData(event, dataType); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// decodeData unmarshals the data of a decoded event into the registered type named dataType:
+// from JSON for a json.RawMessage, and from the protobuf binary format for the []byte of
+// ProtoCodec. Data is left as is for other types.
+func decodeData(event *Event, dataType string) error {
+	if dataType == "" {
+		return nil
+	}
+	registered, ok := dataTypes.Load(dataType)
+	if !ok {
+		return nil
+	}
+
+	t := registered.(reflect.Type)
+	isPointer := t.Kind() == reflect.Pointer
+	if isPointer {
+		t = t.Elem()
+	}
+	value := reflect.New(t)
+	msg, isProto := value.Interface().(proto.Message)
+
+	var err error
+	switch data := event.Data.(type) {
+	case json.RawMessage:
+		if isProto {
+			err = protojson.Unmarshal(data, msg)
+		} else {
+			err = json.Unmarshal(data, value.Interface())
+		}
+	case []byte:
+		if !isProto {
+			return nil
+		}
+		err = proto.Unmarshal(data, msg)
+	default:
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to decode data of event %s as %s: %w", event.Topic, dataType, err)
+	}
+
+	if isPointer {
+		event.Data = value.Interface()
+	} else {
+		event.Data = value.Elem().Interface()
+	}
+	return nil
+}
@@ -2,17 +2,26 @@ package event_observer
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"reflect"
 	"sync"
 	"time"
 
 	"github.com/NusaCrew/atlas-go/log"
+
+	"github.com/google/uuid"
 )
 
 type Event struct {
-	Topic    string
-	Data     any
-	Metadata map[string]any
+	ID            string // unique, set by Publish when empty, see Stamp
+	Topic         string
+	Data          any
+	Metadata      map[string]any
+	Key           string    // partition key of DeliveryOrdered, and the message key of transports
+	Timestamp     time.Time // when the event occurred
+	Source        string    // the service publishing the event
+	CorrelationID string    // shared by the events caused by the same request
 }
 
 type HandlerFunc func(ctx context.Context, event *Event) error
@@ -21,51 +30,562 @@ type Subscriber struct {
 	TopicName      string
 	SubscriberName string
 	HandlerFunc    HandlerFunc
+	RetryPolicy    *RetryPolicy  // defaults to the retry policy of the EventObserver
+	Pool           *PoolConfig   // gives the subscriber a pool of its own instead of the pool of its topic
+	Timeout        time.Duration // of every attempt, defaults to 30s
+	Middlewares    []Middleware  // run after the middlewares of the EventObserver
+	DeliveryMode   DeliveryMode  // defaults to the delivery mode of the EventObserver
+
+	pool          *workerPool
+	stopConsuming context.CancelFunc
+	group         string // <service name>.<subscriber name>, shared by the instances of the service
+	unnamed       bool   // SubscriberName was generated by Subscribe
 }
 
 type EventObserver struct {
-	mu          sync.RWMutex
-	serviceName string
-	subscribers map[string][]Subscriber
+	mu              sync.RWMutex
+	serviceName     string
+	subscribers     map[string][]Subscriber
+	retryPolicy     RetryPolicy
+	deadLetterStore DeadLetterStore
+	poolConfig      PoolConfig
+	topicPools      map[string]PoolConfig
+	pools           map[string]*workerPool
+	closedPools     []*workerPool // pools of unsubscribed subscribers, still handling their queue
+	metrics         PoolMetrics
+	middlewares     []Middleware
+	recoverPanics   bool
+	deliveryMode    DeliveryMode
+	syncDeliveries  sync.WaitGroup
+	unnamed         int
+	idempotent      bool // subscribers need a name, set by WithIdempotencyStore
+
+	scheduleStore        ScheduleStore
+	schedulePollInterval time.Duration
+
+	transport       Transport
+	ownsTransport   bool
+	codec           Codec
+	consumers       sync.WaitGroup // transport consumers and scheduler, stopped by stopConsumers
+	stopConsumers   context.CancelFunc
+	consumerContext context.Context
+
+	closeMu sync.RWMutex
+	closed  bool
+	stop    chan struct{}
+}
+
+type Option func(eo *EventObserver)
+
+// WithRetryPolicy sets the retry policy of subscribers without one of their own.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(eo *EventObserver) {
+		eo.retryPolicy = policy
+	}
+}
+
+// WithDeadLetterStore keeps events whose handler kept failing after the last retry, instead of
+// only logging the error.
+func WithDeadLetterStore(store DeadLetterStore) Option {
+	return func(eo *EventObserver) {
+		eo.deadLetterStore = store
+	}
+}
+
+// WithPool sets the pool config of subscribers without a pool of their own or of their topic.
+// Each of them gets a dedicated pool.
+func WithPool(config PoolConfig) Option {
+	return func(eo *EventObserver) {
+		eo.poolConfig = config
+	}
+}
+
+// WithTopicPool makes the subscribers of topic share one pool, unless they have a pool of their own.
+func WithTopicPool(topic string, config PoolConfig) Option {
+	return func(eo *EventObserver) {
+		eo.topicPools[topic] = config
+	}
 }
 
-func NewEventObserver(serviceName string) *EventObserver {
-	return &EventObserver{
-		serviceName: serviceName,
-		subscribers: make(map[string][]Subscriber),
+// WithMetrics records the queue depth, queue latency and overflows of the pools, and the
+// recovered panics when metrics implement HandlerMetrics.
+func WithMetrics(metrics PoolMetrics) Option {
+	return func(eo *EventObserver) {
+		eo.metrics = metrics
+	}
+}
+
+// WithTransport publishes events through transport, and consumes them in a group per
+// subscriber named "<service name>.<subscriber name>", instead of delivering them within
+// the process. Codec defaults to JSONCodec. Pools do not apply to consumed events: each
+// subscriber handles one message at a time and acknowledges it once handled or dead lettered.
+// Close leaves transport open, as other observers may share it: use WithOwnedTransport for
+// the EventObserver to close it.
+func WithTransport(transport Transport, codec Codec) Option {
+	return func(eo *EventObserver) {
+		if codec == nil {
+			codec = JSONCodec
+		}
+		eo.transport = transport
+		eo.codec = codec
+	}
+}
+
+// WithOwnedTransport is WithTransport, with Close closing transport once the consumers stopped.
+func WithOwnedTransport(transport Transport, codec Codec) Option {
+	return func(eo *EventObserver) {
+		WithTransport(transport, codec)(eo)
+		eo.ownsTransport = true
+	}
+}
+
+// WithMiddleware wraps the handlers of every subscriber with middlewares.
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(eo *EventObserver) {
+		eo.middlewares = append(eo.middlewares, middlewares...)
+	}
+}
+
+// WithoutPanicRecovery lets panics of handlers crash the service, e.g. to debug them.
+func WithoutPanicRecovery() Option {
+	return func(eo *EventObserver) {
+		eo.recoverPanics = false
+	}
+}
+
+func NewEventObserver(serviceName string, opts ...Option) *EventObserver {
+	eo := &EventObserver{
+		serviceName:   serviceName,
+		subscribers:   make(map[string][]Subscriber),
+		retryPolicy:   NoRetry,
+		poolConfig:    DefaultPoolConfig,
+		topicPools:    make(map[string]PoolConfig),
+		pools:         make(map[string]*workerPool),
+		metrics:       noopMetrics{},
+		recoverPanics: true,
+		deliveryMode:  DeliveryAsync,
+		stop:          make(chan struct{}),
+	}
+	eo.consumerContext, eo.stopConsumers = context.WithCancel(context.Background())
+	for _, opt := range opts {
+		opt(eo)
 	}
+	if eo.scheduleStore != nil {
+		eo.consumers.Add(1)
+		go eo.runScheduler(eo.consumerContext)
+	}
+	return eo
+}
+
+// Use wraps the handlers of every subscriber with middlewares, including the subscribers
+// already subscribed.
+func (eo *EventObserver) Use(middlewares ...Middleware) {
+	eo.mu.Lock()
+	defer eo.mu.Unlock()
+	eo.middlewares = append(eo.middlewares, middlewares...)
 }
 
-func (eo *EventObserver) Subscribe(topic string, subscriber Subscriber) {
+// Subscribe subscribes to topic, which may be a pattern of dot separated tokens where "*"
+// matches a single token and a trailing ">" one or more tokens, e.g. "order.*" or "order.>".
+// Patterns are passed as-is to transports, which only NATS supports. Subscriber names must
+// be unique within a topic. Subscribers without name get a generated one, depending on the
+// order of Subscribe, unless a transport or WithIdempotencyStore is configured: their groups
+// and processed events are keyed by name, so Subscribe returns ErrUnnamedSubscriber.
+func (eo *EventObserver) Subscribe(topic string, subscriber Subscriber) error {
+	if err := validatePattern(topic); err != nil {
+		return err
+	}
+
+	eo.closeMu.RLock()
+	defer eo.closeMu.RUnlock()
+	if eo.closed {
+		return fmt.Errorf("subscriber %s cannot join topic %s: %w", subscriber.SubscriberName, topic, ErrObserverClosed)
+	}
+
 	eo.mu.Lock()
 	defer eo.mu.Unlock()
+	if subscriber.SubscriberName == "" {
+		if eo.transport != nil || eo.idempotent {
+			return fmt.Errorf("subscriber cannot join topic %s: %w", topic, ErrUnnamedSubscriber)
+		}
+		eo.unnamed++
+		subscriber.SubscriberName = fmt.Sprintf("subscriber-%d", eo.unnamed)
+		subscriber.unnamed = true
+	}
+	subscriber.group = fmt.Sprintf("%s.%s", eo.serviceName, subscriber.SubscriberName)
+	for _, s := range eo.subscribers[topic] {
+		if s.SubscriberName == subscriber.SubscriberName {
+			return fmt.Errorf("subscriber %s cannot join topic %s: %w", subscriber.SubscriberName, topic, ErrDuplicateSubscriber)
+		}
+	}
+
+	if eo.transport != nil {
+		subscriber.stopConsuming = eo.consume(topic, subscriber)
+	} else if eo.deliveryModeOf(subscriber) != DeliverySync {
+		subscriber.pool = eo.poolFor(topic, subscriber)
+	}
 	eo.subscribers[topic] = append(eo.subscribers[topic], subscriber)
 	log.Info("subscriber %s successfully joined topic %s", subscriber.SubscriberName, topic)
+	return nil
 }
 
-func (eo *EventObserver) NotifySubscribers(parentCtx context.Context, event *Event) {
-	eo.mu.RLock()
-	subscribers := eo.subscribers[event.Topic]
-	eo.mu.RUnlock()
+// consume handles the events of topic received from the transport until the EventObserver
+// closes or the returned function is called.
+func (eo *EventObserver) consume(topic string, subscriber Subscriber) context.CancelFunc {
+	group := subscriber.group
+	handler := func(ctx context.Context, msg *Message) error {
+		event, err := eo.decode(msg)
+		if err != nil {
+			// redelivering the message would fail the same way
+			log.WithError(err).Error("failed to decode message %s of topic %s, dropping it", msg.ID, topic)
+			return nil
+		}
+
+		err = eo.deliver(context.WithoutCancel(ctx), subscriber, event)
+		if err != nil && eo.deadLetterStore != nil {
+			return nil
+		}
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(eo.consumerContext)
+	eo.consumers.Add(1)
+	go func() {
+		defer eo.consumers.Done()
+		err := eo.transport.Subscribe(ctx, topic, group, handler)
+		if err != nil {
+			log.WithError(err).Error("subscriber %s stopped consuming topic %s", subscriber.SubscriberName, topic)
+		}
+	}()
+	return cancel
+}
+
+// poolFor returns the pool delivering events to subscriber, creating it on first use. Ordered
+// subscribers always get a pool of their own, with the config of their topic's pool if any.
+// It must be called with eo.mu held.
+func (eo *EventObserver) poolFor(topic string, subscriber Subscriber) *workerPool {
+	name := fmt.Sprintf("%s/%s", topic, subscriber.SubscriberName)
+	ordered := eo.deliveryModeOf(subscriber) == DeliveryOrdered
+	config := eo.poolConfig
+	if subscriber.Pool != nil {
+		config = *subscriber.Pool
+	} else if topicConfig, ok := eo.topicPools[topic]; ok {
+		config = topicConfig
+		if !ordered {
+			name = topic
+		}
+	}
+
+	if pool, ok := eo.pools[name]; ok {
+		return pool
+	}
+	pool := newWorkerPool(name, config, ordered, eo.metrics, eo.stop, eo.deliver)
+	eo.pools[name] = pool
+	return pool
+}
+
+// Publish queues event for every subscriber of its topic, then handles it for the DeliverySync
+// ones. It returns an error joining the subscribers the event could not be queued for,
+// according to their pool's QueueFullPolicy, and the sync handlers which failed, or
+// ErrObserverClosed once Close was called.
+func (eo *EventObserver) Publish(ctx context.Context, event *Event) error {
+	_, err := eo.publish(ctx, event)
+	return err
+}
+
+// publish is Publish, also reporting whether the event reached the transport or the
+// subscribers, even when some of them failed to take or handle it.
+func (eo *EventObserver) publish(ctx context.Context, event *Event) (bool, error) {
+	eo.closeMu.RLock()
+	if eo.closed {
+		eo.closeMu.RUnlock()
+		return false, fmt.Errorf("failed to publish topic %s: %w", event.Topic, ErrObserverClosed)
+	}
+	event.Stamp(ctx, eo.serviceName)
+
+	if eo.transport != nil {
+		eo.closeMu.RUnlock()
+		err := eo.publishToTransport(ctx, event)
+		return err == nil, err
+	}
+
+	// closeMu is released before queueing, so Close is not held back by a publish waiting
+	// for room in a full queue: closing the pools interrupts such waits. Sync handlers run
+	// without it too, so they can publish events themselves.
+	subscribers := eo.subscribersOf(event.Topic)
+	eo.syncDeliveries.Add(1)
+	eo.closeMu.RUnlock()
 
 	log.Info("publishing topic %s to %d subscribers", event.Topic, len(subscribers))
+	var (
+		errs   []error
+		inline []Subscriber
+	)
 	for _, subscriber := range subscribers {
-		go func(s Subscriber) {
-			ctx, cancel := context.WithTimeout(context.WithoutCancel(parentCtx), 30*time.Second)
-			defer cancel()
-			tracer := log.NewTracer(ctx, s.SubscriberName, fmt.Sprintf("EventObserver-%s", eo.serviceName)).WithFields(map[string]any{
-				"subscriber": s.SubscriberName,
-				"topic":      s.TopicName,
-			})
-
-			log.Info("starting event handler for topic %s", event.Topic)
-
-			err := s.HandlerFunc(ctx, event)
-			if err != nil {
-				tracer.WithField("operation", "subscription_handler").Error(log.ServerError, log.Response, err, "got error from subscription %s with topic %s", s.SubscriberName, s.TopicName)
+		if subscriber.pool == nil {
+			inline = append(inline, subscriber)
+			continue
+		}
+		err := subscriber.pool.submit(ctx, subscriber, event)
+		if errors.Is(err, errPoolClosed) {
+			if !eo.isClosed() {
+				continue // unsubscribed since the snapshot
 			}
-			defer tracer.TraceResponse(err)
-		}(subscriber)
+			err = fmt.Errorf("failed to publish topic %s to subscriber %s: %w", event.Topic, subscriber.SubscriberName, ErrObserverClosed)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
 	}
 
+	errs = append(errs, eo.deliverSync(ctx, inline, event))
+	return true, errors.Join(errs...)
+}
+
+func (eo *EventObserver) publishToTransport(ctx context.Context, event *Event) error {
+	msg, err := eo.encode(event)
+	if err != nil {
+		return fmt.Errorf("failed to publish topic %s: %w", event.Topic, err)
+	}
+	if err := eo.transport.Publish(ctx, msg); err != nil {
+		return fmt.Errorf("failed to publish topic %s: %w", event.Topic, err)
+	}
+	return nil
+}
+
+// encode returns event as a message, naming the type of its data in DataTypeHeader for
+// decode.
+func (eo *EventObserver) encode(event *Event) (*Message, error) {
+	var msg *Message
+	if codec, ok := eo.codec.(MessageCodec); ok {
+		var err error
+		if msg, err = codec.EncodeMessage(event); err != nil {
+			return nil, err
+		}
+	} else {
+		payload, err := eo.codec.Encode(event)
+		if err != nil {
+			return nil, err
+		}
+		msg = &Message{
+			Topic:   event.Topic,
+			Key:     event.Key,
+			Payload: payload,
+			Headers: map[string]string{ContentTypeHeader: eo.codec.ContentType()},
+		}
+	}
+
+	if dataType := dataTypeName(reflect.TypeOf(event.Data)); dataType != "" {
+		if msg.Headers == nil {
+			msg.Headers = map[string]string{}
+		}
+		msg.Headers[DataTypeHeader] = dataType
+	}
+	return msg, nil
+}
+
+// decode returns the event of msg, with its data as the type it was published with when
+// registered by RegisterDataType or NewTopic, as handlers get it within the process.
+func (eo *EventObserver) decode(msg *Message) (*Event, error) {
+	var (
+		event *Event
+		err   error
+	)
+	if codec, ok := eo.codec.(MessageCodec); ok {
+		event, err = codec.DecodeMessage(msg)
+	} else {
+		event, err = eo.codec.Decode(msg.Payload)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := decodeData(event, msg.Headers[DataTypeHeader]); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func (eo *EventObserver) NotifySubscribers(ctx context.Context, event *Event) {
+	if err := eo.Publish(ctx, event); err != nil {
+		log.WithError(err).Error("failed to publish topic %s", event.Topic)
+	}
+}
+
+func (eo *EventObserver) retryPolicyOf(s Subscriber) RetryPolicy {
+	if s.RetryPolicy != nil {
+		return *s.RetryPolicy
+	}
+	return eo.retryPolicy
+}
+
+// deliver runs the handler of s until it succeeds, its retry policy is exhausted or the
+// EventObserver stopped, in which case the event is dead lettered.
+func (eo *EventObserver) deliver(ctx context.Context, s Subscriber, event *Event) error {
+	tracer := log.NewTracer(ctx, s.SubscriberName, fmt.Sprintf("EventObserver-%s", eo.serviceName)).WithFields(map[string]any{
+		"subscriber": s.SubscriberName,
+		"topic":      s.TopicName,
+		"event_id":   event.ID,
+	})
+
+	log.Info("starting event handler for topic %s", event.Topic)
+
+	policy := eo.retryPolicyOf(s)
+	maxAttempts := policy.attempts()
+
+	var err error
+	attempt := 1
+	for ; ; attempt++ {
+		err = eo.handle(contextWithAttempt(ctx, attempt), s, event)
+		var panicErr *PanicError
+		if errors.As(err, &panicErr) {
+			if metrics, ok := eo.metrics.(HandlerMetrics); ok {
+				metrics.ObservePanic(event.Topic, s.SubscriberName)
+			}
+			tracer.WithField("attempt", attempt).Error(log.ServerError, log.Response, err, "recovered panic in subscription %s with topic %s", s.SubscriberName, s.TopicName)
+		}
+		if err == nil || attempt >= maxAttempts {
+			break
+		}
+
+		backoff := policy.Backoff(attempt)
+		tracer.WithField("attempt", attempt).Warning(log.ServerError, log.Response, "got error from subscription %s with topic %s, retrying in %s: %s", s.SubscriberName, s.TopicName, backoff, err.Error())
+		if !eo.sleep(backoff) {
+			break
+		}
+	}
+
+	if err != nil {
+		tracer.WithField("operation", "subscription_handler").WithField("attempt", attempt).Error(log.ServerError, log.Response, err, "got error from subscription %s with topic %s", s.SubscriberName, s.TopicName)
+		eo.deadLetter(ctx, s, event, err, attempt)
+	}
+	tracer.TraceResponse(err)
+
+	return err
+}
+
+// handle runs a single attempt of the handler of s, wrapped by the middlewares, recovering
+// their panics unless disabled.
+func (eo *EventObserver) handle(parentCtx context.Context, s Subscriber, event *Event) (err error) {
+	if eo.recoverPanics {
+		defer recoverPanic(s, event, &err)
+	}
+
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = defaultHandlerTimeout
+	}
+	ctx := context.WithValue(parentCtx, subscriberContextKey{}, s)
+	ctx = context.WithValue(ctx, handlingContextKey{}, eo)
+	if event.CorrelationID != "" {
+		ctx = ContextWithCorrelationID(ctx, event.CorrelationID)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	eo.mu.RLock()
+	middlewares := append(append([]Middleware{}, eo.middlewares...), s.Middlewares...)
+	eo.mu.RUnlock()
+
+	return chain(s.HandlerFunc, middlewares...)(ctx, event)
+}
+
+func (eo *EventObserver) deadLetter(ctx context.Context, s Subscriber, event *Event, err error, attempts int) {
+	if eo.deadLetterStore == nil {
+		return
+	}
+
+	deadLetter := &DeadLetter{
+		ID:             uuid.NewString(),
+		Topic:          event.Topic,
+		SubscriberName: s.SubscriberName,
+		Event:          event,
+		Error:          err.Error(),
+		Attempts:       attempts,
+		FailedAt:       time.Now(),
+	}
+	if saveErr := eo.deadLetterStore.Save(ctx, deadLetter); saveErr != nil {
+		log.WithError(saveErr).Error("failed to dead letter event of topic %s for subscriber %s, event is lost", event.Topic, s.SubscriberName)
+	}
+}
+
+// DeadLetters lists the dead lettered events matching filter.
+func (eo *EventObserver) DeadLetters(ctx context.Context, filter DeadLetterFilter) ([]*DeadLetter, error) {
+	if eo.deadLetterStore == nil {
+		return nil, errors.New("event observer has no dead letter store")
+	}
+	return eo.deadLetterStore.List(ctx, filter)
+}
+
+// ReplayDeadLetter delivers a dead lettered event again to the subscriber it failed for,
+// synchronously and with the subscriber's retry policy. The dead letter is removed when the
+// handler succeeds, and updated with the new error otherwise.
+func (eo *EventObserver) ReplayDeadLetter(ctx context.Context, id string) error {
+	if eo.deadLetterStore == nil {
+		return errors.New("event observer has no dead letter store")
+	}
+
+	deadLetter, err := eo.deadLetterStore.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	subscriber, ok := eo.findSubscriber(deadLetter.Topic, deadLetter.SubscriberName)
+	if !ok {
+		return fmt.Errorf("subscriber %s of topic %s is not registered", deadLetter.SubscriberName, deadLetter.Topic)
+	}
+
+	policy := eo.retryPolicyOf(subscriber)
+	for attempt := 1; ; attempt++ {
+		err = eo.handle(contextWithAttempt(ctx, deadLetter.Attempts+attempt), subscriber, deadLetter.Event)
+		if err == nil || attempt >= policy.attempts() {
+			deadLetter.Attempts += attempt
+			break
+		}
+		if !eo.sleep(policy.Backoff(attempt)) {
+			deadLetter.Attempts += attempt
+			break
+		}
+	}
+
+	if err != nil {
+		deadLetter.Error = err.Error()
+		deadLetter.FailedAt = time.Now()
+		if saveErr := eo.deadLetterStore.Save(ctx, deadLetter); saveErr != nil {
+			return errors.Join(err, saveErr)
+		}
+		return err
+	}
+
+	return eo.deadLetterStore.Delete(ctx, id)
+}
+
+// ReplayDeadLetters replays every dead letter matching filter and returns how many succeeded,
+// along with the errors of the ones that failed again.
+func (eo *EventObserver) ReplayDeadLetters(ctx context.Context, filter DeadLetterFilter) (int, error) {
+	deadLetters, err := eo.DeadLetters(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		replayed int
+		errs     []error
+	)
+	for _, deadLetter := range deadLetters {
+		if err := eo.ReplayDeadLetter(ctx, deadLetter.ID); err != nil {
+			errs = append(errs, fmt.Errorf("dead letter %s: %w", deadLetter.ID, err))
+			continue
+		}
+		replayed++
+	}
+	return replayed, errors.Join(errs...)
+}
+
+func (eo *EventObserver) findSubscriber(topic, subscriberName string) (Subscriber, bool) {
+	for _, s := range eo.subscribersOf(topic) {
+		if s.SubscriberName == subscriberName {
+			return s, true
+		}
+	}
+	return Subscriber{}, false
 }
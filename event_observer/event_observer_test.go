@@ -150,3 +150,180 @@ func TestConcurrentSubscribeAndNotify(t *testing.T) {
 	assert.Equal(t, 10, counter)
 	mu.Unlock()
 }
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	testCases := []struct {
+		name     string
+		policy   RetryPolicy
+		attempt  int
+		expected time.Duration
+	}{
+		{
+			name:     "defaults",
+			policy:   RetryPolicy{},
+			attempt:  1,
+			expected: 100 * time.Millisecond,
+		},
+		{
+			name:     "exponential",
+			policy:   RetryPolicy{InitialBackoff: time.Second, Multiplier: 3},
+			attempt:  3,
+			expected: 9 * time.Second,
+		},
+		{
+			name:     "capped",
+			policy:   RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second},
+			attempt:  10,
+			expected: 5 * time.Second,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.policy.Backoff(tc.attempt))
+		})
+	}
+}
+
+func TestRetryPolicyBackoffJitter(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: time.Second, Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		backoff := policy.Backoff(1)
+		assert.GreaterOrEqual(t, backoff, 500*time.Millisecond)
+		assert.LessOrEqual(t, backoff, 1500*time.Millisecond)
+	}
+}
+
+func TestNotifySubscribersRetry(t *testing.T) {
+	store := NewInMemoryDeadLetterStore()
+	eo := NewEventObserver("some-service-name",
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}),
+		WithDeadLetterStore(store),
+	)
+	var attempts int
+	var mu sync.Mutex
+
+	eo.Subscribe("test-topic", Subscriber{
+		TopicName:      "test-topic",
+		SubscriberName: "flaky",
+		HandlerFunc: func(ctx context.Context, event *Event) error {
+			mu.Lock()
+			defer mu.Unlock()
+			attempts++
+			if attempts < 3 {
+				return assert.AnError
+			}
+			return nil
+		},
+	})
+
+	eo.NotifySubscribers(context.Background(), &Event{Topic: "test-topic", Data: "payload"})
+	time.Sleep(50 * time.Millisecond)
+
+	mu.Lock()
+	assert.Equal(t, 3, attempts)
+	mu.Unlock()
+
+	deadLetters, err := eo.DeadLetters(context.Background(), DeadLetterFilter{})
+	assert.NoError(t, err)
+	assert.Empty(t, deadLetters)
+}
+
+func TestNotifySubscribersDeadLetter(t *testing.T) {
+	store := NewInMemoryDeadLetterStore()
+	eo := NewEventObserver("some-service-name", WithDeadLetterStore(store))
+	var attempts int
+	var mu sync.Mutex
+
+	eo.Subscribe("test-topic", Subscriber{
+		TopicName:      "test-topic",
+		SubscriberName: "failing",
+		RetryPolicy:    &RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond},
+		HandlerFunc: func(ctx context.Context, event *Event) error {
+			mu.Lock()
+			defer mu.Unlock()
+			attempts++
+			return assert.AnError
+		},
+	})
+
+	e := &Event{Topic: "test-topic", Data: "payload"}
+	eo.NotifySubscribers(context.Background(), e)
+	time.Sleep(50 * time.Millisecond)
+
+	mu.Lock()
+	assert.Equal(t, 2, attempts)
+	mu.Unlock()
+
+	deadLetters, err := eo.DeadLetters(context.Background(), DeadLetterFilter{Topic: "test-topic"})
+	assert.NoError(t, err)
+	if assert.Len(t, deadLetters, 1) {
+		assert.NotEmpty(t, deadLetters[0].ID)
+		assert.Equal(t, "failing", deadLetters[0].SubscriberName)
+		assert.Equal(t, assert.AnError.Error(), deadLetters[0].Error)
+		assert.Equal(t, 2, deadLetters[0].Attempts)
+		assert.Equal(t, e, deadLetters[0].Event)
+	}
+
+	deadLetters, err = eo.DeadLetters(context.Background(), DeadLetterFilter{SubscriberName: "other"})
+	assert.NoError(t, err)
+	assert.Empty(t, deadLetters)
+}
+
+func TestReplayDeadLetter(t *testing.T) {
+	store := NewInMemoryDeadLetterStore()
+	eo := NewEventObserver("some-service-name", WithDeadLetterStore(store))
+	var fail = true
+	var received []*Event
+	var mu sync.Mutex
+
+	eo.Subscribe("test-topic", Subscriber{
+		TopicName:      "test-topic",
+		SubscriberName: "recovering",
+		HandlerFunc: func(ctx context.Context, event *Event) error {
+			mu.Lock()
+			defer mu.Unlock()
+			received = append(received, event)
+			if fail {
+				return assert.AnError
+			}
+			return nil
+		},
+	})
+
+	ctx := context.Background()
+	err := store.Save(ctx, &DeadLetter{
+		ID:             "dl-1",
+		Topic:          "test-topic",
+		SubscriberName: "recovering",
+		Event:          &Event{Topic: "test-topic", Data: "payload"},
+		Attempts:       1,
+	})
+	assert.NoError(t, err)
+
+	err = eo.ReplayDeadLetter(ctx, "dl-1")
+	assert.ErrorIs(t, err, assert.AnError)
+	deadLetter, err := store.Get(ctx, "dl-1")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, deadLetter.Attempts)
+
+	mu.Lock()
+	fail = false
+	mu.Unlock()
+
+	replayed, err := eo.ReplayDeadLetters(ctx, DeadLetterFilter{Topic: "test-topic"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, replayed)
+	_, err = store.Get(ctx, "dl-1")
+	assert.ErrorIs(t, err, ErrDeadLetterNotFound)
+	assert.Len(t, received, 2)
+
+	assert.ErrorIs(t, eo.ReplayDeadLetter(ctx, "unknown"), ErrDeadLetterNotFound)
+}
+
+func TestDeadLettersWithoutStore(t *testing.T) {
+	eo := NewEventObserver("some-service-name")
+	_, err := eo.DeadLetters(context.Background(), DeadLetterFilter{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,161 @@
+package event_observer
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/NusaCrew/atlas-go/log"
+	"github.com/NusaCrew/atlas-go/storage/redis"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+var ErrUnnamedSubscriber = errors.New("subscriber has no explicit name")
+
+// IdempotencyStore remembers the events each subscriber processed. Subscribers are named
+// <service name>.<subscriber name> by IdempotencyMiddleware, so services sharing a store
+// do not skip the events of each other.
+type IdempotencyStore interface {
+	Processed(ctx context.Context, subscriberName, eventID string) (bool, error)
+	MarkProcessed(ctx context.Context, subscriberName, eventID string) error
+}
+
+// IdempotencyMiddleware skips the events whose ID the subscriber already processed according
+// to store, so duplicates of at-least-once delivery are handled once. Events are marked once
+// their handler succeeded: duplicates delivered while the first one is being handled still run.
+// Subscribers need an explicit SubscriberName, the generated ones depending on the order of
+// Subscribe: their events fail with ErrUnnamedSubscriber. WithIdempotencyStore refuses them
+// at Subscribe instead.
+func IdempotencyMiddleware(store IdempotencyStore) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, event *Event) error {
+			if event.ID == "" {
+				return next(ctx, event)
+			}
+
+			s, ok := SubscriberFromContext(ctx)
+			if !ok || s.unnamed {
+				return fmt.Errorf("failed to check if event %s was processed: %w", event.ID, ErrUnnamedSubscriber)
+			}
+			processed, err := store.Processed(ctx, s.group, event.ID)
+			if err != nil {
+				return fmt.Errorf("failed to check if event %s was processed: %w", event.ID, err)
+			}
+			if processed {
+				log.FromContext(ctx).Info("skipping event %s of topic %s already processed by subscriber %s", event.ID, event.Topic, s.SubscriberName)
+				return nil
+			}
+
+			if err := next(ctx, event); err != nil {
+				return err
+			}
+			if err := store.MarkProcessed(ctx, s.group, event.ID); err != nil {
+				// the event was handled, failing would only handle it again
+				log.FromContext(ctx).WithError(err).Warning("failed to mark event %s of topic %s as processed by subscriber %s", event.ID, event.Topic, s.SubscriberName)
+			}
+			return nil
+		}
+	}
+}
+
+// WithIdempotencyStore wraps the handlers of every subscriber with IdempotencyMiddleware,
+// and makes Subscribe return ErrUnnamedSubscriber for subscribers without name.
+func WithIdempotencyStore(store IdempotencyStore) Option {
+	return func(eo *EventObserver) {
+		eo.middlewares = append(eo.middlewares, IdempotencyMiddleware(store))
+		eo.idempotent = true
+	}
+}
+
+type inMemoryIdempotencyStore struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // most recently processed first
+	entries  map[string]*list.Element
+}
+
+// NewInMemoryIdempotencyStore returns an IdempotencyStore remembering the last capacity
+// processed events of the process, so duplicates are only detected within an instance.
+func NewInMemoryIdempotencyStore(capacity int) IdempotencyStore {
+	if capacity <= 0 {
+		capacity = 10000
+	}
+	return &inMemoryIdempotencyStore{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+func idempotencyKey(subscriberName, eventID string) string {
+	return subscriberName + "/" + eventID
+}
+
+func (s *inMemoryIdempotencyStore) Processed(ctx context.Context, subscriberName, eventID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	element, ok := s.entries[idempotencyKey(subscriberName, eventID)]
+	if ok {
+		s.order.MoveToFront(element)
+	}
+	return ok, nil
+}
+
+func (s *inMemoryIdempotencyStore) MarkProcessed(ctx context.Context, subscriberName, eventID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := idempotencyKey(subscriberName, eventID)
+	if element, ok := s.entries[key]; ok {
+		s.order.MoveToFront(element)
+		return nil
+	}
+	s.entries[key] = s.order.PushFront(key)
+	if s.order.Len() > s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(string))
+	}
+	return nil
+}
+
+type redisIdempotencyStore struct {
+	client redis.RedisClient
+	ttl    time.Duration
+}
+
+// NewRedisIdempotencyStore returns an IdempotencyStore shared by the instances of a service,
+// remembering processed events for ttl, 24h by default.
+func NewRedisIdempotencyStore(client redis.RedisClient, ttl time.Duration) IdempotencyStore {
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	return &redisIdempotencyStore{
+		client: client,
+		ttl:    ttl,
+	}
+}
+
+func (s *redisIdempotencyStore) key(subscriberName, eventID string) string {
+	return "event_observer:processed:" + idempotencyKey(subscriberName, eventID)
+}
+
+func (s *redisIdempotencyStore) Processed(ctx context.Context, subscriberName, eventID string) (bool, error) {
+	_, err := s.client.Get(ctx, s.key(subscriberName, eventID))
+	if errors.Is(err, goredis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *redisIdempotencyStore) MarkProcessed(ctx context.Context, subscriberName, eventID string) error {
+	return s.client.Set(ctx, s.key(subscriberName, eventID), time.Now().Unix(), s.ttl)
+}
@@ -0,0 +1,61 @@
+package event_observer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/NusaCrew/atlas-go/storage/postgres"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+const DefaultProcessedEventTable = "event_processed"
+
+type postgresIdempotencyStore struct {
+	postgres.CommonRepository
+	table string
+}
+
+// NewPostgresIdempotencyStore returns an IdempotencyStore backed by the given table, created
+// by migrations/000003_create_event_processed.up.sql. Rows are kept until deleted, e.g. by a
+// periodic job on processed_at. The subscriber_name column holds
+// <service name>.<subscriber name>.
+func NewPostgresIdempotencyStore(storage postgres.Storage, table string) IdempotencyStore {
+	if table == "" {
+		table = DefaultProcessedEventTable
+	}
+	return &postgresIdempotencyStore{
+		CommonRepository: postgres.CommonRepository{Storage: storage},
+		table:            table,
+	}
+}
+
+func (s *postgresIdempotencyStore) Processed(ctx context.Context, subscriberName, eventID string) (bool, error) {
+	var processed bool
+	err := s.Builder(nil).
+		Select("1").
+		Prefix("SELECT EXISTS (").
+		From(s.table).
+		Where(sq.Eq{"subscriber_name": subscriberName, "event_id": eventID}).
+		Suffix(")").
+		QueryRowContext(ctx).
+		Scan(&processed)
+	if err != nil {
+		return false, fmt.Errorf("failed to check processed event %s: %w", eventID, err)
+	}
+	return processed, nil
+}
+
+func (s *postgresIdempotencyStore) MarkProcessed(ctx context.Context, subscriberName, eventID string) error {
+	_, err := s.Builder(nil).
+		Insert(s.table).
+		Columns("subscriber_name", "event_id", "processed_at").
+		Values(subscriberName, eventID, time.Now()).
+		Suffix("ON CONFLICT (subscriber_name, event_id) DO NOTHING").
+		ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to mark event %s as processed: %w", eventID, err)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package event_observer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	store := NewInMemoryIdempotencyStore(10)
+	eo := NewEventObserver("some-service-name", WithDeliveryMode(DeliverySync), WithMiddleware(IdempotencyMiddleware(store)))
+
+	handled := make(map[string]int)
+	fail := true
+	subscribe := func(name string) {
+		assert.NoError(t, eo.Subscribe("test-topic", Subscriber{
+			SubscriberName: name,
+			HandlerFunc: func(ctx context.Context, event *Event) error {
+				handled[name]++
+				if name == "failing" && fail {
+					return assert.AnError
+				}
+				return nil
+			},
+		}))
+	}
+	subscribe("first")
+	subscribe("failing")
+
+	event := &Event{ID: "event-1", Topic: "test-topic"}
+	assert.ErrorIs(t, eo.Publish(context.Background(), event), assert.AnError)
+	fail = false
+	assert.NoError(t, eo.Publish(context.Background(), event))
+	assert.NoError(t, eo.Publish(context.Background(), event))
+	assert.NoError(t, eo.Publish(context.Background(), &Event{ID: "event-2", Topic: "test-topic"}))
+
+	assert.Equal(t, map[string]int{"first": 2, "failing": 3}, handled, "failed events are not marked as processed")
+
+	processed, err := store.Processed(context.Background(), "some-service-name.first", "event-1")
+	assert.NoError(t, err)
+	assert.True(t, processed, "events are marked under the service name")
+}
+
+func TestIdempotencyMiddleware_UnnamedSubscriber(t *testing.T) {
+	eo := NewEventObserver("some-service-name", WithDeliveryMode(DeliverySync), WithMiddleware(IdempotencyMiddleware(NewInMemoryIdempotencyStore(10))))
+
+	var handled int
+	assert.NoError(t, eo.Subscribe("test-topic", Subscriber{
+		RetryPolicy: &RetryPolicy{MaxAttempts: 1},
+		HandlerFunc: func(ctx context.Context, event *Event) error {
+			handled++
+			return nil
+		},
+	}))
+
+	assert.ErrorIs(t, eo.Publish(context.Background(), &Event{ID: "event-1", Topic: "test-topic"}), ErrUnnamedSubscriber)
+	assert.Zero(t, handled)
+}
+
+func TestSubscribeUnnamedRequiresName(t *testing.T) {
+	testCases := []struct {
+		name   string
+		option Option
+	}{
+		{name: "idempotency store", option: WithIdempotencyStore(NewInMemoryIdempotencyStore(10))},
+		{name: "transport", option: WithTransport(NewMemoryTransport(), nil)},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			eo := NewEventObserver("some-service-name", tc.option)
+			handler := func(ctx context.Context, event *Event) error { return nil }
+
+			err := eo.Subscribe("test-topic", Subscriber{HandlerFunc: handler})
+			assert.ErrorIs(t, err, ErrUnnamedSubscriber)
+			assert.Empty(t, eo.Topics())
+			assert.NoError(t, eo.Subscribe("test-topic", Subscriber{SubscriberName: "named", HandlerFunc: handler}))
+		})
+	}
+}
+
+func TestInMemoryIdempotencyStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryIdempotencyStore(2)
+
+	assert.NoError(t, store.MarkProcessed(ctx, "subscriber", "event-1"))
+	assert.NoError(t, store.MarkProcessed(ctx, "subscriber", "event-2"))
+	processed, err := store.Processed(ctx, "subscriber", "event-1")
+	assert.NoError(t, err)
+	assert.True(t, processed)
+	processed, _ = store.Processed(ctx, "other-subscriber", "event-1")
+	assert.False(t, processed, "events are remembered per subscriber")
+
+	assert.NoError(t, store.MarkProcessed(ctx, "subscriber", "event-3"))
+	processed, _ = store.Processed(ctx, "subscriber", "event-2")
+	assert.False(t, processed, "least recently used event is evicted")
+	processed, _ = store.Processed(ctx, "subscriber", "event-1")
+	assert.True(t, processed)
+}
+
+type fakeRedisClient struct {
+	mu     sync.Mutex
+	values map[string]any
+	ttls   map[string]time.Duration
+	err    error
+}
+
+func (c *fakeRedisClient) Get(ctx context.Context, key string) (any, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return nil, c.err
+	}
+	value, ok := c.values[key]
+	if !ok {
+		return nil, goredis.Nil
+	}
+	return fmt.Sprint(value), nil
+}
+
+func (c *fakeRedisClient) Set(ctx context.Context, key string, value any, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] = value
+	c.ttls[key] = ttl
+	return nil
+}
+
+func (c *fakeRedisClient) Close() error {
+	return nil
+}
+
+func TestRedisIdempotencyStore(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeRedisClient{values: map[string]any{}, ttls: map[string]time.Duration{}}
+	store := NewRedisIdempotencyStore(client, time.Hour)
+
+	processed, err := store.Processed(ctx, "subscriber", "event-1")
+	assert.NoError(t, err)
+	assert.False(t, processed)
+
+	assert.NoError(t, store.MarkProcessed(ctx, "subscriber", "event-1"))
+	processed, err = store.Processed(ctx, "subscriber", "event-1")
+	assert.NoError(t, err)
+	assert.True(t, processed)
+	assert.Equal(t, time.Hour, client.ttls["event_observer:processed:subscriber/event-1"])
+
+	client.err = errors.New("connection refused")
+	_, err = store.Processed(ctx, "subscriber", "event-1")
+	assert.Error(t, err)
+}
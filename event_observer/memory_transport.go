@@ -0,0 +1,124 @@
+package event_observer
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/NusaCrew/atlas-go/log"
+)
+
+const (
+	memoryTransportQueueSize  = 1024
+	memoryTransportRedelivery = 100 * time.Millisecond
+)
+
+var ErrTransportClosed = errors.New("transport is closed")
+
+type memoryTransport struct {
+	mu     sync.Mutex
+	groups map[string]map[string]chan *Message // queues by topic and group
+	closed bool
+	done   chan struct{} // closed by Close, stopping the subscribers
+	seq    atomic.Int64
+}
+
+// NewMemoryTransport returns a Transport within the process, meant for tests. Messages are
+// only delivered to the groups subscribed when they are published, and redelivered after
+// 100ms when their handler fails. Close makes the blocked Subscribe calls return
+// ErrTransportClosed.
+func NewMemoryTransport() Transport {
+	return &memoryTransport{
+		groups: make(map[string]map[string]chan *Message),
+		done:   make(chan struct{}),
+	}
+}
+
+func (t *memoryTransport) queue(topic, group string) (chan *Message, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return nil, ErrTransportClosed
+	}
+	if t.groups[topic] == nil {
+		t.groups[topic] = make(map[string]chan *Message)
+	}
+	if t.groups[topic][group] == nil {
+		t.groups[topic][group] = make(chan *Message, memoryTransportQueueSize)
+	}
+	return t.groups[topic][group], nil
+}
+
+func (t *memoryTransport) Publish(ctx context.Context, msg *Message) error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return ErrTransportClosed
+	}
+	queues := make([]chan *Message, 0, len(t.groups[msg.Topic]))
+	for _, queue := range t.groups[msg.Topic] {
+		queues = append(queues, queue)
+	}
+	t.mu.Unlock()
+
+	id := strconv.FormatInt(t.seq.Add(1), 10)
+	for _, queue := range queues {
+		delivered := &Message{
+			ID:      id,
+			Topic:   msg.Topic,
+			Key:     msg.Key,
+			Payload: msg.Payload,
+			Headers: maps.Clone(msg.Headers),
+		}
+		select {
+		case queue <- delivered:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.done:
+			return ErrTransportClosed
+		}
+	}
+	return nil
+}
+
+func (t *memoryTransport) Subscribe(ctx context.Context, topic, group string, handler MessageHandler) error {
+	queue, err := t.queue(topic, group)
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.done:
+			return ErrTransportClosed
+		case msg := <-queue:
+			if err := handler(ctx, msg); err != nil {
+				time.AfterFunc(memoryTransportRedelivery, func() {
+					select {
+					case queue <- msg:
+					case <-t.done:
+					default:
+						log.Warning("queue of group %s for topic %s is full, dropped message %s", group, topic, msg.ID)
+					}
+				})
+			}
+		}
+	}
+}
+
+func (t *memoryTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.closed {
+		t.closed = true
+		close(t.done)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package event_observer
+
+import (
+	"time"
+
+	"github.com/NusaCrew/atlas-go/internal/promutil"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PoolMetrics records the load of the worker pools.
+type PoolMetrics interface {
+	ObserveQueueDepth(pool string, depth int)
+	ObserveQueueLatency(pool string, latency time.Duration)
+	ObserveOverflow(pool string, policy QueueFullPolicy)
+}
+
+// HandlerMetrics records the handled events with MetricsMiddleware, and the recovered panics
+// when the PoolMetrics of WithMetrics implement it too.
+type HandlerMetrics interface {
+	ObserveHandled(topic, subscriber string, duration time.Duration, err error)
+	ObservePanic(topic, subscriber string)
+}
+
+// Metrics records both the load of the worker pools and the handled events.
+type Metrics interface {
+	PoolMetrics
+	HandlerMetrics
+}
+
+type noopMetrics struct{}
+
+func (noopMetrics) ObserveQueueDepth(string, int)                       {}
+func (noopMetrics) ObserveQueueLatency(string, time.Duration)           {}
+func (noopMetrics) ObserveOverflow(string, QueueFullPolicy)             {}
+func (noopMetrics) ObserveHandled(string, string, time.Duration, error) {}
+func (noopMetrics) ObservePanic(string, string)                         {}
+
+type PrometheusMetricsConfig = promutil.Config
+
+type prometheusMetrics struct {
+	depth    *prometheus.GaugeVec
+	latency  *prometheus.HistogramVec
+	overflow *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	handled  *prometheus.CounterVec
+	panics   *prometheus.CounterVec
+}
+
+// NewPrometheusMetrics registers the event observer metrics:
+//   - <namespace>_event_observer_queue_depth, a gauge of queued events by pool
+//   - <namespace>_event_observer_queue_latency_seconds, a histogram of the time events wait in the queue by pool
+//   - <namespace>_event_observer_queue_overflows_total, a counter of events published to a full queue by pool and policy
+//   - <namespace>_event_observer_handler_duration_seconds, a histogram of handler durations by topic and subscriber
+//   - <namespace>_event_observer_handled_total, a counter of handled events by topic, subscriber and result
+//   - <namespace>_event_observer_panics_total, a counter of recovered handler panics by topic and subscriber
+func NewPrometheusMetrics(config PrometheusMetricsConfig) (Metrics, error) {
+	config = config.WithDefaults()
+	namespace, registerer, buckets := config.Namespace, config.Registerer, config.Buckets
+
+	depth := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "event_observer",
+		Name:      "queue_depth",
+		Help:      "Number of events waiting for a worker.",
+	}, []string{"pool"})
+
+	latency := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "event_observer",
+		Name:      "queue_latency_seconds",
+		Help:      "Time events waited in the queue before a worker picked them up.",
+		Buckets:   buckets,
+	}, []string{"pool"})
+
+	overflow := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "event_observer",
+		Name:      "queue_overflows_total",
+		Help:      "Number of events published to a full queue.",
+	}, []string{"pool", "policy"})
+
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "event_observer",
+		Name:      "handler_duration_seconds",
+		Help:      "Duration of event handlers.",
+		Buckets:   buckets,
+	}, []string{"topic", "subscriber"})
+
+	handled := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "event_observer",
+		Name:      "handled_total",
+		Help:      "Number of handled events, by result: success or error.",
+	}, []string{"topic", "subscriber", "result"})
+
+	panics := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "event_observer",
+		Name:      "panics_total",
+		Help:      "Number of recovered handler panics.",
+	}, []string{"topic", "subscriber"})
+
+	var err error
+	if depth, err = promutil.Register(registerer, depth); err != nil {
+		return nil, err
+	}
+	if latency, err = promutil.Register(registerer, latency); err != nil {
+		return nil, err
+	}
+	if overflow, err = promutil.Register(registerer, overflow); err != nil {
+		return nil, err
+	}
+	if duration, err = promutil.Register(registerer, duration); err != nil {
+		return nil, err
+	}
+	if handled, err = promutil.Register(registerer, handled); err != nil {
+		return nil, err
+	}
+	if panics, err = promutil.Register(registerer, panics); err != nil {
+		return nil, err
+	}
+
+	return &prometheusMetrics{
+		depth:    depth,
+		latency:  latency,
+		overflow: overflow,
+		duration: duration,
+		handled:  handled,
+		panics:   panics,
+	}, nil
+}
+
+func (m *prometheusMetrics) ObserveQueueDepth(pool string, depth int) {
+	m.depth.WithLabelValues(pool).Set(float64(depth))
+}
+
+func (m *prometheusMetrics) ObserveQueueLatency(pool string, latency time.Duration) {
+	m.latency.WithLabelValues(pool).Observe(latency.Seconds())
+}
+
+func (m *prometheusMetrics) ObserveOverflow(pool string, policy QueueFullPolicy) {
+	m.overflow.WithLabelValues(pool, policy.String()).Inc()
+}
+
+func (m *prometheusMetrics) ObserveHandled(topic, subscriber string, duration time.Duration, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	m.duration.WithLabelValues(topic, subscriber).Observe(duration.Seconds())
+	m.handled.WithLabelValues(topic, subscriber, result).Inc()
+}
+
+func (m *prometheusMetrics) ObservePanic(topic, subscriber string) {
+	m.panics.WithLabelValues(topic, subscriber).Inc()
+}
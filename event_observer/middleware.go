@@ -0,0 +1,109 @@
+package event_observer
+
+import (
+	"context"
+	"time"
+
+	"github.com/NusaCrew/atlas-go/log"
+)
+
+// Middleware wraps the handler of a subscriber, e.g. to add behaviour around every attempt.
+type Middleware func(next HandlerFunc) HandlerFunc
+
+const defaultHandlerTimeout = 30 * time.Second
+
+type subscriberContextKey struct{}
+
+// SubscriberFromContext returns the subscriber whose handler is running with ctx.
+func SubscriberFromContext(ctx context.Context) (Subscriber, bool) {
+	s, ok := ctx.Value(subscriberContextKey{}).(Subscriber)
+	return s, ok
+}
+
+// handlingContextKey holds the EventObserver running the handler of the context.
+type handlingContextKey struct{}
+
+type attemptContextKey struct{}
+
+// AttemptFromContext returns the number of the attempt, starting at 1, of the handler
+// running with ctx.
+func AttemptFromContext(ctx context.Context) int {
+	if attempt, ok := ctx.Value(attemptContextKey{}).(int); ok {
+		return attempt
+	}
+	return 1
+}
+
+func contextWithAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, attemptContextKey{}, attempt)
+}
+
+// chain wraps handler so the first middleware runs first.
+func chain(handler HandlerFunc, middlewares ...Middleware) HandlerFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// RecoverMiddleware turns a panic of the handler into a *PanicError, so the event goes
+// through the retry and dead letter path instead of crashing the service. The EventObserver
+// already recovers panics unless created WithoutPanicRecovery.
+func RecoverMiddleware() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, event *Event) (err error) {
+			s, _ := SubscriberFromContext(ctx)
+			defer recoverPanic(s, event, &err)
+			return next(ctx, event)
+		}
+	}
+}
+
+// TimeoutMiddleware cancels the context of the handler after timeout.
+func TimeoutMiddleware(timeout time.Duration) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, event *Event) error {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			return next(ctx, event)
+		}
+	}
+}
+
+// MetricsMiddleware records the duration and result of every attempt in metrics.
+func MetricsMiddleware(metrics HandlerMetrics) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, event *Event) error {
+			s, _ := SubscriberFromContext(ctx)
+			start := time.Now()
+			err := next(ctx, event)
+			metrics.ObserveHandled(event.Topic, s.SubscriberName, time.Since(start), err)
+			return err
+		}
+	}
+}
+
+// LoggingMiddleware logs the start and the result of every attempt.
+func LoggingMiddleware() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, event *Event) error {
+			s, _ := SubscriberFromContext(ctx)
+			logger := log.FromContext(ctx).WithFields(map[string]any{
+				"subscriber": s.SubscriberName,
+				"topic":      event.Topic,
+				"event_id":   event.ID,
+			})
+
+			logger.Info("starting event handler for topic %s", event.Topic)
+			start := time.Now()
+			err := next(ctx, event)
+			duration := time.Since(start).Milliseconds()
+			if err != nil {
+				logger.WithError(err).Warning("event handler for topic %s failed after %dms", event.Topic, duration)
+				return err
+			}
+			logger.Info("event handler for topic %s succeeded after %dms", event.Topic, duration)
+			return nil
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package event_observer
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NusaCrew/atlas-go/log"
+	"github.com/NusaCrew/atlas-go/log/logtest"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func recordingMiddleware(name string, mu *sync.Mutex, calls *[]string) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, event *Event) error {
+			mu.Lock()
+			*calls = append(*calls, name)
+			mu.Unlock()
+			return next(ctx, event)
+		}
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+
+	eo := NewEventObserver("some-service-name", WithMiddleware(recordingMiddleware("option", &mu, &calls)))
+	eo.Subscribe("test-topic", Subscriber{
+		TopicName:      "test-topic",
+		SubscriberName: "subscriber",
+		Middlewares:    []Middleware{recordingMiddleware("subscriber", &mu, &calls)},
+		HandlerFunc: func(ctx context.Context, event *Event) error {
+			s, ok := SubscriberFromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, "subscriber", s.SubscriberName)
+
+			mu.Lock()
+			calls = append(calls, "handler")
+			mu.Unlock()
+			return nil
+		},
+	})
+	eo.Use(recordingMiddleware("use", &mu, &calls))
+
+	s, _ := eo.findSubscriber("test-topic", "subscriber")
+	assert.NoError(t, eo.handle(context.Background(), s, &Event{Topic: "test-topic"}))
+
+	mu.Lock()
+	assert.Equal(t, []string{"option", "use", "subscriber", "handler"}, calls)
+	mu.Unlock()
+}
+
+func TestSubscriberTimeout(t *testing.T) {
+	testCases := []struct {
+		name     string
+		timeout  time.Duration
+		expected time.Duration
+	}{
+		{
+			name:     "default",
+			expected: defaultHandlerTimeout,
+		},
+		{
+			name:     "subscriber",
+			timeout:  time.Minute,
+			expected: time.Minute,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			eo := NewEventObserver("some-service-name")
+			var remaining time.Duration
+			s := Subscriber{
+				TopicName: "test-topic",
+				Timeout:   tc.timeout,
+				HandlerFunc: func(ctx context.Context, event *Event) error {
+					deadline, _ := ctx.Deadline()
+					remaining = time.Until(deadline)
+					return nil
+				},
+			}
+
+			assert.NoError(t, eo.handle(context.Background(), s, &Event{Topic: "test-topic"}))
+			assert.InDelta(t, tc.expected, remaining, float64(time.Second))
+		})
+	}
+}
+
+func TestRecoverMiddleware(t *testing.T) {
+	handler := chain(func(ctx context.Context, event *Event) error {
+		panic("boom")
+	}, RecoverMiddleware())
+
+	err := handler(context.Background(), &Event{Topic: "test-topic"})
+	var panicErr *PanicError
+	if assert.ErrorAs(t, err, &panicErr) {
+		assert.Equal(t, "boom", panicErr.Value)
+	}
+}
+
+func TestTimeoutMiddleware(t *testing.T) {
+	handler := chain(func(ctx context.Context, event *Event) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, TimeoutMiddleware(time.Millisecond))
+
+	assert.ErrorIs(t, handler(context.Background(), &Event{}), context.DeadlineExceeded)
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	metrics, err := NewPrometheusMetrics(PrometheusMetricsConfig{Registerer: prometheus.NewRegistry()})
+	assert.NoError(t, err)
+
+	eo := NewEventObserver("some-service-name", WithMiddleware(MetricsMiddleware(metrics)))
+	s := Subscriber{
+		TopicName:      "test-topic",
+		SubscriberName: "subscriber",
+		HandlerFunc: func(ctx context.Context, event *Event) error {
+			if event.Data == "fail" {
+				return assert.AnError
+			}
+			return nil
+		},
+	}
+
+	assert.NoError(t, eo.handle(context.Background(), s, &Event{Topic: "test-topic"}))
+	assert.Error(t, eo.handle(context.Background(), s, &Event{Topic: "test-topic", Data: "fail"}))
+
+	handled := metrics.(*prometheusMetrics).handled
+	assert.Equal(t, float64(1), testutil.ToFloat64(handled.WithLabelValues("test-topic", "subscriber", "success")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(handled.WithLabelValues("test-topic", "subscriber", "error")))
+}
+
+func TestLoggingMiddleware(t *testing.T) {
+	recorder := logtest.Capture(t, "some-service-name")
+
+	eo := NewEventObserver("some-service-name", WithMiddleware(LoggingMiddleware()))
+	s := Subscriber{
+		TopicName:      "test-topic",
+		SubscriberName: "subscriber",
+		HandlerFunc: func(ctx context.Context, event *Event) error {
+			return assert.AnError
+		},
+	}
+	assert.Error(t, eo.handle(context.Background(), s, &Event{Topic: "test-topic"}))
+
+	logtest.Logged(t, recorder, log.INFO, "starting event handler for topic test-topic")
+	logtest.LoggedWithField(t, recorder, "subscriber", "subscriber")
+	logtest.Logged(t, recorder, log.WARNING, "event handler for topic test-topic failed")
+}
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS event_dead_letters (
+    id              TEXT PRIMARY KEY,
+    topic           TEXT        NOT NULL,
+    subscriber_name TEXT        NOT NULL,
+    event           JSONB       NOT NULL,
+    data_type       TEXT        NOT NULL DEFAULT '',
+    error           TEXT        NOT NULL,
+    attempts        INTEGER     NOT NULL,
+    failed_at       TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_dead_letters_topic_subscriber ON event_dead_letters (topic, subscriber_name);
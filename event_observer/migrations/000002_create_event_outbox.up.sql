@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS event_outbox (
+    id           TEXT PRIMARY KEY,
+    topic        TEXT        NOT NULL,
+    event        JSONB       NOT NULL,
+    attempts     INTEGER     NOT NULL DEFAULT 0,
+    last_error   TEXT,
+    locked_until TIMESTAMPTZ NOT NULL DEFAULT '-infinity',
+    dead_at      TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_outbox_created_at ON event_outbox (created_at) WHERE dead_at IS NULL;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS event_processed (
+    subscriber_name TEXT        NOT NULL,
+    event_id        TEXT        NOT NULL,
+    processed_at    TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (subscriber_name, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_processed_processed_at ON event_processed (processed_at);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS event_schedules (
+    id           TEXT PRIMARY KEY,
+    topic        TEXT        NOT NULL,
+    event        JSONB       NOT NULL,
+    data_type    TEXT        NOT NULL DEFAULT '',
+    deliver_at   TIMESTAMPTZ NOT NULL,
+    attempts     INTEGER     NOT NULL DEFAULT 0,
+    last_error   TEXT,
+    locked_until TIMESTAMPTZ NOT NULL DEFAULT '-infinity',
+    dead_at      TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_schedules_deliver_at ON event_schedules (deliver_at) WHERE dead_at IS NULL;
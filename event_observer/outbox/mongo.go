@@ -0,0 +1,203 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	eo "github.com/NusaCrew/atlas-go/event_observer"
+	"github.com/NusaCrew/atlas-go/log"
+	"github.com/NusaCrew/atlas-go/storage/mongo"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	mongodriver "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const DefaultCollection = "event_outbox"
+
+type MongoConfig struct {
+	// Collection defaults to DefaultCollection.
+	Collection string
+	// Lease is how long a relay owns the record it dispatches before another relay may take
+	// it over, defaults to 30s.
+	Lease time.Duration
+	// RetryPolicy of the records failing to be published, defaults to DefaultRetryPolicy.
+	RetryPolicy *eo.RetryPolicy
+}
+
+type mongoRecord struct {
+	ID          string     `bson:"_id"`
+	Topic       string     `bson:"topic"`
+	Event       []byte     `bson:"event"`
+	Attempts    int        `bson:"attempts"`
+	LastError   string     `bson:"last_error,omitempty"`
+	CreatedAt   time.Time  `bson:"created_at"`
+	LockedUntil time.Time  `bson:"locked_until"`
+	DeadAt      *time.Time `bson:"dead_at,omitempty"`
+}
+
+type MongoStore struct {
+	mongo.CommonRepository
+	collection  string
+	lease       time.Duration
+	retryPolicy eo.RetryPolicy
+}
+
+func NewMongoStore(storage mongo.Storage, config MongoConfig) *MongoStore {
+	if config.Collection == "" {
+		config.Collection = DefaultCollection
+	}
+	if config.Lease <= 0 {
+		config.Lease = 30 * time.Second
+	}
+	if config.RetryPolicy == nil {
+		config.RetryPolicy = &DefaultRetryPolicy
+	}
+	return &MongoStore{
+		CommonRepository: mongo.CommonRepository{Storage: storage},
+		collection:       config.Collection,
+		lease:            config.Lease,
+		retryPolicy:      *config.RetryPolicy,
+	}
+}
+
+// Add writes events to the outbox. Pass the session context of RunInTransaction, so they are
+// relayed only if the transaction commits.
+func (s *MongoStore) Add(ctx context.Context, events ...*eo.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	documents := make([]any, 0, len(events))
+	for _, event := range events {
+		event.Stamp(ctx, "")
+		payload, err := eo.JSONCodec.Encode(event)
+		if err != nil {
+			return err
+		}
+		documents = append(documents, mongoRecord{
+			ID:        uuid.NewString(),
+			Topic:     event.Topic,
+			Event:     payload,
+			CreatedAt: now,
+		})
+	}
+
+	if _, err := s.GetCollection(s.collection).InsertMany(ctx, documents); err != nil {
+		return fmt.Errorf("failed to add events to outbox: %w", err)
+	}
+	return nil
+}
+
+// Dispatch leases the records one by one, so relays of several instances dispatch different
+// records.
+func (s *MongoStore) Dispatch(ctx context.Context, limit int, publish PublishFunc) (int, error) {
+	collection := s.GetCollection(s.collection)
+
+	var published int
+	for i := 0; i < limit; i++ {
+		now := time.Now()
+		var doc mongoRecord
+		err := collection.FindOneAndUpdate(ctx,
+			pendingFilter(now),
+			bson.M{"$set": bson.M{"locked_until": now.Add(s.lease)}},
+			options.FindOneAndUpdate().SetSort(bson.D{{Key: "created_at", Value: 1}}),
+		).Decode(&doc)
+		if errors.Is(err, mongodriver.ErrNoDocuments) {
+			break
+		}
+		if err != nil {
+			return published, fmt.Errorf("failed to lease outbox record: %w", err)
+		}
+
+		event, err := eo.JSONCodec.Decode(doc.Event)
+		if err != nil {
+			// no attempt can publish it, park it right away
+			if err := s.fail(ctx, &doc, err, false); err != nil {
+				return published, err
+			}
+			continue
+		}
+		record := &Record{
+			ID:        doc.ID,
+			Event:     event,
+			Attempts:  doc.Attempts,
+			LastError: doc.LastError,
+			CreatedAt: doc.CreatedAt,
+		}
+
+		if err := publish(ctx, record); err != nil {
+			// the backoff keeps the record from being leased again by this dispatch
+			if err := s.fail(ctx, &doc, err, true); err != nil {
+				return published, err
+			}
+			continue
+		}
+
+		if _, err := collection.DeleteOne(ctx, bson.M{"_id": doc.ID}); err != nil {
+			return published, fmt.Errorf("failed to delete published outbox record %s: %w", doc.ID, err)
+		}
+		published++
+	}
+	return published, nil
+}
+
+func pendingFilter(now time.Time) bson.M {
+	return bson.M{
+		"dead_at":      nil,
+		"locked_until": bson.M{"$lte": now},
+	}
+}
+
+func (s *MongoStore) fail(ctx context.Context, doc *mongoRecord, err error, retryable bool) error {
+	if _, updateErr := s.GetCollection(s.collection).UpdateOne(ctx, bson.M{"_id": doc.ID}, s.failUpdate(doc, err, retryable, time.Now())); updateErr != nil {
+		return fmt.Errorf("failed to update outbox record %s: %w", doc.ID, updateErr)
+	}
+	return nil
+}
+
+// failUpdate schedules the next attempt of the record after the backoff of the retry policy,
+// or parks it when the policy is exhausted or the error is not retryable.
+func (s *MongoStore) failUpdate(doc *mongoRecord, err error, retryable bool, now time.Time) bson.M {
+	set := bson.M{"last_error": err.Error()}
+	if next, ok := retryAt(s.retryPolicy, doc.Attempts+1, now); ok && retryable {
+		set["locked_until"] = next
+	} else {
+		log.WithError(err).Error("parked outbox record %s of topic %s after %d attempts", doc.ID, doc.Topic, doc.Attempts+1)
+		set["dead_at"] = now
+	}
+	return bson.M{
+		"$inc": bson.M{"attempts": 1},
+		"$set": set,
+	}
+}
+
+// Listen implements Listener with a change stream on inserts, which requires a replica set
+// like transactions do.
+func (s *MongoStore) Listen(ctx context.Context) (<-chan struct{}, error) {
+	pipeline := mongodriver.Pipeline{bson.D{{Key: "$match", Value: bson.M{"operationType": "insert"}}}}
+	stream, err := s.GetCollection(s.collection).Watch(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch %s: %w", s.collection, err)
+	}
+
+	notifications := make(chan struct{}, 1)
+	go func() {
+		defer close(notifications)
+		defer stream.Close(context.WithoutCancel(ctx))
+		for stream.Next(ctx) {
+			select {
+			case notifications <- struct{}{}:
+			default:
+			}
+		}
+		if err := stream.Err(); err != nil && ctx.Err() == nil {
+			log.WithError(err).Error("outbox change stream of %s stopped", s.collection)
+		}
+	}()
+	return notifications, nil
+}
@@ -0,0 +1,56 @@
+package outbox
+
+import (
+	"testing"
+	"time"
+
+	eo "github.com/NusaCrew/atlas-go/event_observer"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestMongoStorePendingFilter(t *testing.T) {
+	now := time.Now()
+	assert.Equal(t, bson.M{"dead_at": nil, "locked_until": bson.M{"$lte": now}}, pendingFilter(now))
+}
+
+func TestMongoStoreFailUpdate(t *testing.T) {
+	store := NewMongoStore(nil, MongoConfig{RetryPolicy: &eo.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Second}})
+	now := time.Now()
+
+	testCases := []struct {
+		name      string
+		attempts  int
+		retryable bool
+		set       bson.M
+	}{
+		{
+			name:      "backoff",
+			attempts:  1,
+			retryable: true,
+			set:       bson.M{"last_error": assert.AnError.Error(), "locked_until": now.Add(2 * time.Second)},
+		},
+		{
+			name:      "exhausted",
+			attempts:  2,
+			retryable: true,
+			set:       bson.M{"last_error": assert.AnError.Error(), "dead_at": now},
+		},
+		{
+			name:      "not retryable",
+			attempts:  0,
+			retryable: false,
+			set:       bson.M{"last_error": assert.AnError.Error(), "dead_at": now},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			doc := &mongoRecord{ID: "some-id", Topic: "some.topic", Attempts: tc.attempts}
+			update := store.failUpdate(doc, assert.AnError, tc.retryable, now)
+			assert.Equal(t, bson.M{"$inc": bson.M{"attempts": 1}, "$set": tc.set}, update)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	eo "github.com/NusaCrew/atlas-go/event_observer"
+)
+
+// Record is an event written to the outbox, waiting to be published by the Relay.
+type Record struct {
+	ID        string
+	Event     *eo.Event
+	Attempts  int
+	LastError string
+	CreatedAt time.Time
+}
+
+// DefaultRetryPolicy retries a failing record for about two hours before parking it.
+var DefaultRetryPolicy = eo.RetryPolicy{
+	MaxAttempts:    20,
+	InitialBackoff: time.Second,
+	MaxBackoff:     10 * time.Minute,
+	Jitter:         0.2,
+}
+
+// PublishFunc publishes the event of a record. Records are removed from the outbox once it
+// returns nil, and dispatched again after the backoff of the retry policy of the store
+// otherwise. Records exhausting the policy are parked: they stay in the outbox with dead_at
+// set and are not dispatched anymore, until dead_at is cleared.
+type PublishFunc func(ctx context.Context, record *Record) error
+
+// Store is the outbox table or collection events are written to within the transaction of
+// the changes they describe.
+type Store interface {
+	// Dispatch passes up to limit pending records to publish, oldest first, and returns how
+	// many were published. Records being dispatched by another relay, waiting for their
+	// backoff or parked are skipped.
+	Dispatch(ctx context.Context, limit int, publish PublishFunc) (int, error)
+}
+
+// Listener is implemented by stores able to signal new records, so the relay does not wait
+// for its next poll. The channel is closed when ctx is done.
+type Listener interface {
+	Listen(ctx context.Context) (<-chan struct{}, error)
+}
+
+// Publisher publishes the relayed events, like an EventObserver with or without transport.
+type Publisher interface {
+	Publish(ctx context.Context, event *eo.Event) error
+}
+
+// retryAt returns when a record failing its attempt-th attempt is dispatched again, or false
+// when the policy is exhausted and the record is parked.
+func retryAt(policy eo.RetryPolicy, attempt int, now time.Time) (time.Time, bool) {
+	if attempt >= max(policy.MaxAttempts, 1) {
+		return time.Time{}, false
+	}
+	return now.Add(policy.Backoff(attempt)), true
+}
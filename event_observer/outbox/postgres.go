@@ -0,0 +1,215 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	eo "github.com/NusaCrew/atlas-go/event_observer"
+	"github.com/NusaCrew/atlas-go/log"
+	"github.com/NusaCrew/atlas-go/storage/postgres"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const DefaultTable = "event_outbox"
+
+type PostgresConfig struct {
+	// Table is created by event_observer/migrations/000002_create_event_outbox.up.sql,
+	// defaults to DefaultTable.
+	Table string
+	// ListenDSN is the connection string used to LISTEN for new records. The relay only
+	// polls when empty.
+	ListenDSN string
+	// RetryPolicy of the records failing to be published, defaults to DefaultRetryPolicy.
+	RetryPolicy *eo.RetryPolicy
+}
+
+type PostgresStore struct {
+	postgres.CommonRepository
+	table       string
+	listenDSN   string
+	retryPolicy eo.RetryPolicy
+}
+
+func NewPostgresStore(storage postgres.Storage, config PostgresConfig) *PostgresStore {
+	if config.Table == "" {
+		config.Table = DefaultTable
+	}
+	if config.RetryPolicy == nil {
+		config.RetryPolicy = &DefaultRetryPolicy
+	}
+	return &PostgresStore{
+		CommonRepository: postgres.CommonRepository{Storage: storage},
+		table:            config.Table,
+		listenDSN:        config.ListenDSN,
+		retryPolicy:      *config.RetryPolicy,
+	}
+}
+
+// Add writes events to the outbox within tx, usually the one of RunInSQLTransaction, so they
+// are relayed only if the transaction commits.
+func (s *PostgresStore) Add(ctx context.Context, tx *sql.Tx, events ...*eo.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	builder := s.Builder(tx).Insert(s.table).Columns("id", "topic", "event", "created_at")
+	for _, event := range events {
+		event.Stamp(ctx, "")
+		payload, err := eo.JSONCodec.Encode(event)
+		if err != nil {
+			return err
+		}
+		builder = builder.Values(uuid.NewString(), event.Topic, payload, now)
+	}
+
+	if _, err := builder.ExecContext(ctx); err != nil {
+		return fmt.Errorf("failed to add events to outbox: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "SELECT pg_notify($1, '')", s.table); err != nil {
+		return fmt.Errorf("failed to notify outbox: %w", err)
+	}
+	return nil
+}
+
+// Dispatch locks the records with FOR UPDATE SKIP LOCKED, so relays of several instances
+// dispatch different records.
+func (s *PostgresStore) Dispatch(ctx context.Context, limit int, publish PublishFunc) (int, error) {
+	var published int
+	err := s.RunInSQLTransaction(ctx, sql.LevelReadCommitted, func(tx *sql.Tx) error {
+		published = 0
+		records, err := s.lockPending(ctx, tx, limit)
+		if err != nil {
+			return err
+		}
+
+		var publishedIDs []string
+		for _, record := range records {
+			if record.Event, err = eo.JSONCodec.Decode(record.payload); err != nil {
+				// no attempt can publish it, park it right away
+				if err := s.fail(ctx, tx, record, err, false); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := publish(ctx, &record.Record); err != nil {
+				if err := s.fail(ctx, tx, record, err, true); err != nil {
+					return err
+				}
+				continue
+			}
+			publishedIDs = append(publishedIDs, record.ID)
+		}
+
+		if len(publishedIDs) > 0 {
+			if _, err := s.Builder(tx).Delete(s.table).Where(sq.Eq{"id": publishedIDs}).ExecContext(ctx); err != nil {
+				return fmt.Errorf("failed to delete published outbox records: %w", err)
+			}
+		}
+		published = len(publishedIDs)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return published, nil
+}
+
+type postgresRecord struct {
+	Record
+	topic   string
+	payload []byte
+}
+
+func (s *PostgresStore) fail(ctx context.Context, tx *sql.Tx, record *postgresRecord, err error, retryable bool) error {
+	if _, updateErr := s.failQuery(tx, record, err, retryable, time.Now()).ExecContext(ctx); updateErr != nil {
+		return fmt.Errorf("failed to update outbox record %s: %w", record.ID, updateErr)
+	}
+	return nil
+}
+
+// failQuery schedules the next attempt of the record after the backoff of the retry policy,
+// or parks it when the policy is exhausted or the error is not retryable.
+func (s *PostgresStore) failQuery(tx *sql.Tx, record *postgresRecord, err error, retryable bool, now time.Time) sq.UpdateBuilder {
+	query := s.Builder(tx).Update(s.table).
+		Set("attempts", sq.Expr("attempts + 1")).
+		Set("last_error", err.Error()).
+		Where(sq.Eq{"id": record.ID})
+
+	if next, ok := retryAt(s.retryPolicy, record.Attempts+1, now); ok && retryable {
+		return query.Set("locked_until", next)
+	}
+	log.WithError(err).Error("parked outbox record %s of topic %s after %d attempts", record.ID, record.topic, record.Attempts+1)
+	return query.Set("dead_at", now)
+}
+
+func (s *PostgresStore) pendingQuery(tx *sql.Tx, limit int, now time.Time) sq.SelectBuilder {
+	return s.Builder(tx).
+		Select("id", "topic", "event", "attempts", "COALESCE(last_error, '')", "created_at").
+		From(s.table).
+		Where(sq.Eq{"dead_at": nil}).
+		Where(sq.LtOrEq{"locked_until": now}).
+		OrderBy("created_at").
+		Limit(uint64(limit)).
+		Suffix("FOR UPDATE SKIP LOCKED")
+}
+
+func (s *PostgresStore) lockPending(ctx context.Context, tx *sql.Tx, limit int) ([]*postgresRecord, error) {
+	rows, err := s.pendingQuery(tx, limit, time.Now()).QueryContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select outbox records: %w", err)
+	}
+	defer rows.Close()
+
+	var records []*postgresRecord
+	for rows.Next() {
+		var record postgresRecord
+		if err := rows.Scan(&record.ID, &record.topic, &record.payload, &record.Attempts, &record.LastError, &record.CreatedAt); err != nil {
+			return nil, err
+		}
+		records = append(records, &record)
+	}
+	return records, rows.Err()
+}
+
+// Listen implements Listener with LISTEN on the channel named after the table, when
+// ListenDSN is set.
+func (s *PostgresStore) Listen(ctx context.Context) (<-chan struct{}, error) {
+	if s.listenDSN == "" {
+		return nil, nil
+	}
+
+	listener := pq.NewListener(s.listenDSN, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.WithError(err).Warning("outbox listener connection event %d", event)
+		}
+	})
+	if err := listener.Listen(s.table); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to listen to %s: %w", s.table, err)
+	}
+
+	notifications := make(chan struct{}, 1)
+	go func() {
+		defer close(notifications)
+		defer listener.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-listener.Notify:
+				// a nil notification follows reconnections, when records may have been missed
+				select {
+				case notifications <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return notifications, nil
+}
@@ -0,0 +1,74 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	eo "github.com/NusaCrew/atlas-go/event_observer"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeStorage struct{}
+
+func (fakeStorage) DB() *sql.DB                    { return nil }
+func (fakeStorage) Ping(ctx context.Context) error { return nil }
+func (fakeStorage) Close() error                   { return nil }
+
+func TestPostgresStorePendingQuery(t *testing.T) {
+	store := NewPostgresStore(fakeStorage{}, PostgresConfig{})
+	now := time.Now()
+
+	query, args, err := store.pendingQuery(nil, 10, now).ToSql()
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT id, topic, event, attempts, COALESCE(last_error, ''), created_at FROM event_outbox WHERE dead_at IS NULL AND locked_until <= $1 ORDER BY created_at LIMIT 10 FOR UPDATE SKIP LOCKED", query)
+	assert.Equal(t, []any{now}, args)
+}
+
+func TestPostgresStoreFailQuery(t *testing.T) {
+	store := NewPostgresStore(fakeStorage{}, PostgresConfig{RetryPolicy: &eo.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Second}})
+	now := time.Now()
+
+	testCases := []struct {
+		name      string
+		attempts  int
+		retryable bool
+		query     string
+		args      []any
+	}{
+		{
+			name:      "backoff",
+			attempts:  1,
+			retryable: true,
+			query:     "UPDATE event_outbox SET attempts = attempts + 1, last_error = $1, locked_until = $2 WHERE id = $3",
+			args:      []any{assert.AnError.Error(), now.Add(2 * time.Second), "some-id"},
+		},
+		{
+			name:      "exhausted",
+			attempts:  2,
+			retryable: true,
+			query:     "UPDATE event_outbox SET attempts = attempts + 1, last_error = $1, dead_at = $2 WHERE id = $3",
+			args:      []any{assert.AnError.Error(), now, "some-id"},
+		},
+		{
+			name:      "not retryable",
+			attempts:  0,
+			retryable: false,
+			query:     "UPDATE event_outbox SET attempts = attempts + 1, last_error = $1, dead_at = $2 WHERE id = $3",
+			args:      []any{assert.AnError.Error(), now, "some-id"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			record := &postgresRecord{Record: Record{ID: "some-id", Attempts: tc.attempts}, topic: "some.topic"}
+			query, args, err := store.failQuery(nil, record, assert.AnError, tc.retryable, now).ToSql()
+			assert.NoError(t, err)
+			assert.Equal(t, tc.query, query)
+			assert.Equal(t, tc.args, args)
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/NusaCrew/atlas-go/log"
+)
+
+type RelayConfig struct {
+	Store     Store
+	Publisher Publisher
+	// BatchSize is the number of records dispatched at once, defaults to 100.
+	BatchSize int
+	// PollInterval is the wait between dispatches when the outbox is empty, defaults to 1s.
+	// Stores implementing Listener wake the relay up as soon as records are added.
+	PollInterval time.Duration
+}
+
+// Relay publishes the records of an outbox, at least once: a record published right before
+// the relay stops may be published again by the next dispatch.
+type Relay struct {
+	config RelayConfig
+	cancel context.CancelFunc
+	done   chan struct{}
+	once   sync.Once
+}
+
+func NewRelay(config RelayConfig) (*Relay, error) {
+	if config.Store == nil {
+		return nil, errors.New("cannot create outbox relay without store")
+	}
+	if config.Publisher == nil {
+		return nil, errors.New("cannot create outbox relay without publisher")
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = time.Second
+	}
+
+	return &Relay{
+		config: config,
+		done:   make(chan struct{}),
+	}, nil
+}
+
+// DispatchPending publishes the pending records until the outbox is empty or a batch fails
+// entirely, and returns how many were published.
+func (r *Relay) DispatchPending(ctx context.Context) (int, error) {
+	var total int
+	for {
+		var failed int
+		dispatched, err := r.config.Store.Dispatch(ctx, r.config.BatchSize, func(ctx context.Context, record *Record) error {
+			if err := r.config.Publisher.Publish(ctx, record.Event); err != nil {
+				failed++
+				log.WithError(err).Error("failed to relay outbox record %s of topic %s, attempt %d", record.ID, record.Event.Topic, record.Attempts+1)
+				return err
+			}
+			return nil
+		})
+		total += dispatched
+		if err != nil {
+			return total, fmt.Errorf("failed to dispatch outbox: %w", err)
+		}
+		if dispatched == 0 || dispatched+failed < r.config.BatchSize {
+			return total, nil
+		}
+	}
+}
+
+// Run implements webserver.WebServer, so the relay runs and stops along the servers of
+// RunServersCommand.
+func (r *Relay) Run(ctx context.Context, errorChannel chan error) {
+	ctx, r.cancel = context.WithCancel(ctx)
+	go func() {
+		defer close(r.done)
+		if err := r.run(ctx); err != nil {
+			errorChannel <- err
+		}
+	}()
+}
+
+func (r *Relay) run(ctx context.Context) error {
+	var notifications <-chan struct{}
+	if listener, ok := r.config.Store.(Listener); ok {
+		var err error
+		if notifications, err = listener.Listen(ctx); err != nil {
+			return fmt.Errorf("failed to listen to outbox: %w", err)
+		}
+	}
+
+	ticker := time.NewTicker(r.config.PollInterval)
+	defer ticker.Stop()
+
+	log.Info("starting outbox relay")
+	for {
+		if _, err := r.DispatchPending(ctx); err != nil && ctx.Err() == nil {
+			log.WithError(err).Error("failed to relay outbox")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case _, ok := <-notifications:
+			if !ok {
+				notifications = nil
+			}
+		}
+	}
+}
+
+func (r *Relay) GetName() string {
+	return "Outbox Relay"
+}
+
+// Stop stops the relay and waits for the current dispatch to finish.
+func (r *Relay) Stop() {
+	r.once.Do(func() {
+		if r.cancel == nil {
+			return
+		}
+		r.cancel()
+		<-r.done
+		log.Info("stopped outbox relay")
+	})
+}
@@ -0,0 +1,198 @@
+package outbox
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	eo "github.com/NusaCrew/atlas-go/event_observer"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeStore struct {
+	mu            sync.Mutex
+	records       []*Record
+	notifications chan struct{}
+}
+
+func (s *fakeStore) add(events ...*eo.Event) {
+	s.mu.Lock()
+	for _, event := range events {
+		s.records = append(s.records, &Record{ID: event.Topic, Event: event})
+	}
+	s.mu.Unlock()
+
+	if s.notifications != nil {
+		s.notifications <- struct{}{}
+	}
+}
+
+func (s *fakeStore) pending() []*Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*Record(nil), s.records...)
+}
+
+func (s *fakeStore) Dispatch(ctx context.Context, limit int, publish PublishFunc) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var (
+		published int
+		remaining []*Record
+	)
+	for i, record := range s.records {
+		if i >= limit {
+			remaining = append(remaining, record)
+			continue
+		}
+		if err := publish(ctx, record); err != nil {
+			record.Attempts++
+			record.LastError = err.Error()
+			remaining = append(remaining, record)
+			continue
+		}
+		published++
+	}
+	s.records = remaining
+	return published, nil
+}
+
+type listeningStore struct {
+	*fakeStore
+}
+
+func (s listeningStore) Listen(ctx context.Context) (<-chan struct{}, error) {
+	return s.notifications, nil
+}
+
+type fakePublisher struct {
+	mu        sync.Mutex
+	published []string
+	failing   map[string]bool
+}
+
+func (p *fakePublisher) Publish(ctx context.Context, event *eo.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.failing[event.Topic] {
+		return assert.AnError
+	}
+	p.published = append(p.published, event.Topic)
+	return nil
+}
+
+func (p *fakePublisher) topics() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.published...)
+}
+
+func TestNewRelay(t *testing.T) {
+	_, err := NewRelay(RelayConfig{Publisher: &fakePublisher{}})
+	assert.Error(t, err)
+
+	_, err = NewRelay(RelayConfig{Store: &fakeStore{}})
+	assert.Error(t, err)
+
+	relay, err := NewRelay(RelayConfig{Store: &fakeStore{}, Publisher: &fakePublisher{}})
+	assert.NoError(t, err)
+	assert.Equal(t, 100, relay.config.BatchSize)
+	assert.Equal(t, time.Second, relay.config.PollInterval)
+}
+
+func TestDispatchPending(t *testing.T) {
+	store := &fakeStore{}
+	publisher := &fakePublisher{failing: map[string]bool{"b": true}}
+	relay, err := NewRelay(RelayConfig{Store: store, Publisher: publisher, BatchSize: 2})
+	assert.NoError(t, err)
+
+	store.add(&eo.Event{Topic: "a"}, &eo.Event{Topic: "b"}, &eo.Event{Topic: "c"}, &eo.Event{Topic: "d"})
+
+	published, err := relay.DispatchPending(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, published)
+	assert.Equal(t, []string{"a", "c", "d"}, publisher.topics())
+
+	pending := store.pending()
+	if assert.Len(t, pending, 1) {
+		assert.Equal(t, "b", pending[0].ID)
+		assert.Equal(t, assert.AnError.Error(), pending[0].LastError)
+		assert.GreaterOrEqual(t, pending[0].Attempts, 1)
+	}
+}
+
+func TestRelayRun(t *testing.T) {
+	testCases := []struct {
+		name         string
+		store        func(store *fakeStore) Store
+		pollInterval time.Duration
+	}{
+		{
+			name:         "polling",
+			store:        func(store *fakeStore) Store { return store },
+			pollInterval: 5 * time.Millisecond,
+		},
+		{
+			name: "listening",
+			store: func(store *fakeStore) Store {
+				store.notifications = make(chan struct{}, 1)
+				return listeningStore{store}
+			},
+			pollInterval: time.Hour,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			store := &fakeStore{}
+			publisher := &fakePublisher{}
+			relay, err := NewRelay(RelayConfig{Store: tc.store(store), Publisher: publisher, PollInterval: tc.pollInterval})
+			assert.NoError(t, err)
+
+			errorChannel := make(chan error, 1)
+			relay.Run(context.Background(), errorChannel)
+			time.Sleep(5 * time.Millisecond)
+
+			store.add(&eo.Event{Topic: "a"})
+			time.Sleep(20 * time.Millisecond)
+			assert.Equal(t, []string{"a"}, publisher.topics())
+
+			relay.Stop()
+			relay.Stop()
+			assert.Empty(t, errorChannel)
+		})
+	}
+}
+
+func TestRelayWithEventObserver(t *testing.T) {
+	observer := eo.NewEventObserver("some-service-name")
+	received := make(chan *eo.Event, 1)
+	observer.Subscribe("user.created", eo.Subscriber{
+		TopicName:      "user.created",
+		SubscriberName: "welcome",
+		HandlerFunc: func(ctx context.Context, event *eo.Event) error {
+			received <- event
+			return nil
+		},
+	})
+
+	store := &fakeStore{}
+	relay, err := NewRelay(RelayConfig{Store: store, Publisher: observer})
+	assert.NoError(t, err)
+
+	store.add(&eo.Event{Topic: "user.created", Data: "payload"})
+	published, err := relay.DispatchPending(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, published)
+
+	select {
+	case event := <-received:
+		assert.Equal(t, "payload", event.Data)
+	case <-time.After(time.Second):
+		t.Fatal("event was not delivered")
+	}
+}
@@ -0,0 +1,33 @@
+package event_observer
+
+import (
+	"fmt"
+
+	"github.com/NusaCrew/atlas-go/log"
+)
+
+// PanicError is the error of a handler which panicked. It is wrapped with the stack trace of
+// the panic, rendered by error logs.
+type PanicError struct {
+	Topic          string
+	SubscriberName string
+	Value          any
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic in handler %s of topic %s: %v", e.SubscriberName, e.Topic, e.Value)
+}
+
+// recoverPanic turns a panic into a *PanicError with its stack trace, assigned to err. It must
+// be deferred directly.
+func recoverPanic(s Subscriber, event *Event, err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	*err = log.WithStack(&PanicError{
+		Topic:          event.Topic,
+		SubscriberName: s.SubscriberName,
+		Value:          r,
+	})
+}
@@ -0,0 +1,62 @@
+package event_observer
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NusaCrew/atlas-go/log"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func panickingSubscriber() Subscriber {
+	return Subscriber{
+		TopicName:      "test-topic",
+		SubscriberName: "panicking",
+		HandlerFunc: func(ctx context.Context, event *Event) error {
+			panic("boom")
+		},
+	}
+}
+
+func TestPanicRecovery(t *testing.T) {
+	metrics, err := NewPrometheusMetrics(PrometheusMetricsConfig{Registerer: prometheus.NewRegistry()})
+	assert.NoError(t, err)
+	store := NewInMemoryDeadLetterStore()
+	eo := NewEventObserver("some-service-name",
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}),
+		WithDeadLetterStore(store),
+		WithMetrics(metrics),
+	)
+
+	err = eo.deliver(context.Background(), panickingSubscriber(), &Event{Topic: "test-topic"})
+
+	var panicErr *PanicError
+	if assert.ErrorAs(t, err, &panicErr) {
+		assert.Equal(t, "boom", panicErr.Value)
+		assert.Equal(t, "panicking", panicErr.SubscriberName)
+	}
+	var stackTracer log.StackTracer
+	if assert.ErrorAs(t, err, &stackTracer) {
+		assert.True(t, strings.Contains(strings.Join(stackTracer.StackTrace(), "\n"), "panickingSubscriber"), "stack trace points at the handler")
+	}
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.(*prometheusMetrics).panics.WithLabelValues("test-topic", "panicking")))
+
+	deadLetters, err := store.List(context.Background(), DeadLetterFilter{})
+	assert.NoError(t, err)
+	if assert.Len(t, deadLetters, 1) {
+		assert.Contains(t, deadLetters[0].Error, "panic in handler panicking of topic test-topic: boom")
+	}
+}
+
+func TestWithoutPanicRecovery(t *testing.T) {
+	eo := NewEventObserver("some-service-name", WithoutPanicRecovery())
+	assert.PanicsWithValue(t, "boom", func() {
+		_ = eo.handle(context.Background(), panickingSubscriber(), &Event{Topic: "test-topic"})
+	})
+}
@@ -0,0 +1,272 @@
+package event_observer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/NusaCrew/atlas-go/log"
+)
+
+// --------------- ENUMERATIONS ---------------
+
+// QueueFullPolicy decides what happens to an event published to a pool whose queue is full.
+type QueueFullPolicy int
+
+const (
+	QueueFullBlock      QueueFullPolicy = iota + 1 // wait for room in the queue, until the publish context is done
+	QueueFullDropOldest                            // drop the oldest queued event to make room
+	QueueFullReject                                // fail the publish with ErrQueueFull
+)
+
+func (p QueueFullPolicy) String() string {
+	switch p {
+	case QueueFullBlock:
+		return "BLOCK"
+	case QueueFullDropOldest:
+		return "DROP_OLDEST"
+	case QueueFullReject:
+		return "REJECT"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// --------------- POOL ---------------
+
+var ErrQueueFull = errors.New("event queue is full")
+
+// errPoolClosed is returned by submit once the pool closed, on Close or Unsubscribe.
+var errPoolClosed = errors.New("pool is closed")
+
+// PoolConfig bounds the number of goroutines handling events and the number of events
+// waiting for one. Zero fields default to DefaultPoolConfig.
+type PoolConfig struct {
+	Workers    int
+	QueueSize  int
+	FullPolicy QueueFullPolicy
+}
+
+var DefaultPoolConfig = PoolConfig{
+	Workers:    8,
+	QueueSize:  1024,
+	FullPolicy: QueueFullBlock,
+}
+
+func (c PoolConfig) withDefaults() PoolConfig {
+	if c.Workers <= 0 {
+		c.Workers = DefaultPoolConfig.Workers
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = DefaultPoolConfig.QueueSize
+	}
+	if c.FullPolicy == 0 {
+		c.FullPolicy = DefaultPoolConfig.FullPolicy
+	}
+	return c
+}
+
+type job struct {
+	ctx        context.Context
+	subscriber Subscriber
+	event      *Event
+	enqueuedAt time.Time
+}
+
+// workerPool delivers queued events with a fixed number of workers. Retries are waited out
+// by the worker, so a slow or failing subscriber only holds back its own pool. An ordered pool
+// has a queue per worker, events sharing a key going to the same one.
+type workerPool struct {
+	name     string
+	config   PoolConfig
+	queues   []chan job
+	next     atomic.Uint64 // queue of the next event without key in an ordered pool
+	metrics  PoolMetrics
+	deliver  func(ctx context.Context, s Subscriber, event *Event) error
+	stop     <-chan struct{}
+	wg       sync.WaitGroup
+	inFlight atomic.Int64
+
+	// closing interrupts the submits waiting for room, so close can take sendMu, which
+	// submits hold so they never send to a closed queue.
+	closing   chan struct{}
+	closeOnce sync.Once
+	sendMu    sync.RWMutex
+	closed    bool
+}
+
+// newWorkerPool starts the workers of the pool. They run until the queue is closed and
+// drained, or until stop is closed.
+func newWorkerPool(name string, config PoolConfig, ordered bool, metrics PoolMetrics, stop <-chan struct{}, deliver func(ctx context.Context, s Subscriber, event *Event) error) *workerPool {
+	config = config.withDefaults()
+	p := &workerPool{
+		name:    name,
+		config:  config,
+		metrics: metrics,
+		deliver: deliver,
+		stop:    stop,
+		closing: make(chan struct{}),
+	}
+
+	if ordered {
+		queueSize := max(config.QueueSize/config.Workers, 1)
+		for i := 0; i < config.Workers; i++ {
+			p.queues = append(p.queues, make(chan job, queueSize))
+		}
+	} else {
+		p.queues = []chan job{make(chan job, config.QueueSize)}
+	}
+
+	p.wg.Add(config.Workers)
+	for i := 0; i < config.Workers; i++ {
+		go p.work(p.queues[i%len(p.queues)])
+	}
+	return p
+}
+
+func (p *workerPool) work(queue chan job) {
+	defer p.wg.Done()
+	for {
+		select {
+		case <-p.stop:
+			return
+		default:
+		}
+
+		j, ok := <-queue
+		if !ok {
+			return
+		}
+		p.metrics.ObserveQueueDepth(p.name, p.depth())
+		p.metrics.ObserveQueueLatency(p.name, time.Since(j.enqueuedAt))
+
+		p.inFlight.Add(1)
+		_ = p.deliver(j.ctx, j.subscriber, j.event)
+		p.inFlight.Add(-1)
+	}
+}
+
+// depth returns the number of queued events.
+func (p *workerPool) depth() int {
+	depth := 0
+	for _, queue := range p.queues {
+		depth += len(queue)
+	}
+	return depth
+}
+
+// queueFor returns the queue of event: the only one, or in an ordered pool the one of the
+// event's key, events without key being spread over the queues.
+func (p *workerPool) queueFor(event *Event) chan job {
+	if len(p.queues) == 1 {
+		return p.queues[0]
+	}
+	if event.Key == "" {
+		return p.queues[p.next.Add(1)%uint64(len(p.queues))]
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(event.Key))
+	return p.queues[h.Sum32()%uint32(len(p.queues))]
+}
+
+// close stops accepting events, failing the submits waiting for room with errPoolClosed.
+// Workers keep delivering the queued events.
+func (p *workerPool) close() {
+	p.closeOnce.Do(func() {
+		close(p.closing)
+		p.sendMu.Lock()
+		defer p.sendMu.Unlock()
+
+		p.closed = true
+		for _, queue := range p.queues {
+			close(queue)
+		}
+	})
+}
+
+// wait blocks until every worker returned or ctx is done.
+func (p *workerPool) wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// drain removes the events left in the queue of a closed pool.
+func (p *workerPool) drain() []job {
+	var jobs []job
+	for _, queue := range p.queues {
+		for j := range queue {
+			jobs = append(jobs, j)
+		}
+	}
+	p.metrics.ObserveQueueDepth(p.name, 0)
+	return jobs
+}
+
+// submit queues the delivery of event to s, applying the pool's QueueFullPolicy when the queue
+// is full. ctx only bounds the wait of QueueFullBlock, the delivery itself is not cancelled by it.
+func (p *workerPool) submit(ctx context.Context, s Subscriber, event *Event) error {
+	j := job{
+		ctx:        context.WithoutCancel(ctx),
+		subscriber: s,
+		event:      event,
+		enqueuedAt: time.Now(),
+	}
+	queue := p.queueFor(event)
+	defer func() {
+		p.metrics.ObserveQueueDepth(p.name, p.depth())
+	}()
+
+	p.sendMu.RLock()
+	defer p.sendMu.RUnlock()
+	if p.closed {
+		return fmt.Errorf("failed to queue event of topic %s for subscriber %s in pool %s: %w", event.Topic, s.SubscriberName, p.name, errPoolClosed)
+	}
+
+	select {
+	case queue <- j:
+		return nil
+	default:
+	}
+
+	p.metrics.ObserveOverflow(p.name, p.config.FullPolicy)
+	switch p.config.FullPolicy {
+	case QueueFullReject:
+		return fmt.Errorf("failed to queue event of topic %s for subscriber %s in pool %s: %w", event.Topic, s.SubscriberName, p.name, ErrQueueFull)
+	case QueueFullDropOldest:
+		for {
+			select {
+			case queue <- j:
+				return nil
+			default:
+			}
+			select {
+			case dropped := <-queue:
+				log.Warning("queue of pool %s is full, dropped event of topic %s for subscriber %s", p.name, dropped.event.Topic, dropped.subscriber.SubscriberName)
+			default:
+			}
+		}
+	default:
+		select {
+		case queue <- j:
+			return nil
+		case <-ctx.Done():
+			return fmt.Errorf("failed to queue event of topic %s for subscriber %s in pool %s: %w", event.Topic, s.SubscriberName, p.name, ctx.Err())
+		case <-p.closing:
+			return fmt.Errorf("failed to queue event of topic %s for subscriber %s in pool %s: %w", event.Topic, s.SubscriberName, p.name, errPoolClosed)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package event_observer
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingSubscriber returns a subscriber whose handler waits for release, recording the
+// data of the events it handled.
+func blockingSubscriber(name string, pool *PoolConfig, release <-chan struct{}, mu *sync.Mutex, handled *[]any) Subscriber {
+	return Subscriber{
+		TopicName:      "test-topic",
+		SubscriberName: name,
+		Pool:           pool,
+		HandlerFunc: func(ctx context.Context, event *Event) error {
+			<-release
+			mu.Lock()
+			*handled = append(*handled, event.Data)
+			mu.Unlock()
+			return nil
+		},
+	}
+}
+
+func TestQueueFullPolicy(t *testing.T) {
+	testCases := []struct {
+		name          string
+		policy        QueueFullPolicy
+		expectedError error
+		expected      []any
+	}{
+		{
+			name:          "reject",
+			policy:        QueueFullReject,
+			expectedError: ErrQueueFull,
+			expected:      []any{1, 2},
+		},
+		{
+			name:     "drop oldest",
+			policy:   QueueFullDropOldest,
+			expected: []any{1, 3},
+		},
+		{
+			name:          "block until context is done",
+			policy:        QueueFullBlock,
+			expectedError: context.DeadlineExceeded,
+			expected:      []any{1, 2},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			eo := NewEventObserver("some-service-name")
+			release := make(chan struct{})
+			var mu sync.Mutex
+			var handled []any
+			eo.Subscribe("test-topic", blockingSubscriber("slow", &PoolConfig{Workers: 1, QueueSize: 1, FullPolicy: tc.policy}, release, &mu, &handled))
+
+			ctx := context.Background()
+			assert.NoError(t, eo.Publish(ctx, &Event{Topic: "test-topic", Data: 1}))
+			// wait for the worker to pick the first event up, leaving the queue empty
+			time.Sleep(10 * time.Millisecond)
+			assert.NoError(t, eo.Publish(ctx, &Event{Topic: "test-topic", Data: 2}))
+
+			ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+			defer cancel()
+			err := eo.Publish(ctx, &Event{Topic: "test-topic", Data: 3})
+			if tc.expectedError != nil {
+				assert.ErrorIs(t, err, tc.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			close(release)
+			time.Sleep(10 * time.Millisecond)
+
+			mu.Lock()
+			assert.Equal(t, tc.expected, handled)
+			mu.Unlock()
+		})
+	}
+}
+
+func TestTopicPool(t *testing.T) {
+	eo := NewEventObserver("some-service-name", WithTopicPool("test-topic", PoolConfig{Workers: 1, QueueSize: 2, FullPolicy: QueueFullReject}))
+	release := make(chan struct{})
+	defer close(release)
+	var mu sync.Mutex
+	var handled []any
+
+	eo.Subscribe("test-topic", blockingSubscriber("first", nil, release, &mu, &handled))
+	eo.Subscribe("test-topic", blockingSubscriber("second", nil, release, &mu, &handled))
+	eo.Subscribe("test-topic", blockingSubscriber("own-pool", &PoolConfig{Workers: 1}, release, &mu, &handled))
+
+	assert.Len(t, eo.pools, 2)
+	assert.Same(t, eo.subscribers["test-topic"][0].pool, eo.subscribers["test-topic"][1].pool)
+
+	// the shared worker takes the event of first, the queue keeps the event of second
+	assert.NoError(t, eo.Publish(context.Background(), &Event{Topic: "test-topic", Data: 1}))
+	time.Sleep(10 * time.Millisecond)
+	err := eo.Publish(context.Background(), &Event{Topic: "test-topic", Data: 2})
+	assert.ErrorIs(t, err, ErrQueueFull)
+	assert.ErrorContains(t, err, "subscriber second")
+	assert.NotContains(t, err.Error(), "subscriber first")
+	assert.NotContains(t, err.Error(), "own-pool")
+}
+
+func TestPrometheusMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	metrics, err := NewPrometheusMetrics(PrometheusMetricsConfig{Registerer: registry})
+	assert.NoError(t, err)
+
+	// registering twice reuses the collectors
+	_, err = NewPrometheusMetrics(PrometheusMetricsConfig{Registerer: registry})
+	assert.NoError(t, err)
+
+	eo := NewEventObserver("some-service-name", WithMetrics(metrics))
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var handled []any
+	eo.Subscribe("test-topic", blockingSubscriber("slow", &PoolConfig{Workers: 1, QueueSize: 1, FullPolicy: QueueFullReject}, release, &mu, &handled))
+
+	for i := 0; i < 3; i++ {
+		_ = eo.Publish(context.Background(), &Event{Topic: "test-topic", Data: i})
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	pool := "test-topic/slow"
+	depth, err := metrics.(*prometheusMetrics).depth.GetMetricWithLabelValues(pool)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1), testutil.ToFloat64(depth))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.(*prometheusMetrics).overflow.WithLabelValues(pool, "REJECT")))
+
+	close(release)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, float64(0), testutil.ToFloat64(depth))
+	assert.Equal(t, 1, testutil.CollectAndCount(registry, "atlas_event_observer_queue_latency_seconds"))
+}
+
+// poolOnlyMetrics implements PoolMetrics only, like the implementations written before
+// HandlerMetrics.
+type poolOnlyMetrics struct {
+	mu     sync.Mutex
+	depths []int
+}
+
+func (m *poolOnlyMetrics) ObserveQueueDepth(pool string, depth int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.depths = append(m.depths, depth)
+}
+
+func (m *poolOnlyMetrics) ObserveQueueLatency(string, time.Duration) {}
+func (m *poolOnlyMetrics) ObserveOverflow(string, QueueFullPolicy)   {}
+
+func TestPoolMetricsOnly(t *testing.T) {
+	metrics := &poolOnlyMetrics{}
+	eo := NewEventObserver("some-service-name", WithMetrics(metrics))
+
+	done := make(chan struct{})
+	eo.Subscribe("test-topic", Subscriber{
+		SubscriberName: "panicking",
+		RetryPolicy:    &RetryPolicy{MaxAttempts: 1},
+		HandlerFunc: func(ctx context.Context, event *Event) error {
+			defer close(done)
+			panic("boom")
+		},
+	})
+
+	assert.NoError(t, eo.Publish(context.Background(), &Event{Topic: "test-topic"}))
+	<-done
+	assert.NoError(t, eo.Close(context.Background()), "panics are not observed without HandlerMetrics")
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	assert.NotEmpty(t, metrics.depths)
+}
@@ -0,0 +1,63 @@
+package event_observer
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	defaultInitialBackoff = 100 * time.Millisecond
+	defaultMaxBackoff     = 30 * time.Second
+	defaultMultiplier     = 2.0
+)
+
+// RetryPolicy controls how many times a failing handler is retried and how long to wait
+// between attempts: InitialBackoff * Multiplier^(attempt-1), capped to MaxBackoff and
+// randomized by Jitter.
+type RetryPolicy struct {
+	MaxAttempts    int           // total attempts including the first one, defaults to 1 (no retry)
+	InitialBackoff time.Duration // defaults to 100ms
+	MaxBackoff     time.Duration // defaults to 30s
+	Multiplier     float64       // defaults to 2
+	Jitter         float64       // fraction of the backoff to randomize, between 0 and 1
+}
+
+// NoRetry delivers every event once.
+var NoRetry = RetryPolicy{MaxAttempts: 1}
+
+func (p RetryPolicy) attempts() int {
+	if p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// Backoff returns the wait before the attempt following the given failed attempt (starting at 1).
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	initial := p.InitialBackoff
+	if initial <= 0 {
+		initial = defaultInitialBackoff
+	}
+	maxBackoff := p.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxBackoff
+	}
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = defaultMultiplier
+	}
+
+	backoff := float64(initial) * math.Pow(multiplier, float64(attempt-1))
+	if backoff > float64(maxBackoff) {
+		backoff = float64(maxBackoff)
+	}
+
+	jitter := math.Min(math.Max(p.Jitter, 0), 1)
+	if jitter > 0 {
+		// spread the backoff uniformly in [backoff * (1 - jitter), backoff * (1 + jitter)]
+		backoff = backoff * (1 - jitter + 2*jitter*rand.Float64())
+	}
+
+	return time.Duration(backoff)
+}
@@ -0,0 +1,202 @@
+package event_observer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/NusaCrew/atlas-go/log"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultSchedulePollInterval = time.Second
+	scheduleBatchSize           = 100
+)
+
+var ErrScheduleNotFound = errors.New("scheduled event not found")
+
+// ScheduledEvent is an event waiting in a ScheduleStore to be published at DeliverAt.
+type ScheduledEvent struct {
+	ID        string
+	Event     *Event
+	DeliverAt time.Time
+	Attempts  int
+	LastError string
+}
+
+// ScheduleStore keeps the scheduled events until they are published, so they survive restarts.
+type ScheduleStore interface {
+	Save(ctx context.Context, scheduled *ScheduledEvent) error
+	// Dispatch passes up to limit events due at now to publish, earliest first, and returns
+	// how many were published. Events are removed once publish returns nil, and kept with
+	// the error for a later dispatch otherwise, which stores may give up on after some
+	// attempts. Events being dispatched by another instance are skipped.
+	Dispatch(ctx context.Context, now time.Time, limit int, publish func(ctx context.Context, scheduled *ScheduledEvent) error) (int, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// WithScheduleStore enables PublishAt and PublishAfter, publishing the events of store when
+// they are due, checked every pollInterval, 1s by default. Events due while the service was
+// down are published on the first check, one interval after NewEventObserver so subscribers
+// have joined.
+func WithScheduleStore(store ScheduleStore, pollInterval time.Duration) Option {
+	return func(eo *EventObserver) {
+		if pollInterval <= 0 {
+			pollInterval = defaultSchedulePollInterval
+		}
+		eo.scheduleStore = store
+		eo.schedulePollInterval = pollInterval
+	}
+}
+
+// PublishAt stores event to be published at, or soon after, the given time and returns the
+// ID to cancel it with. The event is stamped now, so its ID is kept when it is published.
+func (eo *EventObserver) PublishAt(ctx context.Context, event *Event, at time.Time) (string, error) {
+	if eo.scheduleStore == nil {
+		return "", errors.New("event observer has no schedule store")
+	}
+
+	eo.closeMu.RLock()
+	defer eo.closeMu.RUnlock()
+	if eo.closed {
+		return "", fmt.Errorf("failed to schedule topic %s: %w", event.Topic, ErrObserverClosed)
+	}
+
+	event.Stamp(ctx, eo.serviceName)
+	scheduled := &ScheduledEvent{
+		ID:        uuid.NewString(),
+		Event:     event,
+		DeliverAt: at,
+	}
+	if err := eo.scheduleStore.Save(ctx, scheduled); err != nil {
+		return "", fmt.Errorf("failed to schedule topic %s: %w", event.Topic, err)
+	}
+	log.Info("scheduled topic %s at %s", event.Topic, at.Format(time.RFC3339))
+	return scheduled.ID, nil
+}
+
+// PublishAfter is PublishAt in d from now.
+func (eo *EventObserver) PublishAfter(ctx context.Context, event *Event, d time.Duration) (string, error) {
+	return eo.PublishAt(ctx, event, time.Now().Add(d))
+}
+
+// CancelScheduled removes a scheduled event before it is published, returning
+// ErrScheduleNotFound when it was already published or cancelled.
+func (eo *EventObserver) CancelScheduled(ctx context.Context, id string) error {
+	if eo.scheduleStore == nil {
+		return errors.New("event observer has no schedule store")
+	}
+	return eo.scheduleStore.Delete(ctx, id)
+}
+
+// runScheduler publishes the due events every poll interval until the EventObserver closes.
+func (eo *EventObserver) runScheduler(ctx context.Context) {
+	defer eo.consumers.Done()
+
+	ticker := time.NewTicker(eo.schedulePollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			eo.dispatchScheduled(ctx)
+		}
+	}
+}
+
+func (eo *EventObserver) dispatchScheduled(ctx context.Context) {
+	for ctx.Err() == nil {
+		published, err := eo.scheduleStore.Dispatch(ctx, time.Now(), scheduleBatchSize, func(ctx context.Context, scheduled *ScheduledEvent) error {
+			published, err := eo.publish(ctx, scheduled.Event)
+			if err != nil && published {
+				// publishing again would duplicate the event for the subscribers which took it,
+				// the failures of the others were retried with their own policy
+				log.WithError(err).Error("scheduled event %s of topic %s failed for some subscribers", scheduled.ID, scheduled.Event.Topic)
+				return nil
+			}
+			return err
+		})
+		if err != nil {
+			log.WithError(err).Error("failed to dispatch scheduled events of %s", eo.serviceName)
+			return
+		}
+		if published < scheduleBatchSize {
+			return
+		}
+	}
+}
+
+type inMemoryScheduleStore struct {
+	mu        sync.Mutex
+	scheduled map[string]*ScheduledEvent
+}
+
+// NewInMemoryScheduleStore returns a ScheduleStore losing its content on restart, meant for
+// tests and non critical events.
+func NewInMemoryScheduleStore() ScheduleStore {
+	return &inMemoryScheduleStore{
+		scheduled: make(map[string]*ScheduledEvent),
+	}
+}
+
+func (s *inMemoryScheduleStore) Save(ctx context.Context, scheduled *ScheduledEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := *scheduled
+	s.scheduled[scheduled.ID] = &stored
+	return nil
+}
+
+// Dispatch takes the due events out of the store while they are published, so handlers can
+// schedule events themselves.
+func (s *inMemoryScheduleStore) Dispatch(ctx context.Context, now time.Time, limit int, publish func(ctx context.Context, scheduled *ScheduledEvent) error) (int, error) {
+	s.mu.Lock()
+	var due []*ScheduledEvent
+	for _, scheduled := range s.scheduled {
+		if !scheduled.DeliverAt.After(now) {
+			due = append(due, scheduled)
+		}
+	}
+	slices.SortFunc(due, func(a, b *ScheduledEvent) int {
+		return a.DeliverAt.Compare(b.DeliverAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	for _, scheduled := range due {
+		delete(s.scheduled, scheduled.ID)
+	}
+	s.mu.Unlock()
+
+	published := 0
+	for _, scheduled := range due {
+		if err := publish(ctx, scheduled); err != nil {
+			scheduled.Attempts++
+			scheduled.LastError = err.Error()
+			s.mu.Lock()
+			s.scheduled[scheduled.ID] = scheduled
+			s.mu.Unlock()
+			continue
+		}
+		published++
+	}
+	return published, nil
+}
+
+func (s *inMemoryScheduleStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.scheduled[id]; !ok {
+		return ErrScheduleNotFound
+	}
+	delete(s.scheduled, id)
+	return nil
+}
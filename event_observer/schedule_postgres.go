@@ -0,0 +1,193 @@
+package event_observer
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/NusaCrew/atlas-go/log"
+	"github.com/NusaCrew/atlas-go/storage/postgres"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+const DefaultScheduleTable = "event_schedules"
+
+// DefaultScheduleRetryPolicy retries a scheduled event failing to be published for about half
+// an hour before parking it.
+var DefaultScheduleRetryPolicy = RetryPolicy{
+	MaxAttempts:    10,
+	InitialBackoff: time.Second,
+	MaxBackoff:     5 * time.Minute,
+	Jitter:         0.2,
+}
+
+type PostgresScheduleConfig struct {
+	// Table has the schema of migrations/000004_create_event_schedules.up.sql, which creates
+	// DefaultScheduleTable: copy it with the table renamed to use another one. Defaults to
+	// DefaultScheduleTable.
+	Table string
+	// Lease is how long an instance owns the events it publishes before another instance
+	// may take them over, defaults to 30s.
+	Lease time.Duration
+	// RetryPolicy of the events failing to be published, defaults to
+	// DefaultScheduleRetryPolicy. Events exhausting it are parked: they stay in the table
+	// with dead_at set and are not published anymore, until dead_at is cleared.
+	RetryPolicy *RetryPolicy
+}
+
+type postgresScheduleStore struct {
+	postgres.CommonRepository
+	table       string
+	lease       time.Duration
+	retryPolicy RetryPolicy
+}
+
+// NewPostgresScheduleStore returns a ScheduleStore backed by PostgreSQL. Due events are leased
+// with FOR UPDATE SKIP LOCKED, so each is published by a single instance, and published once
+// the lease is committed. Data of the events is published as its type when registered by
+// RegisterDataType or NewTopic, and as json.RawMessage otherwise.
+func NewPostgresScheduleStore(storage postgres.Storage, config PostgresScheduleConfig) ScheduleStore {
+	if config.Table == "" {
+		config.Table = DefaultScheduleTable
+	}
+	if config.Lease <= 0 {
+		config.Lease = 30 * time.Second
+	}
+	if config.RetryPolicy == nil {
+		config.RetryPolicy = &DefaultScheduleRetryPolicy
+	}
+	return &postgresScheduleStore{
+		CommonRepository: postgres.CommonRepository{Storage: storage},
+		table:            config.Table,
+		lease:            config.Lease,
+		retryPolicy:      *config.RetryPolicy,
+	}
+}
+
+func (s *postgresScheduleStore) Save(ctx context.Context, scheduled *ScheduledEvent) error {
+	payload, dataType, err := encodeStoredEvent(scheduled.Event)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.Builder(nil).
+		Insert(s.table).
+		Columns("id", "topic", "event", "data_type", "deliver_at", "created_at").
+		Values(scheduled.ID, scheduled.Event.Topic, payload, dataType, scheduled.DeliverAt, time.Now()).
+		ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to save scheduled event %s: %w", scheduled.ID, err)
+	}
+	return nil
+}
+
+func (s *postgresScheduleStore) Dispatch(ctx context.Context, now time.Time, limit int, publish func(ctx context.Context, scheduled *ScheduledEvent) error) (int, error) {
+	due, err := s.leaseDue(ctx, now, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	var published int
+	for _, leased := range due {
+		scheduled := &leased.ScheduledEvent
+		if scheduled.Event, err = decodeStoredEvent(leased.payload, leased.dataType); err != nil {
+			// no attempt can publish it, park it right away
+			if err := s.fail(ctx, scheduled, err, false); err != nil {
+				return published, err
+			}
+			continue
+		}
+		if err := publish(ctx, scheduled); err != nil {
+			if err := s.fail(ctx, scheduled, err, true); err != nil {
+				return published, err
+			}
+			continue
+		}
+		if _, err := s.Builder(nil).Delete(s.table).Where(sq.Eq{"id": scheduled.ID}).ExecContext(ctx); err != nil {
+			return published, fmt.Errorf("failed to delete published scheduled event %s: %w", scheduled.ID, err)
+		}
+		published++
+	}
+	return published, nil
+}
+
+type leasedSchedule struct {
+	ScheduledEvent
+	payload  []byte
+	dataType string
+}
+
+// leaseQuery leases the due events in a single statement, so the lease is committed before
+// they are published.
+func (s *postgresScheduleStore) leaseQuery(now time.Time, limit int) sq.UpdateBuilder {
+	due := sq.Select("id").
+		From(s.table).
+		Where(sq.Eq{"dead_at": nil}).
+		Where(sq.LtOrEq{"deliver_at": now, "locked_until": now}).
+		OrderBy("deliver_at").
+		Limit(uint64(limit)).
+		Suffix("FOR UPDATE SKIP LOCKED")
+
+	return s.Builder(nil).Update(s.table).
+		Set("locked_until", now.Add(s.lease)).
+		Where(sq.Expr("id IN (?)", due)).
+		Suffix("RETURNING id, event, data_type, deliver_at, attempts, COALESCE(last_error, '')")
+}
+
+func (s *postgresScheduleStore) leaseDue(ctx context.Context, now time.Time, limit int) ([]*leasedSchedule, error) {
+	rows, err := s.leaseQuery(now, limit).QueryContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lease scheduled events: %w", err)
+	}
+	defer rows.Close()
+
+	var due []*leasedSchedule
+	for rows.Next() {
+		var leased leasedSchedule
+		if err := rows.Scan(&leased.ID, &leased.payload, &leased.dataType, &leased.DeliverAt, &leased.Attempts, &leased.LastError); err != nil {
+			return nil, err
+		}
+		due = append(due, &leased)
+	}
+	// RETURNING does not keep the order of the subquery
+	slices.SortFunc(due, func(a, b *leasedSchedule) int {
+		return a.DeliverAt.Compare(b.DeliverAt)
+	})
+	return due, rows.Err()
+}
+
+func (s *postgresScheduleStore) fail(ctx context.Context, scheduled *ScheduledEvent, err error, retryable bool) error {
+	if _, updateErr := s.failQuery(scheduled, err, retryable, time.Now()).ExecContext(ctx); updateErr != nil {
+		return fmt.Errorf("failed to update scheduled event %s: %w", scheduled.ID, updateErr)
+	}
+	return nil
+}
+
+// failQuery schedules the next attempt of the event after the backoff of the retry policy,
+// or parks it when the policy is exhausted or the error is not retryable.
+func (s *postgresScheduleStore) failQuery(scheduled *ScheduledEvent, err error, retryable bool, now time.Time) sq.UpdateBuilder {
+	query := s.Builder(nil).Update(s.table).
+		Set("attempts", sq.Expr("attempts + 1")).
+		Set("last_error", err.Error()).
+		Where(sq.Eq{"id": scheduled.ID})
+
+	attempt := scheduled.Attempts + 1
+	if attempt < s.retryPolicy.attempts() && retryable {
+		return query.Set("locked_until", now.Add(s.retryPolicy.Backoff(attempt)))
+	}
+	log.WithError(err).Error("parked scheduled event %s after %d attempts", scheduled.ID, attempt)
+	return query.Set("dead_at", now)
+}
+
+func (s *postgresScheduleStore) Delete(ctx context.Context, id string) error {
+	result, err := s.Builder(nil).Delete(s.table).Where(sq.Eq{"id": id}).ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete scheduled event %s: %w", id, err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrScheduleNotFound
+	}
+	return nil
+}
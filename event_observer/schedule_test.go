@@ -0,0 +1,248 @@
+package event_observer
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublishAfter(t *testing.T) {
+	eo := NewEventObserver("some-service-name", WithScheduleStore(NewInMemoryScheduleStore(), 10*time.Millisecond))
+
+	var mu sync.Mutex
+	var received []*Event
+	assert.NoError(t, eo.Subscribe("reminder", Subscriber{
+		SubscriberName: "send-reminder",
+		HandlerFunc: func(ctx context.Context, event *Event) error {
+			mu.Lock()
+			defer mu.Unlock()
+			received = append(received, event)
+			return nil
+		},
+	}))
+
+	event := &Event{Topic: "reminder", Data: "payload"}
+	id, err := eo.PublishAfter(context.Background(), event, 50*time.Millisecond)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, id)
+	assert.NotEmpty(t, event.ID, "event is stamped when scheduled")
+
+	time.Sleep(20 * time.Millisecond)
+	mu.Lock()
+	assert.Empty(t, received)
+	mu.Unlock()
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 1
+	}, time.Second, 10*time.Millisecond)
+	mu.Lock()
+	assert.Equal(t, event.ID, received[0].ID)
+	mu.Unlock()
+	assert.ErrorIs(t, eo.CancelScheduled(context.Background(), id), ErrScheduleNotFound, "published events cannot be cancelled")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, eo.Close(ctx))
+}
+
+func TestCancelScheduled(t *testing.T) {
+	eo := NewEventObserver("some-service-name", WithScheduleStore(NewInMemoryScheduleStore(), 10*time.Millisecond))
+
+	var mu sync.Mutex
+	var calls int
+	assert.NoError(t, eo.Subscribe("reminder", Subscriber{
+		SubscriberName: "send-reminder",
+		HandlerFunc: func(ctx context.Context, event *Event) error {
+			mu.Lock()
+			defer mu.Unlock()
+			calls++
+			return nil
+		},
+	}))
+
+	id, err := eo.PublishAfter(context.Background(), &Event{Topic: "reminder"}, 30*time.Millisecond)
+	assert.NoError(t, err)
+	assert.NoError(t, eo.CancelScheduled(context.Background(), id))
+	assert.ErrorIs(t, eo.CancelScheduled(context.Background(), id), ErrScheduleNotFound)
+
+	time.Sleep(60 * time.Millisecond)
+	mu.Lock()
+	assert.Zero(t, calls)
+	mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, eo.Close(ctx))
+}
+
+func TestScheduleRecovery(t *testing.T) {
+	store := NewInMemoryScheduleStore()
+	first := NewEventObserver("some-service-name", WithScheduleStore(store, time.Hour))
+	_, err := first.PublishAt(context.Background(), &Event{Topic: "reminder"}, time.Now().Add(-time.Minute))
+	assert.NoError(t, err)
+	_, err = first.PublishAfter(context.Background(), &Event{Topic: "reminder"}, time.Hour)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, first.Close(ctx))
+
+	var mu sync.Mutex
+	var calls int
+	second := NewEventObserver("some-service-name", WithDeliveryMode(DeliverySync), WithScheduleStore(store, 20*time.Millisecond))
+	assert.NoError(t, second.Subscribe("reminder", Subscriber{
+		SubscriberName: "send-reminder",
+		HandlerFunc: func(ctx context.Context, event *Event) error {
+			mu.Lock()
+			defer mu.Unlock()
+			calls++
+			return nil
+		},
+	}))
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls == 1
+	}, time.Second, 10*time.Millisecond, "events due while down are published on start")
+	assert.NoError(t, second.Close(ctx))
+}
+
+func TestInMemoryScheduleStoreDispatch(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryScheduleStore()
+	now := time.Now()
+	for i, id := range []string{"late", "early", "future"} {
+		offset := []time.Duration{-time.Second, -time.Minute, time.Minute}[i]
+		assert.NoError(t, store.Save(ctx, &ScheduledEvent{ID: id, Event: &Event{Topic: "test-topic"}, DeliverAt: now.Add(offset)}))
+	}
+
+	var dispatched []string
+	published, err := store.Dispatch(ctx, now, 10, func(ctx context.Context, scheduled *ScheduledEvent) error {
+		dispatched = append(dispatched, scheduled.ID)
+		if scheduled.ID == "late" {
+			return assert.AnError
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, published)
+	assert.Equal(t, []string{"early", "late"}, dispatched, "due events are dispatched earliest first")
+
+	dispatched = nil
+	published, err = store.Dispatch(ctx, now, 10, func(ctx context.Context, scheduled *ScheduledEvent) error {
+		dispatched = append(dispatched, scheduled.ID)
+		assert.Equal(t, 1, scheduled.Attempts)
+		assert.Equal(t, assert.AnError.Error(), scheduled.LastError)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, published)
+	assert.Equal(t, []string{"late"}, dispatched, "failed events are kept")
+}
+
+func TestPublishAtWithoutStore(t *testing.T) {
+	eo := NewEventObserver("some-service-name")
+	_, err := eo.PublishAfter(context.Background(), &Event{Topic: "reminder"}, time.Minute)
+	assert.Error(t, err)
+	assert.Error(t, eo.CancelScheduled(context.Background(), "id"))
+}
+
+func TestScheduledPartialFailure(t *testing.T) {
+	store := NewInMemoryScheduleStore()
+	eo := NewEventObserver("some-service-name", WithScheduleStore(store, 10*time.Millisecond))
+
+	var succeeded, failed atomic.Int32
+	assert.NoError(t, eo.Subscribe("reminder", Subscriber{
+		SubscriberName: "send-reminder",
+		DeliveryMode:   DeliverySync,
+		HandlerFunc: func(ctx context.Context, event *Event) error {
+			succeeded.Add(1)
+			return nil
+		},
+	}))
+	assert.NoError(t, eo.Subscribe("reminder", Subscriber{
+		SubscriberName: "failing",
+		DeliveryMode:   DeliverySync,
+		HandlerFunc: func(ctx context.Context, event *Event) error {
+			failed.Add(1)
+			return assert.AnError
+		},
+	}))
+
+	_, err := eo.PublishAfter(context.Background(), &Event{Topic: "reminder"}, 0)
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return failed.Load() == 1 }, time.Second, 5*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(1), succeeded.Load(), "the event is not published again for the subscribers which handled it")
+	assert.Equal(t, int32(1), failed.Load())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, eo.Close(ctx))
+}
+
+type fakeStorage struct{}
+
+func (fakeStorage) DB() *sql.DB                    { return nil }
+func (fakeStorage) Ping(ctx context.Context) error { return nil }
+func (fakeStorage) Close() error                   { return nil }
+
+func TestPostgresScheduleStoreQueries(t *testing.T) {
+	store := NewPostgresScheduleStore(fakeStorage{}, PostgresScheduleConfig{
+		Lease:       time.Minute,
+		RetryPolicy: &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Second},
+	}).(*postgresScheduleStore)
+	now := time.Now()
+
+	query, args, err := store.leaseQuery(now, 10).ToSql()
+	assert.NoError(t, err)
+	assert.Equal(t, "UPDATE event_schedules SET locked_until = $1 WHERE id IN (SELECT id FROM event_schedules WHERE dead_at IS NULL AND deliver_at <= $2 AND locked_until <= $3 ORDER BY deliver_at LIMIT 10 FOR UPDATE SKIP LOCKED) RETURNING id, event, data_type, deliver_at, attempts, COALESCE(last_error, '')", query)
+	assert.Equal(t, []any{now.Add(time.Minute), now, now}, args)
+
+	testCases := []struct {
+		name      string
+		attempts  int
+		retryable bool
+		query     string
+		args      []any
+	}{
+		{
+			name:      "backoff",
+			attempts:  1,
+			retryable: true,
+			query:     "UPDATE event_schedules SET attempts = attempts + 1, last_error = $1, locked_until = $2 WHERE id = $3",
+			args:      []any{assert.AnError.Error(), now.Add(2 * time.Second), "some-id"},
+		},
+		{
+			name:      "exhausted",
+			attempts:  2,
+			retryable: true,
+			query:     "UPDATE event_schedules SET attempts = attempts + 1, last_error = $1, dead_at = $2 WHERE id = $3",
+			args:      []any{assert.AnError.Error(), now, "some-id"},
+		},
+		{
+			name:      "not retryable",
+			retryable: false,
+			query:     "UPDATE event_schedules SET attempts = attempts + 1, last_error = $1, dead_at = $2 WHERE id = $3",
+			args:      []any{assert.AnError.Error(), now, "some-id"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			scheduled := &ScheduledEvent{ID: "some-id", Attempts: tc.attempts}
+			query, args, err := store.failQuery(scheduled, assert.AnError, tc.retryable, now).ToSql()
+			assert.NoError(t, err)
+			assert.Equal(t, tc.query, query)
+			assert.Equal(t, tc.args, args)
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package event_observer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/NusaCrew/atlas-go/log"
+)
+
+var (
+	ErrObserverClosed = errors.New("event observer is closed")
+	ErrCloseInHandler = errors.New("event observer cannot be closed by its own handlers")
+)
+
+// UndeliveredEvent is an event left in a queue when the EventObserver was closed.
+type UndeliveredEvent struct {
+	SubscriberName string
+	Event          *Event
+}
+
+// UndeliveredError is returned by Close when handlers did not finish before its deadline.
+type UndeliveredError struct {
+	Undelivered []UndeliveredEvent // events no handler started, dead lettered when a store is configured
+	InFlight    int                // handlers still running when Close returned
+	Err         error              // the error of the Close context
+}
+
+func (e *UndeliveredError) Error() string {
+	return fmt.Sprintf("event observer closed with %d undelivered events and %d handlers still running: %s", len(e.Undelivered), e.InFlight, e.Err)
+}
+
+func (e *UndeliveredError) Unwrap() error {
+	return e.Err
+}
+
+// Close stops accepting events and waits for the queued and in-flight ones to be handled,
+// including the DeliverySync ones within Publish, until ctx is done. Then workers stop
+// picking up events, retries are given up, and the events left in the queues are dead
+// lettered and reported in an *UndeliveredError. With a transport, consumers stop receiving
+// messages and the transport is closed once they returned. Scheduled events not published
+// yet are left in the schedule store.
+//
+// Close waits for the handlers, so they cannot call it: it returns ErrCloseInHandler when
+// ctx is the one of a handler, and would wait for the handler itself with another context.
+func (eo *EventObserver) Close(ctx context.Context) error {
+	if handling, _ := ctx.Value(handlingContextKey{}).(*EventObserver); handling == eo {
+		return ErrCloseInHandler
+	}
+
+	eo.closeMu.Lock()
+	if eo.closed {
+		eo.closeMu.Unlock()
+		return ErrObserverClosed
+	}
+	eo.closed = true
+	eo.closeMu.Unlock()
+
+	eo.mu.RLock()
+	pools := make([]*workerPool, 0, len(eo.pools))
+	for _, pool := range eo.pools {
+		pools = append(pools, pool)
+	}
+	eo.mu.RUnlock()
+
+	log.Info("closing event observer of %s, waiting for %d pools", eo.serviceName, len(pools))
+	eo.stopConsumers()
+	for _, pool := range pools {
+		pool.close()
+	}
+	pools = append(pools, eo.closedPools...)
+
+	waitErr := waitGroup(ctx, &eo.consumers)
+	if waitErr == nil {
+		waitErr = waitGroup(ctx, &eo.syncDeliveries)
+	}
+	for _, pool := range pools {
+		if waitErr != nil {
+			break
+		}
+		waitErr = pool.wait(ctx)
+	}
+	if waitErr == nil {
+		log.Info("event observer of %s closed", eo.serviceName)
+		return eo.closeTransport()
+	}
+
+	close(eo.stop)
+	undeliveredErr := &UndeliveredError{Err: waitErr}
+	for _, pool := range pools {
+		for _, j := range pool.drain() {
+			undeliveredErr.Undelivered = append(undeliveredErr.Undelivered, UndeliveredEvent{SubscriberName: j.subscriber.SubscriberName, Event: j.event})
+			eo.deadLetter(context.WithoutCancel(ctx), j.subscriber, j.event, ErrObserverClosed, 0)
+		}
+		undeliveredErr.InFlight += int(pool.inFlight.Load())
+	}
+	log.WithError(undeliveredErr).Error("event observer of %s did not close in time", eo.serviceName)
+
+	if err := eo.closeTransport(); err != nil {
+		return errors.Join(undeliveredErr, err)
+	}
+	return undeliveredErr
+}
+
+func (eo *EventObserver) isClosed() bool {
+	eo.closeMu.RLock()
+	defer eo.closeMu.RUnlock()
+	return eo.closed
+}
+
+// waitGroup blocks until wg is done or ctx is done.
+func waitGroup(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (eo *EventObserver) closeTransport() error {
+	if eo.transport == nil || !eo.ownsTransport {
+		return nil
+	}
+	if err := eo.transport.Close(); err != nil {
+		return fmt.Errorf("failed to close transport: %w", err)
+	}
+	return nil
+}
+
+// sleep waits for d, returning false when the EventObserver stopped first.
+func (eo *EventObserver) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-eo.stop:
+		return false
+	}
+}
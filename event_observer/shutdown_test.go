@@ -0,0 +1,175 @@
+package event_observer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClose(t *testing.T) {
+	eo := NewEventObserver("some-service-name")
+	var handled []any
+	var mu sync.Mutex
+
+	eo.Subscribe("test-topic", Subscriber{
+		TopicName:      "test-topic",
+		SubscriberName: "slow",
+		Pool:           &PoolConfig{Workers: 1},
+		HandlerFunc: func(ctx context.Context, event *Event) error {
+			time.Sleep(5 * time.Millisecond)
+			mu.Lock()
+			handled = append(handled, event.Data)
+			mu.Unlock()
+			return nil
+		},
+	})
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, eo.Publish(context.Background(), &Event{Topic: "test-topic", Data: i}))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, eo.Close(ctx))
+
+	mu.Lock()
+	assert.Equal(t, []any{0, 1, 2}, handled)
+	mu.Unlock()
+
+	assert.ErrorIs(t, eo.Publish(context.Background(), &Event{Topic: "test-topic"}), ErrObserverClosed)
+	assert.ErrorIs(t, eo.Close(ctx), ErrObserverClosed)
+}
+
+func TestCloseInHandler(t *testing.T) {
+	eo := NewEventObserver("some-service-name", WithDeliveryMode(DeliverySync))
+
+	var closeErr error
+	assert.NoError(t, eo.Subscribe("test-topic", Subscriber{
+		SubscriberName: "closing",
+		HandlerFunc: func(ctx context.Context, event *Event) error {
+			closeErr = eo.Close(ctx)
+			return nil
+		},
+	}))
+
+	assert.NoError(t, eo.Publish(context.Background(), &Event{Topic: "test-topic"}))
+	assert.ErrorIs(t, closeErr, ErrCloseInHandler)
+	assert.NoError(t, eo.Close(context.Background()), "the observer is still open")
+}
+
+func TestCloseDeadline(t *testing.T) {
+	store := NewInMemoryDeadLetterStore()
+	eo := NewEventObserver("some-service-name", WithDeadLetterStore(store))
+	release := make(chan struct{})
+	defer close(release)
+
+	eo.Subscribe("test-topic", Subscriber{
+		TopicName:      "test-topic",
+		SubscriberName: "stuck",
+		Pool:           &PoolConfig{Workers: 1},
+		HandlerFunc: func(ctx context.Context, event *Event) error {
+			<-release
+			return nil
+		},
+	})
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, eo.Publish(context.Background(), &Event{Topic: "test-topic", Data: i}))
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := eo.Close(ctx)
+
+	var undeliveredErr *UndeliveredError
+	if assert.True(t, errors.As(err, &undeliveredErr)) {
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, 1, undeliveredErr.InFlight)
+		if assert.Len(t, undeliveredErr.Undelivered, 2) {
+			assert.Equal(t, "stuck", undeliveredErr.Undelivered[0].SubscriberName)
+			assert.Equal(t, 1, undeliveredErr.Undelivered[0].Event.Data)
+		}
+	}
+
+	deadLetters, err := store.List(context.Background(), DeadLetterFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, deadLetters, 2)
+}
+
+func TestCloseAbortsRetries(t *testing.T) {
+	store := NewInMemoryDeadLetterStore()
+	eo := NewEventObserver("some-service-name",
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Hour}),
+		WithDeadLetterStore(store),
+	)
+
+	eo.Subscribe("test-topic", Subscriber{
+		TopicName:      "test-topic",
+		SubscriberName: "failing",
+		HandlerFunc: func(ctx context.Context, event *Event) error {
+			return assert.AnError
+		},
+	})
+	assert.NoError(t, eo.Publish(context.Background(), &Event{Topic: "test-topic"}))
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, eo.Close(ctx), context.DeadlineExceeded)
+
+	time.Sleep(10 * time.Millisecond)
+	deadLetters, err := store.List(context.Background(), DeadLetterFilter{})
+	assert.NoError(t, err)
+	if assert.Len(t, deadLetters, 1) {
+		assert.Equal(t, 1, deadLetters[0].Attempts)
+	}
+}
+
+func TestCloseInterruptsBlockedPublish(t *testing.T) {
+	eo := NewEventObserver("some-service-name")
+	release := make(chan struct{})
+	defer close(release)
+
+	assert.NoError(t, eo.Subscribe("test-topic", Subscriber{
+		SubscriberName: "stuck",
+		Pool:           &PoolConfig{Workers: 1, QueueSize: 1, FullPolicy: QueueFullBlock},
+		HandlerFunc: func(ctx context.Context, event *Event) error {
+			<-release
+			return nil
+		},
+	}))
+
+	// the first event is handled, the second fills the queue
+	assert.NoError(t, eo.Publish(context.Background(), &Event{Topic: "test-topic", Data: 0}))
+	time.Sleep(10 * time.Millisecond)
+	assert.NoError(t, eo.Publish(context.Background(), &Event{Topic: "test-topic", Data: 1}))
+
+	published := make(chan error, 1)
+	go func() {
+		published <- eo.Publish(context.Background(), &Event{Topic: "test-topic", Data: 2})
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := eo.Close(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 500*time.Millisecond, "Close returns at its deadline")
+
+	select {
+	case err := <-published:
+		assert.ErrorIs(t, err, ErrObserverClosed)
+	case <-time.After(time.Second):
+		t.Fatal("blocked publish was not interrupted")
+	}
+
+	// a pending Close does not block other callers
+	assert.ErrorIs(t, eo.Publish(context.Background(), &Event{Topic: "test-topic"}), ErrObserverClosed)
+	assert.ErrorIs(t, eo.Subscribe("other-topic", Subscriber{HandlerFunc: func(ctx context.Context, event *Event) error { return nil }}), ErrObserverClosed)
+}
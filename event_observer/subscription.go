@@ -0,0 +1,205 @@
+package event_observer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NusaCrew/atlas-go/log"
+)
+
+const (
+	topicSeparator         = "."
+	singleTokenWildcard    = "*"
+	trailingTokensWildcard = ">"
+)
+
+var (
+	ErrInvalidTopic        = errors.New("invalid topic")
+	ErrDuplicateSubscriber = errors.New("subscriber already subscribed to topic")
+	ErrSubscriberNotFound  = errors.New("subscriber not found")
+)
+
+// validatePattern checks that topic has no empty token and that ">" is only its last token.
+func validatePattern(topic string) error {
+	tokens := strings.Split(topic, topicSeparator)
+	for i, token := range tokens {
+		if token == "" {
+			return fmt.Errorf("%w %q: empty token", ErrInvalidTopic, topic)
+		}
+		if token == trailingTokensWildcard && i != len(tokens)-1 {
+			return fmt.Errorf("%w %q: %s must be the last token", ErrInvalidTopic, topic, trailingTokensWildcard)
+		}
+	}
+	return nil
+}
+
+func isPattern(topic string) bool {
+	return strings.Contains(topic, singleTokenWildcard) || strings.Contains(topic, trailingTokensWildcard)
+}
+
+// matchTopic reports whether topic matches pattern, where "*" matches a single token and
+// a trailing ">" one or more tokens.
+func matchTopic(pattern, topic string) bool {
+	patternTokens := strings.Split(pattern, topicSeparator)
+	topicTokens := strings.Split(topic, topicSeparator)
+	for i, token := range patternTokens {
+		if token == trailingTokensWildcard {
+			return len(topicTokens) > i
+		}
+		if i >= len(topicTokens) {
+			return false
+		}
+		if token != singleTokenWildcard && token != topicTokens[i] {
+			return false
+		}
+	}
+	return len(patternTokens) == len(topicTokens)
+}
+
+// subscribersOf returns the subscribers of topic and of the patterns matching it.
+func (eo *EventObserver) subscribersOf(topic string) []Subscriber {
+	eo.mu.RLock()
+	defer eo.mu.RUnlock()
+
+	subscribers := append([]Subscriber(nil), eo.subscribers[topic]...)
+	for pattern, patternSubscribers := range eo.subscribers {
+		if pattern != topic && isPattern(pattern) && matchTopic(pattern, topic) {
+			subscribers = append(subscribers, patternSubscribers...)
+		}
+	}
+	return subscribers
+}
+
+// Unsubscribe removes the subscriber named subscriberName from topic, which must be the
+// topic or pattern it subscribed to. Events already queued for it are still handled, and
+// its transport consumer stops receiving messages.
+func (eo *EventObserver) Unsubscribe(topic, subscriberName string) error {
+	eo.closeMu.Lock()
+	defer eo.closeMu.Unlock()
+	if eo.closed {
+		return fmt.Errorf("subscriber %s cannot leave topic %s: %w", subscriberName, topic, ErrObserverClosed)
+	}
+
+	eo.mu.Lock()
+	defer eo.mu.Unlock()
+	subscribers := eo.subscribers[topic]
+	index := -1
+	for i, s := range subscribers {
+		if s.SubscriberName == subscriberName {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return fmt.Errorf("subscriber %s of topic %s: %w", subscriberName, topic, ErrSubscriberNotFound)
+	}
+
+	subscriber := subscribers[index]
+	remaining := append(subscribers[:index:index], subscribers[index+1:]...)
+	if len(remaining) == 0 {
+		delete(eo.subscribers, topic)
+	} else {
+		eo.subscribers[topic] = remaining
+	}
+
+	if subscriber.stopConsuming != nil {
+		subscriber.stopConsuming()
+	}
+	if subscriber.pool != nil && !usesPool(remaining, subscriber.pool) {
+		subscriber.pool.close()
+		delete(eo.pools, subscriber.pool.name)
+		eo.closedPools = append(eo.closedPools, subscriber.pool)
+	}
+	log.Info("subscriber %s successfully left topic %s", subscriberName, topic)
+	return nil
+}
+
+func usesPool(subscribers []Subscriber, pool *workerPool) bool {
+	for _, s := range subscribers {
+		if s.pool == pool {
+			return true
+		}
+	}
+	return false
+}
+
+// TopicInfo describes a topic, or pattern, and its subscribers.
+type TopicInfo struct {
+	Topic       string           `json:"topic"`
+	Subscribers []SubscriberInfo `json:"subscribers"`
+}
+
+// SubscriberInfo describes a subscriber. Pool and QueueDepth are empty with a transport.
+type SubscriberInfo struct {
+	Name        string        `json:"name"`
+	Pool        string        `json:"pool,omitempty"`
+	Workers     int           `json:"workers,omitempty"`
+	Mode        string        `json:"mode"`
+	QueueDepth  int           `json:"queue_depth"`
+	InFlight    int           `json:"in_flight"`
+	MaxAttempts int           `json:"max_attempts"`
+	Timeout     time.Duration `json:"timeout"`
+}
+
+// Topics returns the topics and patterns with subscribers, sorted by name.
+func (eo *EventObserver) Topics() []TopicInfo {
+	eo.mu.RLock()
+	defer eo.mu.RUnlock()
+
+	topics := make([]TopicInfo, 0, len(eo.subscribers))
+	for topic, subscribers := range eo.subscribers {
+		info := TopicInfo{Topic: topic}
+		for _, s := range subscribers {
+			policy := eo.retryPolicy
+			if s.RetryPolicy != nil {
+				policy = *s.RetryPolicy
+			}
+			timeout := s.Timeout
+			if timeout <= 0 {
+				timeout = defaultHandlerTimeout
+			}
+
+			subscriberInfo := SubscriberInfo{
+				Name:        s.SubscriberName,
+				Mode:        eo.deliveryModeOf(s).String(),
+				MaxAttempts: policy.attempts(),
+				Timeout:     timeout,
+			}
+			if s.pool != nil {
+				subscriberInfo.Pool = s.pool.name
+				subscriberInfo.Workers = s.pool.config.Workers
+				subscriberInfo.QueueDepth = s.pool.depth()
+				subscriberInfo.InFlight = int(s.pool.inFlight.Load())
+			}
+			info.Subscribers = append(info.Subscribers, subscriberInfo)
+		}
+		topics = append(topics, info)
+	}
+
+	sort.Slice(topics, func(i, j int) bool {
+		return topics[i].Topic < topics[j].Topic
+	})
+	return topics
+}
+
+// NewAdminHandler returns an http.Handler responding with the Topics of eo as JSON, meant
+// to be mounted on an internal admin endpoint.
+func NewAdminHandler(eo *EventObserver) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(eo.Topics()); err != nil {
+			log.WithError(err).Error("failed to encode topics of event observer")
+		}
+	})
+}
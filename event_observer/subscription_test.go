@@ -0,0 +1,208 @@
+package event_observer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchTopic(t *testing.T) {
+	testCases := []struct {
+		name    string
+		pattern string
+		topic   string
+		match   bool
+	}{
+		{name: "exact", pattern: "order.created", topic: "order.created", match: true},
+		{name: "different", pattern: "order.created", topic: "order.paid", match: false},
+		{name: "single token wildcard", pattern: "order.*", topic: "order.created", match: true},
+		{name: "single token wildcard in the middle", pattern: "order.*.eu", topic: "order.created.eu", match: true},
+		{name: "single token wildcard matches one token", pattern: "order.*", topic: "order.created.eu", match: false},
+		{name: "single token wildcard needs a token", pattern: "order.*", topic: "order", match: false},
+		{name: "trailing wildcard", pattern: "order.>", topic: "order.created.eu", match: true},
+		{name: "trailing wildcard needs a token", pattern: "order.>", topic: "order", match: false},
+		{name: "trailing wildcard alone", pattern: ">", topic: "order.created", match: true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.match, matchTopic(tc.pattern, tc.topic))
+		})
+	}
+}
+
+func TestSubscribeInvalidTopic(t *testing.T) {
+	eo := NewEventObserver("some-service-name")
+	handler := func(ctx context.Context, event *Event) error { return nil }
+
+	assert.ErrorIs(t, eo.Subscribe("order..created", Subscriber{HandlerFunc: handler}), ErrInvalidTopic)
+	assert.ErrorIs(t, eo.Subscribe("order.>.eu", Subscriber{HandlerFunc: handler}), ErrInvalidTopic)
+	assert.NoError(t, eo.Subscribe("order.*.eu", Subscriber{HandlerFunc: handler}))
+}
+
+func TestSubscribeDuplicate(t *testing.T) {
+	eo := NewEventObserver("some-service-name")
+	subscriber := Subscriber{
+		SubscriberName: "subscriber",
+		HandlerFunc:    func(ctx context.Context, event *Event) error { return nil },
+	}
+
+	assert.NoError(t, eo.Subscribe("test-topic", subscriber))
+	assert.ErrorIs(t, eo.Subscribe("test-topic", subscriber), ErrDuplicateSubscriber)
+	assert.NoError(t, eo.Subscribe("other-topic", subscriber), "names are unique per topic")
+
+	unnamed := Subscriber{HandlerFunc: subscriber.HandlerFunc}
+	assert.NoError(t, eo.Subscribe("test-topic", unnamed))
+	assert.NoError(t, eo.Subscribe("test-topic", unnamed), "unnamed subscribers get a generated name")
+	assert.Len(t, eo.subscribers["test-topic"], 3)
+}
+
+func TestPublishWildcard(t *testing.T) {
+	eo := NewEventObserver("some-service-name")
+
+	var mu sync.Mutex
+	received := make(map[string][]string)
+	subscribe := func(topic, name string) {
+		assert.NoError(t, eo.Subscribe(topic, Subscriber{
+			SubscriberName: name,
+			HandlerFunc: func(ctx context.Context, event *Event) error {
+				mu.Lock()
+				defer mu.Unlock()
+				received[name] = append(received[name], event.Topic)
+				return nil
+			},
+		}))
+	}
+	subscribe("order.created", "exact")
+	subscribe("order.*", "single")
+	subscribe("order.>", "trailing")
+
+	assert.NoError(t, eo.Publish(context.Background(), &Event{Topic: "order.created"}))
+	assert.NoError(t, eo.Publish(context.Background(), &Event{Topic: "order.created.eu"}))
+	assert.NoError(t, eo.Publish(context.Background(), &Event{Topic: "payment.created"}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, eo.Close(ctx))
+
+	assert.Equal(t, map[string][]string{
+		"exact":    {"order.created"},
+		"single":   {"order.created"},
+		"trailing": {"order.created", "order.created.eu"},
+	}, received)
+}
+
+func TestUnsubscribe(t *testing.T) {
+	eo := NewEventObserver("some-service-name")
+
+	var mu sync.Mutex
+	var calls int
+	assert.NoError(t, eo.Subscribe("order.*", Subscriber{
+		SubscriberName: "subscriber",
+		HandlerFunc: func(ctx context.Context, event *Event) error {
+			mu.Lock()
+			defer mu.Unlock()
+			calls++
+			return nil
+		},
+	}))
+
+	assert.ErrorIs(t, eo.Unsubscribe("order.created", "subscriber"), ErrSubscriberNotFound)
+	assert.NoError(t, eo.Unsubscribe("order.*", "subscriber"))
+	assert.ErrorIs(t, eo.Unsubscribe("order.*", "subscriber"), ErrSubscriberNotFound)
+	assert.Empty(t, eo.subscribers)
+	assert.Empty(t, eo.pools)
+
+	assert.NoError(t, eo.Publish(context.Background(), &Event{Topic: "order.created"}))
+	time.Sleep(10 * time.Millisecond)
+	mu.Lock()
+	assert.Zero(t, calls)
+	mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, eo.Close(ctx))
+}
+
+func TestUnsubscribeSharedPool(t *testing.T) {
+	eo := NewEventObserver("some-service-name", WithTopicPool("test-topic", PoolConfig{Workers: 1, QueueSize: 1}))
+	handler := func(ctx context.Context, event *Event) error { return nil }
+	assert.NoError(t, eo.Subscribe("test-topic", Subscriber{SubscriberName: "first", HandlerFunc: handler}))
+	assert.NoError(t, eo.Subscribe("test-topic", Subscriber{SubscriberName: "second", HandlerFunc: handler}))
+
+	assert.NoError(t, eo.Unsubscribe("test-topic", "first"))
+	assert.Contains(t, eo.pools, "test-topic", "pool is still used by the second subscriber")
+	assert.NoError(t, eo.Unsubscribe("test-topic", "second"))
+	assert.NotContains(t, eo.pools, "test-topic")
+}
+
+func TestUnsubscribeWithTransport(t *testing.T) {
+	transport := NewMemoryTransport()
+	eo := NewEventObserver("some-service-name", WithTransport(transport, nil))
+
+	var mu sync.Mutex
+	var calls int
+	assert.NoError(t, eo.Subscribe("test-topic", Subscriber{
+		SubscriberName: "subscriber",
+		HandlerFunc: func(ctx context.Context, event *Event) error {
+			mu.Lock()
+			defer mu.Unlock()
+			calls++
+			return nil
+		},
+	}))
+	time.Sleep(10 * time.Millisecond)
+
+	assert.NoError(t, eo.Unsubscribe("test-topic", "subscriber"))
+	time.Sleep(10 * time.Millisecond)
+	assert.NoError(t, eo.Publish(context.Background(), &Event{Topic: "test-topic"}))
+	time.Sleep(10 * time.Millisecond)
+
+	mu.Lock()
+	assert.Zero(t, calls)
+	mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, eo.Close(ctx))
+}
+
+func TestTopics(t *testing.T) {
+	eo := NewEventObserver("some-service-name", WithRetryPolicy(RetryPolicy{MaxAttempts: 3}))
+	handler := func(ctx context.Context, event *Event) error { return nil }
+	assert.NoError(t, eo.Subscribe("order.>", Subscriber{SubscriberName: "audit", HandlerFunc: handler, Timeout: time.Second}))
+	assert.NoError(t, eo.Subscribe("order.created", Subscriber{
+		SubscriberName: "email",
+		HandlerFunc:    handler,
+		Pool:           &PoolConfig{Workers: 2, QueueSize: 10},
+		RetryPolicy:    &NoRetry,
+	}))
+
+	expected := []TopicInfo{
+		{Topic: "order.>", Subscribers: []SubscriberInfo{
+			{Name: "audit", Pool: "order.>/audit", Workers: DefaultPoolConfig.Workers, Mode: "ASYNC", MaxAttempts: 3, Timeout: time.Second},
+		}},
+		{Topic: "order.created", Subscribers: []SubscriberInfo{
+			{Name: "email", Pool: "order.created/email", Workers: 2, Mode: "ASYNC", MaxAttempts: 1, Timeout: defaultHandlerTimeout},
+		}},
+	}
+	assert.Equal(t, expected, eo.Topics())
+
+	recorder := httptest.NewRecorder()
+	NewAdminHandler(eo).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var topics []TopicInfo
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &topics))
+	assert.Equal(t, expected, topics)
+
+	recorder = httptest.NewRecorder()
+	NewAdminHandler(eo).ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+}
@@ -0,0 +1,140 @@
+package event_observer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	goProtoValidators "github.com/mwitkow/go-proto-validators"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+var ErrInvalidEvent = errors.New("invalid event")
+
+// Topic publishes and subscribes to the events of a topic whose data is a T, so publishers
+// and handlers share the topic name and the data type.
+type Topic[T any] struct {
+	observer   *EventObserver
+	name       string
+	validators []func(data T) error
+}
+
+type TopicOption[T any] func(t *Topic[T])
+
+// WithValidator validates data before it is published and before it is handled. Data
+// implementing Validate() error, like messages generated by protoc-gen-govalidators, is
+// validated without it.
+func WithValidator[T any](validate func(data T) error) TopicOption[T] {
+	return func(t *Topic[T]) {
+		t.validators = append(t.validators, validate)
+	}
+}
+
+func NewTopic[T any](observer *EventObserver, name string, opts ...TopicOption[T]) *Topic[T] {
+	RegisterDataType[T]()
+	t := &Topic[T]{
+		observer: observer,
+		name:     name,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+func (t *Topic[T]) Name() string {
+	return t.name
+}
+
+// Publish validates data and publishes it with EventObserver.Publish.
+func (t *Topic[T]) Publish(ctx context.Context, data T) error {
+	if err := t.validate(data); err != nil {
+		return err
+	}
+	return t.observer.Publish(ctx, &Event{Topic: t.name, Data: data})
+}
+
+// Subscribe subscribes handler to the topic.
+func (t *Topic[T]) Subscribe(subscriberName string, handler func(ctx context.Context, data T) error) error {
+	return t.SubscribeWith(Subscriber{SubscriberName: subscriberName}, handler)
+}
+
+// SubscribeWith subscribes handler to the topic with the settings of subscriber, whose
+// TopicName and HandlerFunc are replaced.
+func (t *Topic[T]) SubscribeWith(subscriber Subscriber, handler func(ctx context.Context, data T) error) error {
+	subscriber.TopicName = t.name
+	subscriber.HandlerFunc = func(ctx context.Context, event *Event) error {
+		data, err := t.decode(event.Data)
+		if err != nil {
+			return err
+		}
+		if err := t.validate(data); err != nil {
+			return err
+		}
+		return handler(ctx, data)
+	}
+	return t.observer.Subscribe(t.name, subscriber)
+}
+
+func (t *Topic[T]) validate(data T) error {
+	if validator, ok := any(data).(goProtoValidators.Validator); ok {
+		if err := validator.Validate(); err != nil {
+			return fmt.Errorf("%w of topic %s: %w", ErrInvalidEvent, t.name, err)
+		}
+	}
+	for _, validate := range t.validators {
+		if err := validate(data); err != nil {
+			return fmt.Errorf("%w of topic %s: %w", ErrInvalidEvent, t.name, err)
+		}
+	}
+	return nil
+}
+
+// decode returns data as a T, unmarshalling it when the event went through a Codec: JSON
+// for json.RawMessage, and the protobuf binary format for []byte when T is a proto.Message.
+func (t *Topic[T]) decode(data any) (T, error) {
+	var result T
+	switch d := data.(type) {
+	case T:
+		return d, nil
+	case json.RawMessage:
+		var err error
+		if msg, ok := newProtoMessage[T](); ok {
+			err = protojson.Unmarshal(d, msg)
+			result, _ = msg.(T)
+		} else {
+			err = json.Unmarshal(d, &result)
+		}
+		if err != nil {
+			return result, fmt.Errorf("%w of topic %s: %w", ErrInvalidEvent, t.name, err)
+		}
+		return result, nil
+	case []byte:
+		msg, ok := newProtoMessage[T]()
+		if !ok {
+			if err := json.Unmarshal(d, &result); err != nil {
+				return result, fmt.Errorf("%w of topic %s: %w", ErrInvalidEvent, t.name, err)
+			}
+			return result, nil
+		}
+		if err := proto.Unmarshal(d, msg); err != nil {
+			return result, fmt.Errorf("%w of topic %s: %w", ErrInvalidEvent, t.name, err)
+		}
+		result, _ = msg.(T)
+		return result, nil
+	default:
+		return result, fmt.Errorf("%w of topic %s: data is %T, expected %T", ErrInvalidEvent, t.name, data, result)
+	}
+}
+
+// newProtoMessage returns a new message when T is a generated protobuf message pointer.
+func newProtoMessage[T any]() (proto.Message, bool) {
+	var zero T
+	msg, ok := any(zero).(proto.Message)
+	if !ok {
+		return nil, false
+	}
+	return msg.ProtoReflect().New().Interface(), true
+}
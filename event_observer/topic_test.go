@@ -0,0 +1,152 @@
+package event_observer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type userCreated struct {
+	ID    int    `json:"id"`
+	Email string `json:"email"`
+}
+
+type validatedUser struct {
+	Email string `json:"email"`
+}
+
+func (u validatedUser) Validate() error {
+	if u.Email == "" {
+		return errors.New("email is required")
+	}
+	return nil
+}
+
+func TestTopic(t *testing.T) {
+	eo := NewEventObserver("some-service-name")
+	topic := NewTopic[userCreated](eo, "user.created")
+	assert.Equal(t, "user.created", topic.Name())
+
+	received := make(chan userCreated, 1)
+	topic.Subscribe("welcome", func(ctx context.Context, data userCreated) error {
+		received <- data
+		return nil
+	})
+	assert.Equal(t, "user.created", eo.subscribers["user.created"][0].TopicName)
+
+	assert.NoError(t, topic.Publish(context.Background(), userCreated{ID: 1, Email: "a@b.c"}))
+	select {
+	case data := <-received:
+		assert.Equal(t, userCreated{ID: 1, Email: "a@b.c"}, data)
+	case <-time.After(time.Second):
+		t.Fatal("event was not delivered")
+	}
+}
+
+func TestTopicDecode(t *testing.T) {
+	testCases := []struct {
+		name     string
+		codec    Codec
+		data     any
+		decode   func(data any) (any, error)
+		expected any
+	}{
+		{
+			name:  "json",
+			codec: JSONCodec,
+			data:  userCreated{ID: 1, Email: "a@b.c"},
+			decode: func(data any) (any, error) {
+				return NewTopic[userCreated](nil, "test-topic").decode(data)
+			},
+			expected: userCreated{ID: 1, Email: "a@b.c"},
+		},
+		{
+			name:  "proto message through json",
+			codec: JSONCodec,
+			data:  wrapperspb.String("value"),
+			decode: func(data any) (any, error) {
+				msg, err := NewTopic[*wrapperspb.StringValue](nil, "test-topic").decode(data)
+				return msg.GetValue(), err
+			},
+			expected: "value",
+		},
+		{
+			name:  "proto message through proto",
+			codec: ProtoCodec,
+			data:  wrapperspb.String("value"),
+			decode: func(data any) (any, error) {
+				msg, err := NewTopic[*wrapperspb.StringValue](nil, "test-topic").decode(data)
+				return msg.GetValue(), err
+			},
+			expected: "value",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			payload, err := tc.codec.Encode(&Event{Topic: "test-topic", Data: tc.data})
+			assert.NoError(t, err)
+			event, err := tc.codec.Decode(payload)
+			assert.NoError(t, err)
+
+			actual, err := tc.decode(event.Data)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+
+	_, err := NewTopic[userCreated](nil, "test-topic").decode(42)
+	assert.ErrorIs(t, err, ErrInvalidEvent)
+}
+
+func TestProtoCodecRequiresProtoMessage(t *testing.T) {
+	_, err := ProtoCodec.Encode(&Event{Topic: "test-topic", Data: "not a proto message"})
+	assert.Error(t, err)
+}
+
+func TestTopicValidation(t *testing.T) {
+	eo := NewEventObserver("some-service-name")
+
+	topic := NewTopic[validatedUser](eo, "user.created")
+	assert.ErrorIs(t, topic.Publish(context.Background(), validatedUser{}), ErrInvalidEvent)
+	assert.NoError(t, topic.Publish(context.Background(), validatedUser{Email: "a@b.c"}))
+
+	validator, err := JSONSchemaValidator[userCreated](`{
+		"type": "object",
+		"properties": {"id": {"type": "integer", "minimum": 1}},
+		"required": ["id"]
+	}`)
+	assert.NoError(t, err)
+
+	schemaTopic := NewTopic[userCreated](eo, "user.updated", WithValidator(validator))
+	assert.ErrorIs(t, schemaTopic.Publish(context.Background(), userCreated{ID: 0}), ErrInvalidEvent)
+	assert.NoError(t, schemaTopic.Publish(context.Background(), userCreated{ID: 1}))
+
+	_, err = JSONSchemaValidator[userCreated](`{"type": 1}`)
+	assert.Error(t, err)
+}
+
+func TestTopicWithTransport(t *testing.T) {
+	eo := NewEventObserver("some-service-name", WithTransport(NewMemoryTransport(), ProtoCodec))
+	topic := NewTopic[*wrapperspb.StringValue](eo, "test-topic")
+
+	received := make(chan string, 1)
+	topic.Subscribe("consumer", func(ctx context.Context, data *wrapperspb.StringValue) error {
+		received <- data.GetValue()
+		return nil
+	})
+	time.Sleep(10 * time.Millisecond)
+
+	assert.NoError(t, topic.Publish(context.Background(), wrapperspb.String("value")))
+	select {
+	case value := <-received:
+		assert.Equal(t, "value", value)
+	case <-time.After(time.Second):
+		t.Fatal("event was not delivered")
+	}
+}
@@ -0,0 +1,35 @@
+package event_observer
+
+import (
+	"context"
+)
+
+const (
+	// ContentTypeHeader is the Message header naming the Codec the payload was encoded with.
+	ContentTypeHeader = "content-type"
+	// DataTypeHeader is the Message header naming the type of the data of the event, decoded
+	// into that type when registered by RegisterDataType or NewTopic.
+	DataTypeHeader = "data-type"
+)
+
+// Message is an encoded Event as carried by a Transport.
+type Message struct {
+	ID      string // assigned by the transport when consuming
+	Topic   string
+	Key     string // partitions messages in transports supporting it, e.g. Kafka
+	Payload []byte
+	Headers map[string]string
+}
+
+// MessageHandler handles a consumed message. Returning nil acknowledges the message,
+// returning an error leaves it to be redelivered.
+type MessageHandler func(ctx context.Context, msg *Message) error
+
+// Transport carries events across processes through a message broker.
+type Transport interface {
+	Publish(ctx context.Context, msg *Message) error
+	// Subscribe consumes topic as a member of group, so each message is handled by a single
+	// member of every group. It blocks until ctx is done, returning nil, or consumption fails.
+	Subscribe(ctx context.Context, topic, group string, handler MessageHandler) error
+	Close() error
+}
@@ -0,0 +1,136 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	eo "github.com/NusaCrew/atlas-go/event_observer"
+	"github.com/NusaCrew/atlas-go/log"
+
+	"github.com/segmentio/kafka-go"
+)
+
+type Config struct {
+	Brokers []string
+	// RetryBackoff is the wait before handling a failed message again, defaults to 1s. Kafka
+	// commits offsets in order, so a failing message is retried until it succeeds.
+	RetryBackoff time.Duration
+	// AllowAutoTopicCreation creates topics on first publish when the brokers allow it.
+	AllowAutoTopicCreation bool
+}
+
+// writer and reader are the methods of kafka.Writer and kafka.Reader used by the transport.
+type writer interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+type reader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+type transport struct {
+	config    Config
+	writer    writer
+	newReader func(topic, group string) reader
+}
+
+// NewTransport returns an event_observer.Transport publishing to and consuming from Kafka,
+// with subscriber groups as Kafka consumer groups.
+func NewTransport(config Config) (eo.Transport, error) {
+	if len(config.Brokers) == 0 {
+		return nil, errors.New("cannot create kafka transport without brokers")
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = time.Second
+	}
+
+	return &transport{
+		config: config,
+		writer: &kafka.Writer{
+			Addr:                   kafka.TCP(config.Brokers...),
+			Balancer:               &kafka.Hash{},
+			RequiredAcks:           kafka.RequireAll,
+			AllowAutoTopicCreation: config.AllowAutoTopicCreation,
+		},
+		newReader: func(topic, group string) reader {
+			return kafka.NewReader(kafka.ReaderConfig{
+				Brokers: config.Brokers,
+				GroupID: group,
+				Topic:   topic,
+			})
+		},
+	}, nil
+}
+
+func (t *transport) Publish(ctx context.Context, msg *eo.Message) error {
+	headers := make([]kafka.Header, 0, len(msg.Headers))
+	for key, value := range msg.Headers {
+		headers = append(headers, kafka.Header{Key: key, Value: []byte(value)})
+	}
+
+	m := kafka.Message{
+		Topic:   msg.Topic,
+		Value:   msg.Payload,
+		Headers: headers,
+	}
+	if msg.Key != "" {
+		m.Key = []byte(msg.Key) // messages without key are balanced round robin
+	}
+	err := t.writer.WriteMessages(ctx, m)
+	if err != nil {
+		return fmt.Errorf("failed to write kafka message to topic %s: %w", msg.Topic, err)
+	}
+	return nil
+}
+
+func (t *transport) Subscribe(ctx context.Context, topic, group string, handler eo.MessageHandler) error {
+	reader := t.newReader(topic, group)
+	defer reader.Close()
+
+	for {
+		m, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to fetch kafka message from topic %s: %w", topic, err)
+		}
+
+		msg := &eo.Message{
+			ID:      fmt.Sprintf("%d-%d", m.Partition, m.Offset),
+			Topic:   m.Topic,
+			Key:     string(m.Key),
+			Payload: m.Value,
+			Headers: make(map[string]string, len(m.Headers)),
+		}
+		for _, header := range m.Headers {
+			msg.Headers[header.Key] = string(header.Value)
+		}
+
+		for {
+			err := handler(ctx, msg)
+			if err == nil {
+				break
+			}
+			log.WithError(err).Warning("failed to handle kafka message %s of topic %s, retrying in %s", msg.ID, topic, t.config.RetryBackoff)
+			select {
+			case <-time.After(t.config.RetryBackoff):
+			case <-ctx.Done():
+				return nil
+			}
+		}
+
+		if err := reader.CommitMessages(context.WithoutCancel(ctx), m); err != nil {
+			return fmt.Errorf("failed to commit kafka message %s of topic %s: %w", msg.ID, topic, err)
+		}
+	}
+}
+
+func (t *transport) Close() error {
+	return t.writer.Close()
+}
@@ -0,0 +1,152 @@
+package kafka
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	eo "github.com/NusaCrew/atlas-go/event_observer"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeBroker is a single partition topic, shared by the fake writer and readers.
+type fakeBroker struct {
+	mu        sync.Mutex
+	messages  []kafka.Message
+	committed map[string]int64 // next offset of every group
+	closed    bool
+}
+
+func newFakeBroker() *fakeBroker {
+	return &fakeBroker{committed: map[string]int64{}}
+}
+
+func (b *fakeBroker) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, m := range msgs {
+		m.Offset = int64(len(b.messages))
+		b.messages = append(b.messages, m)
+	}
+	return nil
+}
+
+func (b *fakeBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	return nil
+}
+
+func (b *fakeBroker) committedOffset(group string) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.committed[group]
+}
+
+type fakeReader struct {
+	broker *fakeBroker
+	group  string
+	next   int64
+}
+
+func (r *fakeReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	for {
+		r.broker.mu.Lock()
+		if r.next < int64(len(r.broker.messages)) {
+			m := r.broker.messages[r.next]
+			r.next++
+			r.broker.mu.Unlock()
+			return m, nil
+		}
+		r.broker.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return kafka.Message{}, ctx.Err()
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+func (r *fakeReader) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	r.broker.mu.Lock()
+	defer r.broker.mu.Unlock()
+	for _, m := range msgs {
+		r.broker.committed[r.group] = m.Offset + 1
+	}
+	return nil
+}
+
+func (r *fakeReader) Close() error {
+	return nil
+}
+
+func newTestTransport(broker *fakeBroker) *transport {
+	return &transport{
+		config: Config{RetryBackoff: time.Millisecond},
+		writer: broker,
+		newReader: func(topic, group string) reader {
+			return &fakeReader{broker: broker, group: group, next: broker.committedOffset(group)}
+		},
+	}
+}
+
+func TestNewTransport(t *testing.T) {
+	_, err := NewTransport(Config{})
+	assert.Error(t, err)
+
+	created, err := NewTransport(Config{Brokers: []string{"localhost:9092"}})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second, created.(*transport).config.RetryBackoff)
+}
+
+func TestTransport(t *testing.T) {
+	broker := newFakeBroker()
+	transport := newTestTransport(broker)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err := transport.Publish(ctx, &eo.Message{Topic: "user.created", Key: "user-1", Payload: []byte("payload"), Headers: map[string]string{eo.ContentTypeHeader: "application/json"}})
+	assert.NoError(t, err)
+	broker.mu.Lock()
+	assert.Equal(t, []byte("user-1"), broker.messages[0].Key)
+	broker.mu.Unlock()
+
+	var mu sync.Mutex
+	var received []*eo.Message
+	done := make(chan error)
+	go func() {
+		done <- transport.Subscribe(ctx, "user.created", "service.subscriber", func(ctx context.Context, msg *eo.Message) error {
+			mu.Lock()
+			defer mu.Unlock()
+			received = append(received, msg)
+			if len(received) == 1 {
+				return assert.AnError
+			}
+			return nil
+		})
+	}()
+
+	assert.Eventually(t, func() bool {
+		return broker.committedOffset("service.subscriber") == 1
+	}, time.Second, time.Millisecond, "failed message is retried before being committed")
+
+	mu.Lock()
+	if assert.Len(t, received, 2) {
+		assert.Equal(t, received[0], received[1])
+		assert.Equal(t, "0-0", received[1].ID)
+		assert.Equal(t, "user-1", received[1].Key)
+		assert.Equal(t, "payload", string(received[1].Payload))
+		assert.Equal(t, map[string]string{eo.ContentTypeHeader: "application/json"}, received[1].Headers)
+	}
+	mu.Unlock()
+
+	cancel()
+	assert.NoError(t, <-done)
+	assert.NoError(t, transport.Close())
+	assert.True(t, broker.closed)
+}
@@ -0,0 +1,139 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	eo "github.com/NusaCrew/atlas-go/event_observer"
+	"github.com/NusaCrew/atlas-go/log"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+const DefaultStream = "EVENTS"
+
+type Config struct {
+	Conn *nats.Conn
+	// Stream is the JetStream stream storing the events, defaults to DefaultStream.
+	Stream string
+	// Subjects creates or updates Stream to capture these subjects when set, e.g. "user.>".
+	// Otherwise the stream must exist.
+	Subjects []string
+	// AckWait is how long a message may be handled before it is redelivered, defaults to 30s.
+	AckWait time.Duration
+	// RetryBackoff is the wait before a failed message is redelivered, defaults to 1s.
+	RetryBackoff time.Duration
+}
+
+type transport struct {
+	config Config
+	js     jetstream.JetStream
+}
+
+// NewTransport returns an event_observer.Transport on NATS JetStream, with subscriber groups
+// as durable consumers of the stream filtered on the topic.
+func NewTransport(ctx context.Context, config Config) (eo.Transport, error) {
+	if config.Conn == nil {
+		return nil, errors.New("cannot create nats transport without connection")
+	}
+	if config.Stream == "" {
+		config.Stream = DefaultStream
+	}
+	if config.AckWait <= 0 {
+		config.AckWait = 30 * time.Second
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = time.Second
+	}
+
+	js, err := jetstream.New(config.Conn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create jetstream context: %w", err)
+	}
+
+	if len(config.Subjects) > 0 {
+		_, err := js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+			Name:     config.Stream,
+			Subjects: config.Subjects,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stream %s: %w", config.Stream, err)
+		}
+	}
+
+	return &transport{
+		config: config,
+		js:     js,
+	}, nil
+}
+
+func (t *transport) Publish(ctx context.Context, msg *eo.Message) error {
+	m := nats.NewMsg(msg.Topic)
+	m.Data = msg.Payload
+	for key, value := range msg.Headers {
+		m.Header.Set(key, value)
+	}
+
+	if _, err := t.js.PublishMsg(ctx, m); err != nil {
+		return fmt.Errorf("failed to publish nats message to subject %s: %w", msg.Topic, err)
+	}
+	return nil
+}
+
+// durableName replaces the characters not allowed in consumer names.
+func durableName(group string) string {
+	return strings.NewReplacer(".", "_", " ", "_", "*", "_", ">", "_", "/", "_", "\\", "_").Replace(group)
+}
+
+func (t *transport) Subscribe(ctx context.Context, topic, group string, handler eo.MessageHandler) error {
+	consumer, err := t.js.CreateOrUpdateConsumer(ctx, t.config.Stream, jetstream.ConsumerConfig{
+		Durable:       durableName(group),
+		FilterSubject: topic,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       t.config.AckWait,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create consumer %s for subject %s: %w", group, topic, err)
+	}
+
+	consumeCtx, err := consumer.Consume(func(m jetstream.Msg) {
+		msg := &eo.Message{
+			Topic:   m.Subject(),
+			Payload: m.Data(),
+			Headers: make(map[string]string, len(m.Headers())),
+		}
+		if metadata, err := m.Metadata(); err == nil {
+			msg.ID = strconv.FormatUint(metadata.Sequence.Stream, 10)
+		}
+		for key := range m.Headers() {
+			msg.Headers[strings.ToLower(key)] = m.Headers().Get(key)
+		}
+
+		if err := handler(ctx, msg); err != nil {
+			if err := m.NakWithDelay(t.config.RetryBackoff); err != nil {
+				log.WithError(err).Error("failed to nak nats message %s of subject %s", msg.ID, topic)
+			}
+			return
+		}
+		if err := m.Ack(); err != nil {
+			log.WithError(err).Error("failed to ack nats message %s of subject %s", msg.ID, topic)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to consume subject %s: %w", topic, err)
+	}
+
+	<-ctx.Done()
+	consumeCtx.Stop()
+	return nil
+}
+
+// Close leaves the connection open, it is owned by the caller.
+func (t *transport) Close() error {
+	return nil
+}
@@ -0,0 +1,151 @@
+package nats
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	eo "github.com/NusaCrew/atlas-go/event_observer"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeJetStream records the published messages and consumers, the handler of the last
+// consumer being called by the tests.
+type fakeJetStream struct {
+	jetstream.JetStream
+
+	mu        sync.Mutex
+	published []*nats.Msg
+	consumers []jetstream.ConsumerConfig
+	handler   jetstream.MessageHandler
+	stopped   bool
+}
+
+func (js *fakeJetStream) PublishMsg(ctx context.Context, msg *nats.Msg, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	js.published = append(js.published, msg)
+	return &jetstream.PubAck{Stream: DefaultStream, Sequence: uint64(len(js.published))}, nil
+}
+
+func (js *fakeJetStream) CreateOrUpdateConsumer(ctx context.Context, stream string, cfg jetstream.ConsumerConfig) (jetstream.Consumer, error) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	js.consumers = append(js.consumers, cfg)
+	return &fakeConsumer{js: js}, nil
+}
+
+func (js *fakeJetStream) consumerHandler() jetstream.MessageHandler {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	return js.handler
+}
+
+type fakeConsumer struct {
+	jetstream.Consumer
+	js *fakeJetStream
+}
+
+func (c *fakeConsumer) Consume(handler jetstream.MessageHandler, opts ...jetstream.PullConsumeOpt) (jetstream.ConsumeContext, error) {
+	c.js.mu.Lock()
+	defer c.js.mu.Unlock()
+	c.js.handler = handler
+	return &fakeConsumeContext{js: c.js}, nil
+}
+
+type fakeConsumeContext struct {
+	jetstream.ConsumeContext
+	js *fakeJetStream
+}
+
+func (c *fakeConsumeContext) Stop() {
+	c.js.mu.Lock()
+	defer c.js.mu.Unlock()
+	c.js.stopped = true
+}
+
+type fakeMsg struct {
+	jetstream.Msg
+	msg      *nats.Msg
+	sequence uint64
+	acked    bool
+	nakDelay time.Duration
+}
+
+func (m *fakeMsg) Subject() string      { return m.msg.Subject }
+func (m *fakeMsg) Data() []byte         { return m.msg.Data }
+func (m *fakeMsg) Headers() nats.Header { return m.msg.Header }
+func (m *fakeMsg) Ack() error           { m.acked = true; return nil }
+func (m *fakeMsg) NakWithDelay(delay time.Duration) error {
+	m.nakDelay = delay
+	return nil
+}
+
+func (m *fakeMsg) Metadata() (*jetstream.MsgMetadata, error) {
+	return &jetstream.MsgMetadata{Sequence: jetstream.SequencePair{Stream: m.sequence}}, nil
+}
+
+func TestDurableName(t *testing.T) {
+	assert.Equal(t, "order-service_Reserve_Stock", durableName("order-service.Reserve Stock"))
+	assert.Equal(t, "service_order__", durableName("service.order.>"))
+}
+
+func TestTransport(t *testing.T) {
+	_, err := NewTransport(context.Background(), Config{})
+	assert.Error(t, err)
+
+	js := &fakeJetStream{}
+	transport := &transport{
+		config: Config{Stream: DefaultStream, AckWait: time.Second, RetryBackoff: time.Minute},
+		js:     js,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err = transport.Publish(ctx, &eo.Message{Topic: "user.created", Payload: []byte("payload"), Headers: map[string]string{eo.ContentTypeHeader: "application/json"}})
+	assert.NoError(t, err)
+
+	var received []*eo.Message
+	done := make(chan error)
+	go func() {
+		done <- transport.Subscribe(ctx, "user.created", "service.subscriber", func(ctx context.Context, msg *eo.Message) error {
+			received = append(received, msg)
+			if len(received) == 1 {
+				return assert.AnError
+			}
+			return nil
+		})
+	}()
+	assert.Eventually(t, func() bool { return js.consumerHandler() != nil }, time.Second, time.Millisecond)
+
+	js.mu.Lock()
+	assert.Equal(t, "service_subscriber", js.consumers[0].Durable)
+	assert.Equal(t, "user.created", js.consumers[0].FilterSubject)
+	assert.Equal(t, jetstream.AckExplicitPolicy, js.consumers[0].AckPolicy)
+	msg := js.published[0]
+	js.mu.Unlock()
+
+	failed := &fakeMsg{msg: msg, sequence: 7}
+	js.consumerHandler()(failed)
+	assert.False(t, failed.acked)
+	assert.Equal(t, time.Minute, failed.nakDelay, "failed message is redelivered after the backoff")
+
+	handled := &fakeMsg{msg: msg, sequence: 7}
+	js.consumerHandler()(handled)
+	assert.True(t, handled.acked)
+
+	if assert.Len(t, received, 2) {
+		assert.Equal(t, "7", received[1].ID)
+		assert.Equal(t, "user.created", received[1].Topic)
+		assert.Equal(t, "payload", string(received[1].Payload))
+		assert.Equal(t, map[string]string{eo.ContentTypeHeader: "application/json"}, received[1].Headers)
+	}
+
+	cancel()
+	assert.NoError(t, <-done)
+	assert.True(t, js.stopped)
+}
@@ -0,0 +1,226 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	eo "github.com/NusaCrew/atlas-go/event_observer"
+	"github.com/NusaCrew/atlas-go/log"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const DefaultExchange = "atlas.events"
+
+type Config struct {
+	URL string
+	// Exchange is the durable topic exchange events are published to, defaults to DefaultExchange.
+	Exchange string
+	// Prefetch is the number of unacknowledged messages a subscriber may hold, defaults to 1.
+	Prefetch int
+	// RetryBackoff is the wait before a failed message is requeued, defaults to 1s.
+	RetryBackoff time.Duration
+	// DeadLetterExchange receives the failed messages instead of them being requeued when set,
+	// as the x-dead-letter-exchange of the queues. Queues declared without it must be deleted
+	// first, RabbitMQ refusing to redeclare a queue with other arguments.
+	DeadLetterExchange string
+}
+
+// connection and channel are the methods of amqp.Connection and amqp.Channel used by the
+// transport.
+type connection interface {
+	Channel() (channel, error)
+	Close() error
+}
+
+type channel interface {
+	ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error
+	QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error)
+	QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error
+	Qos(prefetchCount, prefetchSize int, global bool) error
+	PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+	ConsumeWithContext(ctx context.Context, queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error)
+	Close() error
+}
+
+type amqpConnection struct {
+	*amqp.Connection
+}
+
+func (c amqpConnection) Channel() (channel, error) {
+	ch, err := c.Connection.Channel()
+	if err != nil {
+		return nil, err
+	}
+	return ch, nil
+}
+
+type transport struct {
+	config Config
+	conn   connection
+
+	mu      sync.Mutex // amqp channels must not be shared between goroutines
+	channel channel
+}
+
+// NewTransport returns an event_observer.Transport on RabbitMQ. Topics are routing keys of a
+// topic exchange, and each subscriber group consumes a durable queue named "<group>.<topic>".
+func NewTransport(config Config) (eo.Transport, error) {
+	if config.URL == "" {
+		return nil, errors.New("cannot create rabbitmq transport without url")
+	}
+	if config.Exchange == "" {
+		config.Exchange = DefaultExchange
+	}
+	if config.Prefetch <= 0 {
+		config.Prefetch = 1
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = time.Second
+	}
+
+	conn, err := amqp.Dial(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to rabbitmq: %w", err)
+	}
+
+	t, err := newTransport(config, amqpConnection{conn})
+	if err != nil {
+		return nil, err
+	}
+	log.Info("successfully connected to rabbitmq")
+	return t, nil
+}
+
+func newTransport(config Config, conn connection) (*transport, error) {
+	channel, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open rabbitmq channel: %w", err)
+	}
+
+	if err := channel.ExchangeDeclare(config.Exchange, amqp.ExchangeTopic, true, false, false, false, nil); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to declare exchange %s: %w", config.Exchange, err)
+	}
+
+	return &transport{
+		config:  config,
+		conn:    conn,
+		channel: channel,
+	}, nil
+}
+
+func (t *transport) Publish(ctx context.Context, msg *eo.Message) error {
+	headers := make(amqp.Table, len(msg.Headers))
+	for key, value := range msg.Headers {
+		headers[key] = value
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	err := t.channel.PublishWithContext(ctx, t.config.Exchange, msg.Topic, false, false, amqp.Publishing{
+		Headers:      headers,
+		ContentType:  msg.Headers[eo.ContentTypeHeader],
+		DeliveryMode: amqp.Persistent,
+		Body:         msg.Payload,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish rabbitmq message to %s: %w", msg.Topic, err)
+	}
+	return nil
+}
+
+func (t *transport) Subscribe(ctx context.Context, topic, group string, handler eo.MessageHandler) error {
+	channel, err := t.conn.Channel()
+	if err != nil {
+		return fmt.Errorf("failed to open rabbitmq channel: %w", err)
+	}
+	defer channel.Close()
+
+	queue := fmt.Sprintf("%s.%s", group, topic)
+	var args amqp.Table
+	if t.config.DeadLetterExchange != "" {
+		args = amqp.Table{"x-dead-letter-exchange": t.config.DeadLetterExchange}
+	}
+	if _, err := channel.QueueDeclare(queue, true, false, false, false, args); err != nil {
+		return fmt.Errorf("failed to declare queue %s: %w", queue, err)
+	}
+	if err := channel.QueueBind(queue, topic, t.config.Exchange, false, nil); err != nil {
+		return fmt.Errorf("failed to bind queue %s: %w", queue, err)
+	}
+	if err := channel.Qos(t.config.Prefetch, 0, false); err != nil {
+		return fmt.Errorf("failed to set prefetch of queue %s: %w", queue, err)
+	}
+
+	deliveries, err := channel.ConsumeWithContext(ctx, queue, "", false, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("failed to consume queue %s: %w", queue, err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case d, ok := <-deliveries:
+			if !ok {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return fmt.Errorf("rabbitmq closed deliveries of queue %s", queue)
+			}
+
+			msg := &eo.Message{
+				ID:      d.MessageId,
+				Topic:   d.RoutingKey,
+				Payload: d.Body,
+				Headers: make(map[string]string, len(d.Headers)),
+			}
+			if msg.ID == "" {
+				msg.ID = strconv.FormatUint(d.DeliveryTag, 10)
+			}
+			for key, value := range d.Headers {
+				if s, ok := value.(string); ok {
+					msg.Headers[key] = s
+				}
+			}
+
+			if err := handler(ctx, msg); err != nil {
+				if err := t.reject(ctx, d, queue, msg.ID, err); err != nil {
+					return fmt.Errorf("failed to nack rabbitmq message %s of queue %s: %w", msg.ID, queue, err)
+				}
+				continue
+			}
+			if err := d.Ack(false); err != nil {
+				return fmt.Errorf("failed to ack rabbitmq message %s of queue %s: %w", msg.ID, queue, err)
+			}
+		}
+	}
+}
+
+// reject dead letters the failed delivery d when the queues have a DeadLetterExchange, and
+// requeues it after RetryBackoff otherwise, so it is not redelivered right away.
+func (t *transport) reject(ctx context.Context, d amqp.Delivery, queue, id string, handlerErr error) error {
+	if t.config.DeadLetterExchange != "" {
+		log.WithError(handlerErr).Warning("failed to handle rabbitmq message %s of queue %s, dead lettering it", id, queue)
+		return d.Nack(false, false)
+	}
+
+	log.WithError(handlerErr).Warning("failed to handle rabbitmq message %s of queue %s, requeueing in %s", id, queue, t.config.RetryBackoff)
+	timer := time.NewTimer(t.config.RetryBackoff)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	return d.Nack(false, true)
+}
+
+func (t *transport) Close() error {
+	return t.conn.Close()
+}
@@ -0,0 +1,197 @@
+package rabbitmq
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	eo "github.com/NusaCrew/atlas-go/event_observer"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+)
+
+type nack struct {
+	tag     uint64
+	requeue bool
+	at      time.Time
+}
+
+// fakeBroker records what the channels of the fake connection declared and published, and
+// feeds the deliveries to their consumers.
+type fakeBroker struct {
+	mu         sync.Mutex
+	queueArgs  map[string]amqp.Table
+	bindings   map[string]string
+	published  []amqp.Publishing
+	deliveries chan amqp.Delivery
+	acks       []uint64
+	nacks      []nack
+	closed     bool
+}
+
+func newFakeBroker() *fakeBroker {
+	return &fakeBroker{
+		queueArgs:  map[string]amqp.Table{},
+		bindings:   map[string]string{},
+		deliveries: make(chan amqp.Delivery),
+	}
+}
+
+func (b *fakeBroker) Channel() (channel, error) { return &fakeChannel{broker: b}, nil }
+
+func (b *fakeBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	return nil
+}
+
+func (b *fakeBroker) Ack(tag uint64, multiple bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.acks = append(b.acks, tag)
+	return nil
+}
+
+func (b *fakeBroker) Nack(tag uint64, multiple, requeue bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nacks = append(b.nacks, nack{tag: tag, requeue: requeue, at: time.Now()})
+	return nil
+}
+
+func (b *fakeBroker) Reject(tag uint64, requeue bool) error {
+	return b.Nack(tag, false, requeue)
+}
+
+func (b *fakeBroker) deliver(d amqp.Delivery) {
+	d.Acknowledger = b
+	b.deliveries <- d
+}
+
+type fakeChannel struct {
+	broker *fakeBroker
+}
+
+func (c *fakeChannel) ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error {
+	return nil
+}
+
+func (c *fakeChannel) QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error) {
+	c.broker.mu.Lock()
+	defer c.broker.mu.Unlock()
+	c.broker.queueArgs[name] = args
+	return amqp.Queue{Name: name}, nil
+}
+
+func (c *fakeChannel) QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error {
+	c.broker.mu.Lock()
+	defer c.broker.mu.Unlock()
+	c.broker.bindings[name] = key
+	return nil
+}
+
+func (c *fakeChannel) Qos(prefetchCount, prefetchSize int, global bool) error {
+	return nil
+}
+
+func (c *fakeChannel) PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	c.broker.mu.Lock()
+	defer c.broker.mu.Unlock()
+	c.broker.published = append(c.broker.published, msg)
+	return nil
+}
+
+func (c *fakeChannel) ConsumeWithContext(ctx context.Context, queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error) {
+	return c.broker.deliveries, nil
+}
+
+func (c *fakeChannel) Close() error {
+	return nil
+}
+
+func TestNewTransport(t *testing.T) {
+	_, err := NewTransport(Config{})
+	assert.Error(t, err)
+}
+
+func TestTransport(t *testing.T) {
+	testCases := []struct {
+		name               string
+		deadLetterExchange string
+		queueArgs          amqp.Table
+		requeue            bool
+	}{
+		{
+			name:    "requeued after backoff",
+			requeue: true,
+		},
+		{
+			name:               "dead lettered",
+			deadLetterExchange: "atlas.dead-letters",
+			queueArgs:          amqp.Table{"x-dead-letter-exchange": "atlas.dead-letters"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			broker := newFakeBroker()
+			transport, err := newTransport(Config{
+				Exchange:           DefaultExchange,
+				Prefetch:           1,
+				RetryBackoff:       20 * time.Millisecond,
+				DeadLetterExchange: tc.deadLetterExchange,
+			}, broker)
+			assert.NoError(t, err)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			err = transport.Publish(ctx, &eo.Message{Topic: "user.created", Payload: []byte("payload"), Headers: map[string]string{eo.ContentTypeHeader: "application/json"}})
+			assert.NoError(t, err)
+			published := broker.published[0]
+			assert.Equal(t, "application/json", published.ContentType)
+			assert.Equal(t, amqp.Persistent, published.DeliveryMode)
+
+			var received []*eo.Message
+			done := make(chan error)
+			go func() {
+				done <- transport.Subscribe(ctx, "user.created", "service.subscriber", func(ctx context.Context, msg *eo.Message) error {
+					received = append(received, msg)
+					if msg.ID == "failing" {
+						return assert.AnError
+					}
+					return nil
+				})
+			}()
+
+			failedAt := time.Now()
+			broker.deliver(amqp.Delivery{MessageId: "failing", DeliveryTag: 1, RoutingKey: "user.created", Body: published.Body, Headers: published.Headers})
+			broker.deliver(amqp.Delivery{DeliveryTag: 2, RoutingKey: "user.created", Body: published.Body, Headers: published.Headers})
+			cancel()
+			assert.NoError(t, <-done)
+
+			assert.Equal(t, tc.queueArgs, broker.queueArgs["service.subscriber.user.created"])
+			assert.Equal(t, "user.created", broker.bindings["service.subscriber.user.created"])
+			if assert.Len(t, broker.nacks, 1) {
+				assert.Equal(t, uint64(1), broker.nacks[0].tag)
+				assert.Equal(t, tc.requeue, broker.nacks[0].requeue)
+				if tc.requeue {
+					assert.GreaterOrEqual(t, broker.nacks[0].at.Sub(failedAt), 20*time.Millisecond, "requeued after the backoff")
+				}
+			}
+			assert.Equal(t, []uint64{2}, broker.acks)
+
+			if assert.Len(t, received, 2) {
+				assert.Equal(t, "2", received[1].ID, "delivery tag without message ID")
+				assert.Equal(t, "payload", string(received[1].Payload))
+				assert.Equal(t, map[string]string{eo.ContentTypeHeader: "application/json"}, received[1].Headers)
+			}
+
+			assert.NoError(t, transport.Close())
+			assert.True(t, broker.closed)
+		})
+	}
+}
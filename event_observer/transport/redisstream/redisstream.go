@@ -0,0 +1,162 @@
+package redisstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	eo "github.com/NusaCrew/atlas-go/event_observer"
+	"github.com/NusaCrew/atlas-go/log"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	payloadField = "payload"
+	headersField = "headers"
+)
+
+type Config struct {
+	Client redis.UniversalClient
+	// Consumer names this process within its groups, defaults to "<hostname>-<uuid>".
+	Consumer string
+	// BatchSize is the number of messages read at once, defaults to 10.
+	BatchSize int64
+	// Block is how long a read waits for messages, defaults to 1s.
+	Block time.Duration
+	// MinIdle is how long a message stays pending before another consumer of the group
+	// claims it for redelivery, defaults to 30s.
+	MinIdle time.Duration
+	// MaxLen approximately caps the length of the streams when set.
+	MaxLen int64
+}
+
+type transport struct {
+	config Config
+}
+
+// NewTransport returns an event_observer.Transport on Redis Streams, with a stream per topic
+// and subscriber groups as consumer groups. Groups start at the beginning of their stream, so
+// messages published before the first subscribe are consumed. Failed messages stay pending and
+// are claimed again once idle for MinIdle.
+func NewTransport(config Config) (eo.Transport, error) {
+	if config.Client == nil {
+		return nil, errors.New("cannot create redis stream transport without client")
+	}
+	if config.Consumer == "" {
+		hostname, _ := os.Hostname()
+		config.Consumer = fmt.Sprintf("%s-%s", hostname, uuid.NewString())
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 10
+	}
+	if config.Block <= 0 {
+		config.Block = time.Second
+	}
+	if config.MinIdle <= 0 {
+		config.MinIdle = 30 * time.Second
+	}
+
+	return &transport{config: config}, nil
+}
+
+func (t *transport) Publish(ctx context.Context, msg *eo.Message) error {
+	headers, err := json.Marshal(msg.Headers)
+	if err != nil {
+		return fmt.Errorf("failed to encode headers of message to stream %s: %w", msg.Topic, err)
+	}
+
+	err = t.config.Client.XAdd(ctx, &redis.XAddArgs{
+		Stream: msg.Topic,
+		MaxLen: t.config.MaxLen,
+		Approx: t.config.MaxLen > 0,
+		Values: map[string]any{
+			payloadField: msg.Payload,
+			headersField: headers,
+		},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("failed to add message to stream %s: %w", msg.Topic, err)
+	}
+	return nil
+}
+
+func (t *transport) Subscribe(ctx context.Context, topic, group string, handler eo.MessageHandler) error {
+	err := t.config.Client.XGroupCreateMkStream(ctx, topic, group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("failed to create group %s of stream %s: %w", group, topic, err)
+	}
+
+	for ctx.Err() == nil {
+		claimed, _, err := t.config.Client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   topic,
+			Group:    group,
+			Consumer: t.config.Consumer,
+			MinIdle:  t.config.MinIdle,
+			Start:    "0-0",
+			Count:    t.config.BatchSize,
+		}).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to claim pending messages of stream %s: %w", topic, err)
+		}
+		t.handle(ctx, topic, group, claimed, handler)
+
+		streams, err := t.config.Client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    group,
+			Consumer: t.config.Consumer,
+			Streams:  []string{topic, ">"},
+			Count:    t.config.BatchSize,
+			Block:    t.config.Block,
+		}).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				continue
+			}
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to read stream %s: %w", topic, err)
+		}
+		for _, stream := range streams {
+			t.handle(ctx, topic, group, stream.Messages, handler)
+		}
+	}
+	return nil
+}
+
+func (t *transport) handle(ctx context.Context, topic, group string, messages []redis.XMessage, handler eo.MessageHandler) {
+	for _, m := range messages {
+		msg := &eo.Message{
+			ID:      m.ID,
+			Topic:   topic,
+			Headers: map[string]string{},
+		}
+		if payload, ok := m.Values[payloadField].(string); ok {
+			msg.Payload = []byte(payload)
+		}
+		if headers, ok := m.Values[headersField].(string); ok {
+			_ = json.Unmarshal([]byte(headers), &msg.Headers)
+		}
+
+		if err := handler(ctx, msg); err != nil {
+			// left pending, to be claimed again once idle
+			continue
+		}
+		if err := t.config.Client.XAck(context.WithoutCancel(ctx), topic, group, m.ID).Err(); err != nil {
+			log.WithError(err).Error("failed to ack message %s of stream %s", m.ID, topic)
+		}
+	}
+}
+
+// Close leaves the client open, it is owned by the caller.
+func (t *transport) Close() error {
+	return nil
+}
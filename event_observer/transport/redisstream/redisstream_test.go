@@ -0,0 +1,206 @@
+package redisstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	eo "github.com/NusaCrew/atlas-go/event_observer"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+type pendingMessage struct {
+	message     redis.XMessage
+	deliveredAt time.Time
+}
+
+type fakeGroup struct {
+	delivered int // count of the messages of the stream read by the group
+	pending   map[string]*pendingMessage
+}
+
+// fakeClient is a single stream consumer groups implementation of the commands used by
+// the transport.
+type fakeClient struct {
+	redis.UniversalClient
+
+	mu      sync.Mutex
+	streams map[string][]redis.XMessage
+	groups  map[string]*fakeGroup
+	starts  []string
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{
+		streams: map[string][]redis.XMessage{},
+		groups:  map[string]*fakeGroup{},
+	}
+}
+
+func (c *fakeClient) XAdd(ctx context.Context, a *redis.XAddArgs) *redis.StringCmd {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	values := map[string]any{}
+	for key, value := range a.Values.(map[string]any) {
+		values[key] = string(value.([]byte)) // redis returns fields as strings
+	}
+	id := fmt.Sprintf("%d-0", len(c.streams[a.Stream])+1)
+	c.streams[a.Stream] = append(c.streams[a.Stream], redis.XMessage{ID: id, Values: values})
+
+	cmd := redis.NewStringCmd(ctx)
+	cmd.SetVal(id)
+	return cmd
+}
+
+func (c *fakeClient) XGroupCreateMkStream(ctx context.Context, stream, group, start string) *redis.StatusCmd {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cmd := redis.NewStatusCmd(ctx)
+	c.starts = append(c.starts, start)
+	if _, ok := c.groups[group]; ok {
+		cmd.SetErr(errors.New("BUSYGROUP Consumer Group name already exists"))
+		return cmd
+	}
+	g := &fakeGroup{pending: map[string]*pendingMessage{}}
+	if start == "$" {
+		g.delivered = len(c.streams[stream])
+	}
+	c.groups[group] = g
+	cmd.SetVal("OK")
+	return cmd
+}
+
+func (c *fakeClient) XAutoClaim(ctx context.Context, a *redis.XAutoClaimArgs) *redis.XAutoClaimCmd {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var claimed []redis.XMessage
+	for _, p := range c.groups[a.Group].pending {
+		if time.Since(p.deliveredAt) >= a.MinIdle {
+			p.deliveredAt = time.Now()
+			claimed = append(claimed, p.message)
+		}
+	}
+	cmd := redis.NewXAutoClaimCmd(ctx)
+	cmd.SetVal(claimed, "0-0")
+	return cmd
+}
+
+func (c *fakeClient) XReadGroup(ctx context.Context, a *redis.XReadGroupArgs) *redis.XStreamSliceCmd {
+	cmd := redis.NewXStreamSliceCmd(ctx)
+	stream := a.Streams[0]
+
+	c.mu.Lock()
+	g := c.groups[a.Group]
+	messages := c.streams[stream][g.delivered:]
+	g.delivered += len(messages)
+	for _, m := range messages {
+		g.pending[m.ID] = &pendingMessage{message: m, deliveredAt: time.Now()}
+	}
+	c.mu.Unlock()
+
+	if len(messages) == 0 {
+		select {
+		case <-time.After(a.Block):
+			cmd.SetErr(redis.Nil)
+		case <-ctx.Done():
+			cmd.SetErr(ctx.Err())
+		}
+		return cmd
+	}
+	cmd.SetVal([]redis.XStream{{Stream: stream, Messages: messages}})
+	return cmd
+}
+
+func (c *fakeClient) XAck(ctx context.Context, stream, group string, ids ...string) *redis.IntCmd {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, id := range ids {
+		delete(c.groups[group].pending, id)
+	}
+	cmd := redis.NewIntCmd(ctx)
+	cmd.SetVal(int64(len(ids)))
+	return cmd
+}
+
+func (c *fakeClient) pendingCount(group string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.groups[group].pending)
+}
+
+func TestNewTransport(t *testing.T) {
+	_, err := NewTransport(Config{})
+	assert.Error(t, err)
+
+	created, err := NewTransport(Config{Client: newFakeClient()})
+	assert.NoError(t, err)
+	config := created.(*transport).config
+	assert.NotEmpty(t, config.Consumer)
+	assert.Equal(t, int64(10), config.BatchSize)
+	assert.Equal(t, time.Second, config.Block)
+	assert.Equal(t, 30*time.Second, config.MinIdle)
+}
+
+func TestTransport(t *testing.T) {
+	client := newFakeClient()
+	transport, err := NewTransport(Config{Client: client, Block: 5 * time.Millisecond, MinIdle: 20 * time.Millisecond})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err = transport.Publish(ctx, &eo.Message{Topic: "user.created", Payload: []byte("before"), Headers: map[string]string{eo.ContentTypeHeader: "application/json"}})
+	assert.NoError(t, err, "published before the group exists")
+
+	var mu sync.Mutex
+	var received []*eo.Message
+	failures := 1
+	done := make(chan error)
+	go func() {
+		done <- transport.Subscribe(ctx, "user.created", "service.subscriber", func(ctx context.Context, msg *eo.Message) error {
+			mu.Lock()
+			defer mu.Unlock()
+			received = append(received, msg)
+			if msg.ID == "2-0" && failures > 0 {
+				failures--
+				return assert.AnError
+			}
+			return nil
+		})
+	}()
+	time.Sleep(10 * time.Millisecond)
+	assert.NoError(t, transport.Publish(ctx, &eo.Message{Topic: "user.created", Payload: []byte("after")}))
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 3 && client.pendingCount("service.subscriber") == 0
+	}, time.Second, 5*time.Millisecond, "failed message is claimed again once idle")
+
+	mu.Lock()
+	assert.Equal(t, "before", string(received[0].Payload))
+	assert.Equal(t, map[string]string{eo.ContentTypeHeader: "application/json"}, received[0].Headers)
+	assert.Equal(t, "after", string(received[1].Payload))
+	assert.Equal(t, "2-0", received[2].ID)
+	mu.Unlock()
+
+	cancel()
+	assert.NoError(t, <-done)
+	err = transport.Subscribe(ctx, "user.created", "service.subscriber", func(ctx context.Context, msg *eo.Message) error { return nil })
+	assert.NoError(t, err, "existing group is joined")
+
+	client.mu.Lock()
+	for _, start := range client.starts {
+		assert.Equal(t, "0", start, "groups start at the beginning of the stream")
+	}
+	client.mu.Unlock()
+}
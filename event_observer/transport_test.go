@@ -0,0 +1,212 @@
+package event_observer
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestJSONCodec(t *testing.T) {
+	payload, err := JSONCodec.Encode(&Event{
+		Topic:    "test-topic",
+		Key:      "some-key",
+		Data:     map[string]any{"id": 1},
+		Metadata: map[string]any{"source": "test"},
+	})
+	assert.NoError(t, err)
+
+	event, err := JSONCodec.Decode(payload)
+	assert.NoError(t, err)
+	assert.Equal(t, "test-topic", event.Topic)
+	assert.Equal(t, "some-key", event.Key)
+	assert.Equal(t, json.RawMessage(`{"id":1}`), event.Data)
+	assert.Equal(t, map[string]any{"source": "test"}, event.Metadata)
+
+	_, err = JSONCodec.Decode([]byte("not json"))
+	assert.Error(t, err)
+}
+
+func TestStoredEvent(t *testing.T) {
+	type unregistered struct {
+		ID int `json:"id"`
+	}
+	RegisterDataType[userCreated]()
+	RegisterDataType[*wrapperspb.StringValue]()
+
+	testCases := []struct {
+		name     string
+		data     any
+		dataType string
+		expected any
+	}{
+		{name: "registered", data: userCreated{ID: 1, Email: "john@example.com"}, dataType: "github.com/NusaCrew/atlas-go/event_observer.userCreated", expected: userCreated{ID: 1, Email: "john@example.com"}},
+		{name: "registered proto", data: wrapperspb.String("john"), dataType: "*google.golang.org/protobuf/types/known/wrapperspb.StringValue", expected: wrapperspb.String("john")},
+		{name: "unregistered", data: unregistered{ID: 1}, dataType: "github.com/NusaCrew/atlas-go/event_observer.unregistered", expected: json.RawMessage(`{"id":1}`)},
+		{name: "unnamed", data: map[string]any{"id": 1}, expected: json.RawMessage(`{"id":1}`)},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			payload, dataType, err := encodeStoredEvent(&Event{Topic: "user.created", Data: tc.data})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.dataType, dataType)
+
+			event, err := decodeStoredEvent(payload, dataType)
+			assert.NoError(t, err)
+			if msg, ok := tc.expected.(proto.Message); ok {
+				assert.True(t, proto.Equal(msg, event.Data.(proto.Message)))
+				return
+			}
+			assert.Equal(t, tc.expected, event.Data)
+		})
+	}
+}
+
+func TestMemoryTransport(t *testing.T) {
+	transport := NewMemoryTransport()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	received := map[string][]string{}
+	subscribe := func(group string, failures int) {
+		go func() {
+			_ = transport.Subscribe(ctx, "test-topic", group, func(ctx context.Context, msg *Message) error {
+				mu.Lock()
+				defer mu.Unlock()
+				received[group] = append(received[group], string(msg.Payload))
+				if failures > 0 {
+					failures--
+					return assert.AnError
+				}
+				return nil
+			})
+		}()
+	}
+	subscribe("first", 0)
+	subscribe("second", 1)
+	time.Sleep(10 * time.Millisecond)
+
+	err := transport.Publish(ctx, &Message{Topic: "test-topic", Payload: []byte("payload")})
+	assert.NoError(t, err)
+	time.Sleep(150 * time.Millisecond)
+
+	mu.Lock()
+	assert.Equal(t, []string{"payload"}, received["first"])
+	assert.Equal(t, []string{"payload", "payload"}, received["second"], "nacked message is redelivered")
+	mu.Unlock()
+
+	assert.NoError(t, transport.Close())
+	assert.ErrorIs(t, transport.Publish(ctx, &Message{Topic: "test-topic"}), ErrTransportClosed)
+}
+
+func TestMemoryTransportCloseStopsSubscribers(t *testing.T) {
+	transport := NewMemoryTransport()
+	done := make(chan error)
+	go func() {
+		done <- transport.Subscribe(context.Background(), "test-topic", "group", func(ctx context.Context, msg *Message) error {
+			return nil
+		})
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	assert.NoError(t, transport.Close())
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, ErrTransportClosed)
+	case <-time.After(time.Second):
+		t.Fatal("Subscribe did not return after Close")
+	}
+
+	err := transport.Subscribe(context.Background(), "test-topic", "group", func(ctx context.Context, msg *Message) error { return nil })
+	assert.ErrorIs(t, err, ErrTransportClosed)
+	assert.NoError(t, transport.Close(), "closing twice")
+}
+
+func TestEventObserverWithTransport(t *testing.T) {
+	RegisterDataType[userCreated]()
+	transport := NewMemoryTransport()
+	publisher := NewEventObserver("publisher-service", WithTransport(transport, nil))
+	consumer := NewEventObserver("consumer-service", WithTransport(transport, JSONCodec))
+
+	var mu sync.Mutex
+	var received []*Event
+	for _, topic := range []string{"test-topic", "user.created"} {
+		consumer.Subscribe(topic, Subscriber{
+			TopicName:      topic,
+			SubscriberName: "consumer",
+			HandlerFunc: func(ctx context.Context, event *Event) error {
+				mu.Lock()
+				defer mu.Unlock()
+				received = append(received, event)
+				return nil
+			},
+		})
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	assert.NoError(t, publisher.Publish(context.Background(), &Event{Topic: "test-topic", Data: "payload"}))
+	time.Sleep(10 * time.Millisecond)
+	assert.NoError(t, publisher.Publish(context.Background(), &Event{Topic: "user.created", Data: userCreated{ID: 1, Email: "john@example.com"}}))
+	time.Sleep(10 * time.Millisecond)
+
+	mu.Lock()
+	if assert.Len(t, received, 2) {
+		assert.Equal(t, json.RawMessage(`"payload"`), received[0].Data)
+		assert.Equal(t, userCreated{ID: 1, Email: "john@example.com"}, received[1].Data, "registered data keeps its type")
+	}
+	mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, consumer.Close(ctx))
+	assert.NoError(t, publisher.Publish(context.Background(), &Event{Topic: "test-topic"}), "shared transport is left open")
+}
+
+func TestEventObserverWithOwnedTransport(t *testing.T) {
+	transport := NewMemoryTransport()
+	eo := NewEventObserver("some-service-name", WithOwnedTransport(transport, nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, eo.Close(ctx))
+	assert.ErrorIs(t, transport.Publish(context.Background(), &Message{Topic: "test-topic"}), ErrTransportClosed)
+}
+
+func TestEventObserverWithTransportDeadLetter(t *testing.T) {
+	transport := NewMemoryTransport()
+	store := NewInMemoryDeadLetterStore()
+	eo := NewEventObserver("some-service-name", WithTransport(transport, nil), WithDeadLetterStore(store))
+
+	var mu sync.Mutex
+	var attempts int
+	eo.Subscribe("test-topic", Subscriber{
+		TopicName:      "test-topic",
+		SubscriberName: "failing",
+		HandlerFunc: func(ctx context.Context, event *Event) error {
+			mu.Lock()
+			defer mu.Unlock()
+			attempts++
+			return assert.AnError
+		},
+	})
+	time.Sleep(10 * time.Millisecond)
+
+	assert.NoError(t, eo.Publish(context.Background(), &Event{Topic: "test-topic"}))
+	time.Sleep(150 * time.Millisecond)
+
+	mu.Lock()
+	assert.Equal(t, 1, attempts, "dead lettered message is acknowledged")
+	mu.Unlock()
+
+	deadLetters, err := store.List(context.Background(), DeadLetterFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, deadLetters, 1)
+}
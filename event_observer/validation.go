@@ -0,0 +1,51 @@
+package event_observer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// JSONSchemaValidator returns a validator for WithValidator checking the JSON representation
+// of data against schema, protojson for proto messages.
+func JSONSchemaValidator[T any](schema string) (func(data T) error, error) {
+	doc, err := jsonschema.UnmarshalJSON(strings.NewReader(schema))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse json schema: %w", err)
+	}
+
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource("schema.json", doc); err != nil {
+		return nil, fmt.Errorf("failed to add json schema: %w", err)
+	}
+	compiled, err := compiler.Compile("schema.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile json schema: %w", err)
+	}
+
+	return func(data T) error {
+		var (
+			raw []byte
+			err error
+		)
+		if msg, ok := any(data).(proto.Message); ok {
+			raw, err = protojson.Marshal(msg)
+		} else {
+			raw, err = json.Marshal(data)
+		}
+		if err != nil {
+			return err
+		}
+
+		instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(raw))
+		if err != nil {
+			return err
+		}
+		return compiled.Validate(instance)
+	}, nil
+}
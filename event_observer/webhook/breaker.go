@@ -0,0 +1,106 @@
+package webhook
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// --------------- ENUMERATIONS ---------------
+
+type CircuitState int
+
+const (
+	CircuitClosed   CircuitState = iota + 1 // deliveries go through
+	CircuitOpen                             // deliveries fail with ErrCircuitOpen
+	CircuitHalfOpen                         // a single delivery probes the endpoint
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "CLOSED"
+	case CircuitOpen:
+		return "OPEN"
+	case CircuitHalfOpen:
+		return "HALF_OPEN"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// --------------- BREAKER ---------------
+
+var ErrCircuitOpen = errors.New("webhook endpoint circuit is open")
+
+// breaker opens after threshold consecutive failures of an endpoint, failing its deliveries
+// without calling it for openFor. Then one delivery probes the endpoint, closing the circuit
+// on success and opening it again on failure.
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	openFor   time.Duration
+	failures  int
+	openedAt  time.Time
+	probing   bool
+}
+
+func (b *breaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.stateAt(now) {
+	case CircuitClosed:
+		return true
+	case CircuitHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return false
+	}
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.probing = false
+}
+
+func (b *breaker) failure(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+	if b.failures >= b.threshold {
+		b.openedAt = now
+	}
+}
+
+// release ends a delivery allowed without reaching the endpoint.
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (b *breaker) state(now time.Time) CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.stateAt(now)
+}
+
+func (b *breaker) stateAt(now time.Time) CircuitState {
+	if b.failures < b.threshold {
+		return CircuitClosed
+	}
+	if now.Sub(b.openedAt) < b.openFor {
+		return CircuitOpen
+	}
+	return CircuitHalfOpen
+}
@@ -0,0 +1,85 @@
+package webhook
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Delivery is an attempt to deliver an event to an endpoint.
+type Delivery struct {
+	ID         string
+	EndpointID string
+	EventID    string
+	Topic      string
+	URL        string
+	Attempt    int
+	StatusCode int    // 0 when no response was received
+	Response   string // start of the response body
+	Error      string // empty when the endpoint acknowledged the event
+	Duration   time.Duration
+	CreatedAt  time.Time
+}
+
+type DeliveryFilter struct {
+	EndpointID string
+	EventID    string
+	Limit      int
+}
+
+func (f DeliveryFilter) matches(d *Delivery) bool {
+	return (f.EndpointID == "" || f.EndpointID == d.EndpointID) &&
+		(f.EventID == "" || f.EventID == d.EventID)
+}
+
+// DeliveryLog records the delivery attempts, for partners and support to inspect.
+type DeliveryLog interface {
+	Record(ctx context.Context, delivery *Delivery) error
+	// List returns the deliveries matching filter, newest first.
+	List(ctx context.Context, filter DeliveryFilter) ([]*Delivery, error)
+}
+
+type inMemoryDeliveryLog struct {
+	mu         sync.RWMutex
+	capacity   int
+	deliveries []*Delivery
+}
+
+// NewInMemoryDeliveryLog returns a DeliveryLog keeping the last capacity deliveries, 1000 by
+// default, meant for tests and debugging.
+func NewInMemoryDeliveryLog(capacity int) DeliveryLog {
+	if capacity <= 0 {
+		capacity = 1000
+	}
+	return &inMemoryDeliveryLog{capacity: capacity}
+}
+
+func (l *inMemoryDeliveryLog) Record(ctx context.Context, delivery *Delivery) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stored := *delivery
+	l.deliveries = append(l.deliveries, &stored)
+	if len(l.deliveries) > l.capacity {
+		l.deliveries = l.deliveries[len(l.deliveries)-l.capacity:]
+	}
+	return nil
+}
+
+func (l *inMemoryDeliveryLog) List(ctx context.Context, filter DeliveryFilter) ([]*Delivery, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var result []*Delivery
+	for i := len(l.deliveries) - 1; i >= 0; i-- {
+		if !filter.matches(l.deliveries[i]) {
+			continue
+		}
+		stored := *l.deliveries[i]
+		result = append(result, &stored)
+		if filter.Limit > 0 && len(result) == filter.Limit {
+			break
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,78 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/NusaCrew/atlas-go/storage/postgres"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+const DefaultDeliveryTable = "webhook_deliveries"
+
+type postgresDeliveryLog struct {
+	postgres.CommonRepository
+	table string
+}
+
+// NewPostgresDeliveryLog returns a DeliveryLog backed by the given table, created by
+// event_observer/webhook/migrations/000001_create_webhook_deliveries.up.sql.
+func NewPostgresDeliveryLog(storage postgres.Storage, table string) DeliveryLog {
+	if table == "" {
+		table = DefaultDeliveryTable
+	}
+	return &postgresDeliveryLog{
+		CommonRepository: postgres.CommonRepository{Storage: storage},
+		table:            table,
+	}
+}
+
+func (l *postgresDeliveryLog) Record(ctx context.Context, delivery *Delivery) error {
+	_, err := l.Builder(nil).
+		Insert(l.table).
+		Columns("id", "endpoint_id", "event_id", "topic", "url", "attempt", "status_code", "response", "error", "duration_ms", "created_at").
+		Values(delivery.ID, delivery.EndpointID, delivery.EventID, delivery.Topic, delivery.URL, delivery.Attempt, delivery.StatusCode, delivery.Response, delivery.Error, delivery.Duration.Milliseconds(), delivery.CreatedAt).
+		ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to record webhook delivery %s: %w", delivery.ID, err)
+	}
+	return nil
+}
+
+func (l *postgresDeliveryLog) List(ctx context.Context, filter DeliveryFilter) ([]*Delivery, error) {
+	builder := l.Builder(nil).
+		Select("id", "endpoint_id", "event_id", "topic", "url", "attempt", "status_code", "response", "error", "duration_ms", "created_at").
+		From(l.table).
+		OrderBy("created_at DESC")
+	if filter.EndpointID != "" {
+		builder = builder.Where(sq.Eq{"endpoint_id": filter.EndpointID})
+	}
+	if filter.EventID != "" {
+		builder = builder.Where(sq.Eq{"event_id": filter.EventID})
+	}
+	if filter.Limit > 0 {
+		builder = builder.Limit(uint64(filter.Limit))
+	}
+
+	rows, err := builder.QueryContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var result []*Delivery
+	for rows.Next() {
+		var (
+			d          Delivery
+			durationMs int64
+		)
+		if err := rows.Scan(&d.ID, &d.EndpointID, &d.EventID, &d.Topic, &d.URL, &d.Attempt, &d.StatusCode, &d.Response, &d.Error, &durationMs, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		d.Duration = time.Duration(durationMs) * time.Millisecond
+		result = append(result, &d)
+	}
+	return result, rows.Err()
+}
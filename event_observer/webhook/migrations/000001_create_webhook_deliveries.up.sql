@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id          TEXT PRIMARY KEY,
+    endpoint_id TEXT        NOT NULL,
+    event_id    TEXT        NOT NULL,
+    topic       TEXT        NOT NULL,
+    url         TEXT        NOT NULL,
+    attempt     INTEGER     NOT NULL,
+    status_code INTEGER     NOT NULL,
+    response    TEXT        NOT NULL,
+    error       TEXT        NOT NULL,
+    duration_ms BIGINT      NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_endpoint_created_at ON webhook_deliveries (endpoint_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_event_id ON webhook_deliveries (event_id);
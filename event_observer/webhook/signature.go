@@ -0,0 +1,108 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	eo "github.com/NusaCrew/atlas-go/event_observer"
+)
+
+const (
+	IDHeader        = "Webhook-Id"
+	TimestampHeader = "Webhook-Timestamp"
+	SignatureHeader = "Webhook-Signature"
+
+	signatureVersion = "v1"
+	// DefaultTolerance is the age of a signed request past which Verify rejects it.
+	DefaultTolerance = 5 * time.Minute
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrExpiredTimestamp = errors.New("webhook timestamp out of tolerance")
+)
+
+// Sign returns the signature of a request sent with the given ID, timestamp, header and body:
+// v1=hex(HMAC-SHA256(secret, "<id>.<unix timestamp>.<headers>.<body>")), <headers> being the
+// Content-Type and ce-* headers as sorted "<lowercase name>:<values>\n" lines, so the event
+// attributes of the binary mode are signed along the data.
+func Sign(secret, id string, timestamp time.Time, header http.Header, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s.%d.%s.", id, timestamp.Unix(), signedHeaders(header))
+	mac.Write(body)
+	return signatureVersion + "=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func signedHeaders(header http.Header) string {
+	var lines []string
+	for name, values := range header {
+		name = strings.ToLower(name)
+		if name == "content-type" || strings.HasPrefix(name, "ce-") {
+			lines = append(lines, name+":"+strings.Join(values, ",")+"\n")
+		}
+	}
+	slices.Sort(lines)
+	return strings.Join(lines, "")
+}
+
+// Verify checks the signature headers of a webhook against header and body, with any of secrets so
+// receivers can rotate them. Requests signed more than tolerance ago, or ahead, are rejected
+// against replays, tolerance defaulting to DefaultTolerance.
+func Verify(header http.Header, body []byte, tolerance time.Duration, secrets ...string) error {
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+
+	unix, err := strconv.ParseInt(header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: missing or malformed %s", ErrInvalidSignature, TimestampHeader)
+	}
+	timestamp := time.Unix(unix, 0)
+	if age := time.Since(timestamp); age > tolerance || age < -tolerance {
+		return ErrExpiredTimestamp
+	}
+
+	id := header.Get(IDHeader)
+	signatures := strings.Fields(header.Get(SignatureHeader))
+	for _, secret := range secrets {
+		expected := Sign(secret, id, timestamp, header, body)
+		for _, signature := range signatures {
+			if hmac.Equal([]byte(signature), []byte(expected)) {
+				return nil
+			}
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// VerifyRequest verifies a webhook request and returns the event it carries, which must have
+// the ID of the IDHeader. The body of r stays readable.
+func VerifyRequest(r *http.Request, tolerance time.Duration, secrets ...string) (*eo.Event, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook body: %w", err)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	if err := Verify(r.Header, body, tolerance, secrets...); err != nil {
+		return nil, err
+	}
+	event, err := eo.DecodeCloudEventHTTP(r.Header, body)
+	if err != nil {
+		return nil, err
+	}
+	if id := r.Header.Get(IDHeader); event.ID != id {
+		return nil, fmt.Errorf("%w: event %s sent as %s", ErrInvalidSignature, event.ID, id)
+	}
+	return event, nil
+}
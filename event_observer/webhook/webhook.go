@@ -0,0 +1,280 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	eo "github.com/NusaCrew/atlas-go/event_observer"
+	"github.com/NusaCrew/atlas-go/log"
+
+	"github.com/google/uuid"
+)
+
+// maxLoggedResponse bounds the response body kept in the delivery log.
+const maxLoggedResponse = 1024
+
+var (
+	ErrEndpointNotFound  = errors.New("webhook endpoint not found")
+	ErrDuplicateEndpoint = errors.New("webhook endpoint already registered")
+)
+
+// DefaultRetryPolicy retries a failing endpoint for about a minute.
+var DefaultRetryPolicy = eo.RetryPolicy{
+	MaxAttempts:    6,
+	InitialBackoff: time.Second,
+	MaxBackoff:     time.Minute,
+	Jitter:         0.2,
+}
+
+// Endpoint is a URL the events of some topics are POSTed to as CloudEvents.
+type Endpoint struct {
+	ID     string // unique, the endpoint subscribes to its topics as webhook.<ID>
+	URL    string
+	Secret string   // signs the requests, see Verify
+	Topics []string // topics or patterns like order.>
+	// Mode of the CloudEvents HTTP binding, defaults to eo.CloudEventStructured.
+	Mode eo.CloudEventMode
+	// Headers added to the requests, e.g. an Authorization expected by the receiver.
+	Headers map[string]string
+	// RetryPolicy defaults to the one of the Dispatcher.
+	RetryPolicy *eo.RetryPolicy
+}
+
+type Config struct {
+	Observer *eo.EventObserver
+	// Client sending the requests, defaults to one with a 10s timeout.
+	Client *http.Client
+	// RetryPolicy of the endpoints, defaults to DefaultRetryPolicy. Events still failing are
+	// dead lettered by the observer when it has a DeadLetterStore.
+	RetryPolicy *eo.RetryPolicy
+	// Pool of every endpoint, so a slow endpoint only holds back its own deliveries. Zero
+	// fields default to eo.DefaultPoolConfig.
+	Pool eo.PoolConfig
+	// FailureThreshold is the number of consecutive failures opening the circuit of an
+	// endpoint, defaults to 5.
+	FailureThreshold int
+	// OpenDuration is how long an open circuit fails deliveries before probing the endpoint,
+	// defaults to 1m.
+	OpenDuration time.Duration
+	// DeliveryLog records every attempt, optional.
+	DeliveryLog DeliveryLog
+}
+
+type endpoint struct {
+	Endpoint
+	breaker *breaker
+}
+
+// Dispatcher delivers the events of an EventObserver to the registered endpoints.
+type Dispatcher struct {
+	config    Config
+	mu        sync.RWMutex
+	endpoints map[string]*endpoint
+}
+
+func NewDispatcher(config Config) (*Dispatcher, error) {
+	if config.Observer == nil {
+		return nil, errors.New("cannot create webhook dispatcher without observer")
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if config.RetryPolicy == nil {
+		config.RetryPolicy = &DefaultRetryPolicy
+	}
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 5
+	}
+	if config.OpenDuration <= 0 {
+		config.OpenDuration = time.Minute
+	}
+
+	return &Dispatcher{
+		config:    config,
+		endpoints: make(map[string]*endpoint),
+	}, nil
+}
+
+func subscriberName(id string) string {
+	return "webhook." + id
+}
+
+func validate(e Endpoint) error {
+	if e.ID == "" || e.Secret == "" || len(e.Topics) == 0 {
+		return fmt.Errorf("cannot register webhook endpoint %q without id, secret or topics", e.ID)
+	}
+	u, err := url.Parse(e.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("cannot register webhook endpoint %s: invalid url %q", e.ID, e.URL)
+	}
+	return nil
+}
+
+// Register subscribes the endpoint to its topics.
+func (d *Dispatcher) Register(e Endpoint) error {
+	if err := validate(e); err != nil {
+		return err
+	}
+	if e.Mode == 0 {
+		e.Mode = eo.CloudEventStructured
+	}
+	e.Topics = slices.Clone(e.Topics)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.endpoints[e.ID]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateEndpoint, e.ID)
+	}
+	registered := &endpoint{
+		Endpoint: e,
+		breaker:  &breaker{threshold: d.config.FailureThreshold, openFor: d.config.OpenDuration},
+	}
+
+	policy := d.config.RetryPolicy
+	if e.RetryPolicy != nil {
+		policy = e.RetryPolicy
+	}
+	pool := d.config.Pool
+	for i, topic := range e.Topics {
+		err := d.config.Observer.Subscribe(topic, eo.Subscriber{
+			TopicName:      topic,
+			SubscriberName: subscriberName(e.ID),
+			HandlerFunc:    d.handler(registered),
+			RetryPolicy:    policy,
+			Pool:           &pool,
+			DeliveryMode:   eo.DeliveryAsync,
+		})
+		if err != nil {
+			for _, subscribed := range e.Topics[:i] {
+				_ = d.config.Observer.Unsubscribe(subscribed, subscriberName(e.ID))
+			}
+			return fmt.Errorf("failed to subscribe webhook endpoint %s to %s: %w", e.ID, topic, err)
+		}
+	}
+
+	d.endpoints[e.ID] = registered
+	log.Info("registered webhook endpoint %s for %v", e.ID, e.Topics)
+	return nil
+}
+
+// Unregister unsubscribes the endpoint from its topics. Events already queued for it are
+// still delivered.
+func (d *Dispatcher) Unregister(id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	registered, ok := d.endpoints[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrEndpointNotFound, id)
+	}
+	var errs []error
+	for _, topic := range registered.Topics {
+		errs = append(errs, d.config.Observer.Unsubscribe(topic, subscriberName(id)))
+	}
+	delete(d.endpoints, id)
+	return errors.Join(errs...)
+}
+
+// State returns the state of the circuit of the endpoint.
+func (d *Dispatcher) State(id string) (CircuitState, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	registered, ok := d.endpoints[id]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrEndpointNotFound, id)
+	}
+	return registered.breaker.state(time.Now()), nil
+}
+
+// Deliveries lists the deliveries recorded by the DeliveryLog.
+func (d *Dispatcher) Deliveries(ctx context.Context, filter DeliveryFilter) ([]*Delivery, error) {
+	if d.config.DeliveryLog == nil {
+		return nil, errors.New("webhook dispatcher has no delivery log")
+	}
+	return d.config.DeliveryLog.List(ctx, filter)
+}
+
+func (d *Dispatcher) handler(e *endpoint) eo.HandlerFunc {
+	return func(ctx context.Context, event *eo.Event) error {
+		delivery := &Delivery{
+			ID:         uuid.NewString(),
+			EndpointID: e.ID,
+			EventID:    event.ID,
+			Topic:      event.Topic,
+			URL:        e.URL,
+			Attempt:    eo.AttemptFromContext(ctx),
+			CreatedAt:  time.Now(),
+		}
+
+		err := d.post(ctx, e, event, delivery)
+		delivery.Duration = time.Since(delivery.CreatedAt)
+		if err != nil {
+			delivery.Error = err.Error()
+		}
+
+		if d.config.DeliveryLog != nil {
+			if logErr := d.config.DeliveryLog.Record(context.WithoutCancel(ctx), delivery); logErr != nil {
+				log.WithError(logErr).Error("failed to record delivery of event %s to webhook endpoint %s", event.ID, e.ID)
+			}
+		}
+		return err
+	}
+}
+
+// post sends event to the endpoint through its circuit breaker, filling the response of
+// delivery.
+func (d *Dispatcher) post(ctx context.Context, e *endpoint, event *eo.Event, delivery *Delivery) error {
+	if !e.breaker.allow(time.Now()) {
+		return fmt.Errorf("failed to deliver event %s to webhook endpoint %s: %w", event.ID, e.ID, ErrCircuitOpen)
+	}
+
+	header, body, err := eo.EncodeCloudEventHTTP(event, e.Mode)
+	if err != nil {
+		// not the endpoint's fault, the circuit stays as it was
+		e.breaker.release()
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.URL, bytes.NewReader(body))
+	if err != nil {
+		e.breaker.release()
+		return fmt.Errorf("failed to create request to webhook endpoint %s: %w", e.ID, err)
+	}
+	req.Header = header
+	for name, value := range e.Headers {
+		req.Header.Set(name, value)
+	}
+	now := time.Now()
+	req.Header.Set(IDHeader, event.ID)
+	req.Header.Set(TimestampHeader, fmt.Sprint(now.Unix()))
+	req.Header.Set(SignatureHeader, Sign(e.Secret, event.ID, now, req.Header, body))
+
+	resp, err := d.config.Client.Do(req)
+	if err != nil {
+		e.breaker.failure(time.Now())
+		return fmt.Errorf("failed to deliver event %s to webhook endpoint %s: %w", event.ID, e.ID, err)
+	}
+	defer resp.Body.Close()
+
+	response, _ := io.ReadAll(io.LimitReader(resp.Body, maxLoggedResponse))
+	_, _ = io.Copy(io.Discard, resp.Body) // lets the client reuse the connection
+	delivery.StatusCode = resp.StatusCode
+	delivery.Response = string(response)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		e.breaker.failure(time.Now())
+		return fmt.Errorf("failed to deliver event %s to webhook endpoint %s: got status %d", event.ID, e.ID, resp.StatusCode)
+	}
+	e.breaker.success()
+	return nil
+}
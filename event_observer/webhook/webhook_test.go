@@ -0,0 +1,283 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	eo "github.com/NusaCrew/atlas-go/event_observer"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testSecret = "some-secret"
+
+func newTestDispatcher(t *testing.T, config Config) (*eo.EventObserver, *Dispatcher, DeliveryLog) {
+	observer := eo.NewEventObserver("some-service-name")
+	config.Observer = observer
+	config.DeliveryLog = NewInMemoryDeliveryLog(0)
+	if config.RetryPolicy == nil {
+		config.RetryPolicy = &eo.NoRetry
+	}
+	dispatcher, err := NewDispatcher(config)
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = observer.Close(ctx)
+	})
+	return observer, dispatcher, config.DeliveryLog
+}
+
+func waitForDeliveries(t *testing.T, deliveryLog DeliveryLog, count int) []*Delivery {
+	var deliveries []*Delivery
+	assert.Eventually(t, func() bool {
+		deliveries, _ = deliveryLog.List(context.Background(), DeliveryFilter{})
+		return len(deliveries) >= count
+	}, time.Second, 5*time.Millisecond)
+	return deliveries
+}
+
+func TestDispatcherDeliver(t *testing.T) {
+	testCases := []struct {
+		name string
+		mode eo.CloudEventMode
+	}{
+		{name: "structured", mode: eo.CloudEventStructured},
+		{name: "binary", mode: eo.CloudEventBinary},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			received := make(chan *eo.Event, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+				event, err := VerifyRequest(r, 0, testSecret)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusUnauthorized)
+					return
+				}
+				received <- event
+				_, _ = w.Write([]byte("ok"))
+			}))
+			defer server.Close()
+
+			observer, dispatcher, deliveryLog := newTestDispatcher(t, Config{})
+			assert.NoError(t, dispatcher.Register(Endpoint{
+				ID:      "partner",
+				URL:     server.URL,
+				Secret:  testSecret,
+				Topics:  []string{"order.>"},
+				Mode:    tc.mode,
+				Headers: map[string]string{"Authorization": "Bearer token"},
+			}))
+
+			event := &eo.Event{Topic: "order.created", Data: map[string]string{"id": "order-1"}}
+			assert.NoError(t, observer.Publish(context.Background(), event))
+			assert.NoError(t, observer.Publish(context.Background(), &eo.Event{Topic: "payment.created"}))
+
+			select {
+			case got := <-received:
+				assert.Equal(t, event.ID, got.ID)
+				assert.Equal(t, "order.created", got.Topic)
+				assert.Equal(t, "some-service-name", got.Source)
+				assert.JSONEq(t, `{"id":"order-1"}`, string(got.Data.(json.RawMessage)))
+			case <-time.After(time.Second):
+				t.Fatal("webhook not received")
+			}
+
+			deliveries := waitForDeliveries(t, deliveryLog, 1)
+			assert.Len(t, deliveries, 1)
+			assert.Equal(t, "partner", deliveries[0].EndpointID)
+			assert.Equal(t, event.ID, deliveries[0].EventID)
+			assert.Equal(t, http.StatusOK, deliveries[0].StatusCode)
+			assert.Equal(t, "ok", deliveries[0].Response)
+			assert.Equal(t, 1, deliveries[0].Attempt)
+			assert.Empty(t, deliveries[0].Error)
+		})
+	}
+}
+
+func TestDispatcherRetry(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	observer, dispatcher, deliveryLog := newTestDispatcher(t, Config{
+		RetryPolicy: &eo.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+	})
+	assert.NoError(t, dispatcher.Register(Endpoint{ID: "partner", URL: server.URL, Secret: testSecret, Topics: []string{"order.created"}}))
+	assert.NoError(t, observer.Publish(context.Background(), &eo.Event{Topic: "order.created"}))
+
+	deliveries := waitForDeliveries(t, deliveryLog, 3)
+	assert.Equal(t, []int{3, 2, 1}, []int{deliveries[0].Attempt, deliveries[1].Attempt, deliveries[2].Attempt})
+	assert.Equal(t, http.StatusNoContent, deliveries[0].StatusCode)
+	assert.Empty(t, deliveries[0].Error)
+	assert.Equal(t, http.StatusServiceUnavailable, deliveries[2].StatusCode)
+	assert.Contains(t, deliveries[2].Error, "got status 503")
+}
+
+func TestDispatcherCircuitBreaker(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		healthy bool
+		calls   int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if !healthy {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	observer, dispatcher, deliveryLog := newTestDispatcher(t, Config{
+		FailureThreshold: 2,
+		OpenDuration:     50 * time.Millisecond,
+		Pool:             eo.PoolConfig{Workers: 1},
+	})
+	assert.NoError(t, dispatcher.Register(Endpoint{ID: "partner", URL: server.URL, Secret: testSecret, Topics: []string{"order.created"}}))
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, observer.Publish(context.Background(), &eo.Event{Topic: "order.created"}))
+	}
+	deliveries := waitForDeliveries(t, deliveryLog, 3)
+	assert.Contains(t, deliveries[0].Error, ErrCircuitOpen.Error())
+	mu.Lock()
+	assert.Equal(t, 2, calls, "the open circuit does not call the endpoint")
+	healthy = true
+	mu.Unlock()
+
+	state, err := dispatcher.State("partner")
+	assert.NoError(t, err)
+	assert.Equal(t, CircuitOpen, state)
+
+	time.Sleep(60 * time.Millisecond)
+	state, err = dispatcher.State("partner")
+	assert.NoError(t, err)
+	assert.Equal(t, CircuitHalfOpen, state)
+
+	assert.NoError(t, observer.Publish(context.Background(), &eo.Event{Topic: "order.created"}))
+	deliveries = waitForDeliveries(t, deliveryLog, 4)
+	assert.Empty(t, deliveries[0].Error)
+	state, err = dispatcher.State("partner")
+	assert.NoError(t, err)
+	assert.Equal(t, CircuitClosed, state)
+}
+
+func TestDispatcherRegister(t *testing.T) {
+	observer, dispatcher, _ := newTestDispatcher(t, Config{})
+	endpoint := Endpoint{ID: "partner", URL: "https://partner.example.com/webhooks", Secret: testSecret, Topics: []string{"order.created", "order.paid"}}
+
+	assert.Error(t, dispatcher.Register(Endpoint{ID: "partner", URL: "https://partner.example.com", Topics: []string{"order.created"}}))
+	assert.Error(t, dispatcher.Register(Endpoint{ID: "partner", URL: "partner.example.com", Secret: testSecret, Topics: []string{"order.created"}}))
+	assert.NoError(t, dispatcher.Register(endpoint))
+	assert.ErrorIs(t, dispatcher.Register(endpoint), ErrDuplicateEndpoint)
+	assert.Len(t, observer.Topics(), 2)
+
+	assert.NoError(t, dispatcher.Unregister("partner"))
+	assert.ErrorIs(t, dispatcher.Unregister("partner"), ErrEndpointNotFound)
+	assert.Empty(t, observer.Topics())
+	_, err := dispatcher.State("partner")
+	assert.ErrorIs(t, err, ErrEndpointNotFound)
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"id":"order-1"}`)
+	signed := func(secret string, at time.Time) http.Header {
+		header := http.Header{}
+		header.Set("Content-Type", "application/json")
+		header.Set("ce-type", "order.created")
+		header.Set(IDHeader, "event-1")
+		header.Set(TimestampHeader, fmt.Sprint(at.Unix()))
+		header.Set(SignatureHeader, Sign(secret, "event-1", at, header, body))
+		return header
+	}
+	tampered := func(name, value string) http.Header {
+		header := signed(testSecret, time.Now())
+		header.Set(name, value)
+		return header
+	}
+
+	testCases := []struct {
+		name    string
+		header  http.Header
+		body    []byte
+		secrets []string
+		err     error
+	}{
+		{name: "valid", header: signed(testSecret, time.Now()), body: body, secrets: []string{testSecret}},
+		{name: "rotated secret", header: signed(testSecret, time.Now()), body: body, secrets: []string{"new-secret", testSecret}},
+		{name: "unsigned header", header: tampered("Authorization", "Bearer token"), body: body, secrets: []string{testSecret}},
+		{name: "wrong secret", header: signed("other-secret", time.Now()), body: body, secrets: []string{testSecret}, err: ErrInvalidSignature},
+		{name: "tampered body", header: signed(testSecret, time.Now()), body: []byte(`{"id":"order-2"}`), secrets: []string{testSecret}, err: ErrInvalidSignature},
+		{name: "tampered ce header", header: tampered("ce-type", "order.cancelled"), body: body, secrets: []string{testSecret}, err: ErrInvalidSignature},
+		{name: "added ce header", header: tampered("ce-subject", "order-2"), body: body, secrets: []string{testSecret}, err: ErrInvalidSignature},
+		{name: "tampered content type", header: tampered("Content-Type", "text/plain"), body: body, secrets: []string{testSecret}, err: ErrInvalidSignature},
+		{name: "expired", header: signed(testSecret, time.Now().Add(-time.Hour)), body: body, secrets: []string{testSecret}, err: ErrExpiredTimestamp},
+		{name: "missing timestamp", header: http.Header{}, body: body, secrets: []string{testSecret}, err: ErrInvalidSignature},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			err := Verify(tc.header, tc.body, time.Minute, tc.secrets...)
+			if tc.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestVerifyRequest(t *testing.T) {
+	testCases := []struct {
+		name string
+		id   string
+		err  error
+	}{
+		{name: "matching id", id: "event-1"},
+		{name: "other id", id: "event-2", err: ErrInvalidSignature},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			header, body, err := eo.EncodeCloudEventHTTP(&eo.Event{ID: "event-1", Topic: "order.created", Source: "some-service-name"}, eo.CloudEventBinary)
+			assert.NoError(t, err)
+
+			now := time.Now()
+			r := httptest.NewRequest(http.MethodPost, "/webhooks", bytes.NewReader(body))
+			r.Header = header
+			r.Header.Set(IDHeader, tc.id)
+			r.Header.Set(TimestampHeader, fmt.Sprint(now.Unix()))
+			r.Header.Set(SignatureHeader, Sign(testSecret, tc.id, now, r.Header, body))
+
+			event, err := VerifyRequest(r, 0, testSecret)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "event-1", event.ID)
+		})
+	}
+}
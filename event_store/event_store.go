@@ -0,0 +1,225 @@
+package event_store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// expected versions of Append besides the version of an existing stream
+const (
+	AnyVersion int64 = -1 // append whatever the version of the stream
+	NoStream   int64 = 0  // the stream must not exist yet
+)
+
+var (
+	ErrConcurrencyConflict = errors.New("stream version does not match the expected version")
+	ErrDuplicateEvent      = errors.New("event with the same id was already appended")
+	ErrSnapshotNotFound    = errors.New("snapshot not found")
+)
+
+// Event is an immutable fact appended to a stream, usually the history of an aggregate.
+type Event struct {
+	ID         string
+	StreamID   string
+	Version    int64 // position within the stream, from 1
+	Position   int64 // position within all streams, from 1
+	Type       string
+	Data       json.RawMessage
+	Metadata   map[string]any
+	RecordedAt time.Time
+}
+
+// NewEvent returns an event of eventType with data marshalled to JSON, to be appended.
+func NewEvent(eventType string, data any) (*Event, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal data of event %s: %w", eventType, err)
+	}
+	return &Event{
+		ID:   uuid.NewString(),
+		Type: eventType,
+		Data: raw,
+	}, nil
+}
+
+// Decode unmarshals the data of the event into v.
+func (e *Event) Decode(v any) error {
+	if err := json.Unmarshal(e.Data, v); err != nil {
+		return fmt.Errorf("failed to unmarshal data of event %s: %w", e.Type, err)
+	}
+	return nil
+}
+
+// Snapshot is the state of a stream at Version, so it is rebuilt from the events after it.
+type Snapshot struct {
+	StreamID  string
+	Version   int64
+	Data      json.RawMessage
+	CreatedAt time.Time
+}
+
+// Store keeps append-only streams of events.
+type Store interface {
+	// Append appends events to the stream when its version is expectedVersion, or returns
+	// ErrConcurrencyConflict, and returns the new version. The stream, version, position and
+	// record time of events are set. Events whose ID was already appended are rejected with
+	// ErrDuplicateEvent, which retrying the append does not fix.
+	Append(ctx context.Context, streamID string, expectedVersion int64, events ...*Event) (int64, error)
+	// ReadStream returns the events of the stream from fromVersion.
+	ReadStream(ctx context.Context, streamID string, fromVersion int64) ([]*Event, error)
+	// ReadAll returns up to limit events of all streams after position, in order.
+	ReadAll(ctx context.Context, position int64, limit int) ([]*Event, error)
+
+	SaveSnapshot(ctx context.Context, snapshot *Snapshot) error
+	LoadSnapshot(ctx context.Context, streamID string) (*Snapshot, error)
+
+	// Checkpoint returns the position a projection reached, 0 when it did not start.
+	Checkpoint(ctx context.Context, name string) (int64, error)
+	SaveCheckpoint(ctx context.Context, name string, position int64) error
+}
+
+// Load returns the latest snapshot of the stream, nil when there is none, and the events
+// after it.
+func Load(ctx context.Context, store Store, streamID string) (*Snapshot, []*Event, error) {
+	snapshot, err := store.LoadSnapshot(ctx, streamID)
+	if err != nil && !errors.Is(err, ErrSnapshotNotFound) {
+		return nil, nil, err
+	}
+
+	fromVersion := int64(1)
+	if snapshot != nil {
+		fromVersion = snapshot.Version + 1
+	}
+	events, err := store.ReadStream(ctx, streamID, fromVersion)
+	if err != nil {
+		return nil, nil, err
+	}
+	return snapshot, events, nil
+}
+
+type inMemoryStore struct {
+	mu          sync.RWMutex
+	events      []*Event
+	ids         map[string]struct{}
+	streams     map[string][]*Event
+	snapshots   map[string]*Snapshot
+	checkpoints map[string]int64
+}
+
+// NewInMemoryStore returns a Store losing its content on restart, meant for tests.
+func NewInMemoryStore() Store {
+	return &inMemoryStore{
+		ids:         make(map[string]struct{}),
+		streams:     make(map[string][]*Event),
+		snapshots:   make(map[string]*Snapshot),
+		checkpoints: make(map[string]int64),
+	}
+}
+
+func (s *inMemoryStore) Append(ctx context.Context, streamID string, expectedVersion int64, events ...*Event) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	version := int64(len(s.streams[streamID]))
+	if expectedVersion != AnyVersion && expectedVersion != version {
+		return version, fmt.Errorf("failed to append to stream %s at version %d, expected %d: %w", streamID, version, expectedVersion, ErrConcurrencyConflict)
+	}
+
+	appended := make(map[string]struct{}, len(events))
+	for _, event := range events {
+		if event.ID == "" {
+			event.ID = uuid.NewString()
+		}
+		_, stored := s.ids[event.ID]
+		if _, ok := appended[event.ID]; ok || stored {
+			return version, fmt.Errorf("failed to append to stream %s: %w", streamID, ErrDuplicateEvent)
+		}
+		appended[event.ID] = struct{}{}
+	}
+
+	now := time.Now()
+	for _, event := range events {
+		version++
+		s.ids[event.ID] = struct{}{}
+		event.StreamID = streamID
+		event.Version = version
+		event.Position = int64(len(s.events)) + 1
+		event.RecordedAt = now
+
+		stored := *event
+		stored.Metadata = maps.Clone(event.Metadata)
+		s.events = append(s.events, &stored)
+		s.streams[streamID] = append(s.streams[streamID], &stored)
+	}
+	return version, nil
+}
+
+func (s *inMemoryStore) ReadStream(ctx context.Context, streamID string, fromVersion int64) ([]*Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var events []*Event
+	for _, event := range s.streams[streamID] {
+		if event.Version >= fromVersion {
+			copied := *event
+			events = append(events, &copied)
+		}
+	}
+	return events, nil
+}
+
+func (s *inMemoryStore) ReadAll(ctx context.Context, position int64, limit int) ([]*Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var events []*Event
+	for i := position; i < int64(len(s.events)) && len(events) < limit; i++ {
+		copied := *s.events[i]
+		events = append(events, &copied)
+	}
+	return events, nil
+}
+
+func (s *inMemoryStore) SaveSnapshot(ctx context.Context, snapshot *Snapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := *snapshot
+	if stored.CreatedAt.IsZero() {
+		stored.CreatedAt = time.Now()
+	}
+	s.snapshots[snapshot.StreamID] = &stored
+	return nil
+}
+
+func (s *inMemoryStore) LoadSnapshot(ctx context.Context, streamID string) (*Snapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshot, ok := s.snapshots[streamID]
+	if !ok {
+		return nil, ErrSnapshotNotFound
+	}
+	copied := *snapshot
+	return &copied, nil
+}
+
+func (s *inMemoryStore) Checkpoint(ctx context.Context, name string) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.checkpoints[name], nil
+}
+
+func (s *inMemoryStore) SaveCheckpoint(ctx context.Context, name string, position int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkpoints[name] = position
+	return nil
+}
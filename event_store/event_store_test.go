@@ -0,0 +1,90 @@
+package event_store
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type accountOpened struct {
+	Owner string `json:"owner"`
+}
+
+func TestAppend(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+
+	opened, err := NewEvent("account.opened", accountOpened{Owner: "alice"})
+	assert.NoError(t, err)
+	deposited, err := NewEvent("account.deposited", map[string]int{"amount": 10})
+	assert.NoError(t, err)
+
+	version, err := store.Append(ctx, "account-1", NoStream, opened, deposited)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), version)
+	assert.Equal(t, "account-1", deposited.StreamID)
+	assert.Equal(t, int64(2), deposited.Version)
+	assert.Equal(t, int64(2), deposited.Position)
+
+	_, err = store.Append(ctx, "account-1", NoStream, &Event{Type: "account.opened"})
+	assert.ErrorIs(t, err, ErrConcurrencyConflict)
+	_, err = store.Append(ctx, "account-1", 1, &Event{Type: "account.withdrawn"})
+	assert.ErrorIs(t, err, ErrConcurrencyConflict, "stale version is rejected")
+
+	_, err = store.Append(ctx, "account-1", AnyVersion, &Event{ID: opened.ID, Type: "account.opened"})
+	assert.ErrorIs(t, err, ErrDuplicateEvent)
+	_, err = store.Append(ctx, "account-3", NoStream, &Event{ID: "same-id"}, &Event{ID: "same-id"})
+	assert.ErrorIs(t, err, ErrDuplicateEvent, "duplicates within the events are rejected")
+
+	version, err = store.Append(ctx, "account-1", AnyVersion, &Event{Type: "account.withdrawn"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), version)
+	version, err = store.Append(ctx, "account-2", NoStream, &Event{Type: "account.opened"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), version)
+
+	events, err := store.ReadStream(ctx, "account-1", 2)
+	assert.NoError(t, err)
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, "account.deposited", events[0].Type)
+		assert.Equal(t, json.RawMessage(`{"amount":10}`), events[0].Data)
+	}
+
+	all, err := store.ReadAll(ctx, 2, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, all, 2) {
+		assert.Equal(t, int64(3), all[0].Position)
+		assert.Equal(t, "account-2", all[1].StreamID)
+	}
+
+	var data accountOpened
+	assert.NoError(t, opened.Decode(&data))
+	assert.Equal(t, "alice", data.Owner)
+}
+
+func TestLoad(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+	for i := 0; i < 5; i++ {
+		_, err := store.Append(ctx, "account-1", AnyVersion, &Event{Type: "account.deposited"})
+		assert.NoError(t, err)
+	}
+
+	snapshot, events, err := Load(ctx, store, "account-1")
+	assert.NoError(t, err)
+	assert.Nil(t, snapshot)
+	assert.Len(t, events, 5)
+
+	assert.NoError(t, store.SaveSnapshot(ctx, &Snapshot{StreamID: "account-1", Version: 3, Data: json.RawMessage(`{"balance":30}`)}))
+	snapshot, events, err = Load(ctx, store, "account-1")
+	assert.NoError(t, err)
+	if assert.NotNil(t, snapshot) {
+		assert.Equal(t, int64(3), snapshot.Version)
+		assert.False(t, snapshot.CreatedAt.IsZero())
+	}
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, int64(4), events[0].Version)
+	}
+}
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS event_store_checkpoints;
+DROP TABLE IF EXISTS event_store_snapshots;
+DROP TABLE IF EXISTS event_store_events;
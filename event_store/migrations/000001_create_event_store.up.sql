@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS event_store_events (
+    position    BIGSERIAL PRIMARY KEY,
+    id          TEXT        NOT NULL UNIQUE,
+    stream_id   TEXT        NOT NULL,
+    version     BIGINT      NOT NULL,
+    type        TEXT        NOT NULL,
+    data        JSONB       NOT NULL,
+    metadata    JSONB,
+    recorded_at TIMESTAMPTZ NOT NULL,
+    UNIQUE (stream_id, version)
+);
+
+CREATE TABLE IF NOT EXISTS event_store_snapshots (
+    stream_id  TEXT PRIMARY KEY,
+    version    BIGINT      NOT NULL,
+    data       JSONB       NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS event_store_checkpoints (
+    name       TEXT PRIMARY KEY,
+    position   BIGINT      NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,40 @@
+package event_store
+
+import (
+	"context"
+
+	eo "github.com/NusaCrew/atlas-go/event_observer"
+)
+
+// Publisher publishes the events of the store, like an EventObserver with or without transport.
+type Publisher interface {
+	Publish(ctx context.Context, event *eo.Event) error
+}
+
+// ObserverProjection returns a projection publishing the new events of the store to publisher,
+// on the topic returned by topic, the event type when nil. Published events keep the ID of
+// the stored event, for IdempotencyMiddleware, and are keyed by stream for DeliveryOrdered.
+// Rebuilding it would publish the whole history again, so Rebuild refuses it: rename it to
+// republish the events on purpose.
+func ObserverProjection(name string, publisher Publisher, topic func(event *Event) string) Projection {
+	if topic == nil {
+		topic = func(event *Event) string {
+			return event.Type
+		}
+	}
+
+	return Projection{
+		Name:      name,
+		NoRebuild: true,
+		Apply: func(ctx context.Context, event *Event) error {
+			return publisher.Publish(ctx, &eo.Event{
+				ID:        event.ID,
+				Topic:     topic(event),
+				Data:      event.Data,
+				Metadata:  event.Metadata,
+				Key:       event.StreamID,
+				Timestamp: event.RecordedAt,
+			})
+		},
+	}
+}
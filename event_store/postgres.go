@@ -0,0 +1,276 @@
+package event_store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	"github.com/NusaCrew/atlas-go/storage/postgres"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	DefaultEventTable      = "event_store_events"
+	DefaultSnapshotTable   = "event_store_snapshots"
+	DefaultCheckpointTable = "event_store_checkpoints"
+
+	uniqueViolation = "23505"
+)
+
+// PostgresConfig names the tables created by
+// event_store/migrations/000001_create_event_store.up.sql, defaulting to the Default*Table
+// constants. Renamed tables keep the default names of their unique constraints,
+// <table>_id_key and <table>_stream_id_version_key, which tell the errors of Append apart.
+type PostgresConfig struct {
+	EventTable      string
+	SnapshotTable   string
+	CheckpointTable string
+}
+
+type PostgresStore struct {
+	postgres.CommonRepository
+	config  PostgresConfig
+	lockKey int64
+}
+
+// NewPostgresStore returns a Store on storage. Appends are serialized with an advisory lock,
+// so events become visible to ReadAll in the order of their position and projections do not
+// skip events of concurrent transactions.
+func NewPostgresStore(storage postgres.Storage, config PostgresConfig) *PostgresStore {
+	if config.EventTable == "" {
+		config.EventTable = DefaultEventTable
+	}
+	if config.SnapshotTable == "" {
+		config.SnapshotTable = DefaultSnapshotTable
+	}
+	if config.CheckpointTable == "" {
+		config.CheckpointTable = DefaultCheckpointTable
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(config.EventTable))
+	return &PostgresStore{
+		CommonRepository: postgres.CommonRepository{Storage: storage},
+		config:           config,
+		lockKey:          int64(h.Sum64()),
+	}
+}
+
+func (s *PostgresStore) Append(ctx context.Context, streamID string, expectedVersion int64, events ...*Event) (int64, error) {
+	var version int64
+	err := s.RunInSQLTransaction(ctx, sql.LevelReadCommitted, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", s.lockKey); err != nil {
+			return fmt.Errorf("failed to lock event store: %w", err)
+		}
+
+		err := s.Builder(tx).
+			Select("COALESCE(MAX(version), 0)").
+			From(s.config.EventTable).
+			Where(sq.Eq{"stream_id": streamID}).
+			QueryRowContext(ctx).
+			Scan(&version)
+		if err != nil {
+			return fmt.Errorf("failed to get version of stream %s: %w", streamID, err)
+		}
+		if expectedVersion != AnyVersion && expectedVersion != version {
+			return fmt.Errorf("failed to append to stream %s at version %d, expected %d: %w", streamID, version, expectedVersion, ErrConcurrencyConflict)
+		}
+		if len(events) == 0 {
+			return nil
+		}
+
+		now := time.Now()
+		builder, err := s.appendQuery(tx, streamID, version, events, now)
+		if err != nil {
+			return err
+		}
+		rows, err := builder.QueryContext(ctx)
+		if err != nil {
+			return s.appendError(streamID, err)
+		}
+		defer rows.Close()
+
+		for _, event := range events {
+			if !rows.Next() {
+				return fmt.Errorf("failed to read positions of stream %s: %w", streamID, rows.Err())
+			}
+			if err := rows.Scan(&event.Position); err != nil {
+				return err
+			}
+			version++
+			event.StreamID = streamID
+			event.Version = version
+			event.RecordedAt = now
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+func (s *PostgresStore) appendQuery(tx *sql.Tx, streamID string, version int64, events []*Event, now time.Time) (sq.InsertBuilder, error) {
+	builder := s.Builder(tx).
+		Insert(s.config.EventTable).
+		Columns("id", "stream_id", "version", "type", "data", "metadata", "recorded_at").
+		Suffix("RETURNING position")
+	for i, event := range events {
+		if event.ID == "" {
+			event.ID = uuid.NewString()
+		}
+		metadata, err := json.Marshal(event.Metadata)
+		if err != nil {
+			return builder, fmt.Errorf("failed to marshal metadata of event %s: %w", event.Type, err)
+		}
+		builder = builder.Values(event.ID, streamID, version+int64(i)+1, event.Type, []byte(event.Data), metadata, now)
+	}
+	return builder, nil
+}
+
+// appendError tells the unique violations of the event IDs, ErrDuplicateEvent, from the ones
+// of the stream versions, ErrConcurrencyConflict, by the default names of their constraints.
+func (s *PostgresStore) appendError(streamID string, err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		table := s.config.EventTable[strings.LastIndex(s.config.EventTable, ".")+1:]
+		switch pqErr.Constraint {
+		case table + "_id_key":
+			return fmt.Errorf("failed to append to stream %s: %w", streamID, ErrDuplicateEvent)
+		case table + "_stream_id_version_key":
+			return fmt.Errorf("failed to append to stream %s: %w", streamID, ErrConcurrencyConflict)
+		}
+	}
+	return fmt.Errorf("failed to append to stream %s: %w", streamID, err)
+}
+
+func (s *PostgresStore) selectEvents() sq.SelectBuilder {
+	return s.Builder(nil).
+		Select("position", "id", "stream_id", "version", "type", "data", "metadata", "recorded_at").
+		From(s.config.EventTable)
+}
+
+func (s *PostgresStore) readStreamQuery(streamID string, fromVersion int64) sq.SelectBuilder {
+	return s.selectEvents().
+		Where(sq.Eq{"stream_id": streamID}).
+		Where(sq.GtOrEq{"version": fromVersion}).
+		OrderBy("version")
+}
+
+func (s *PostgresStore) ReadStream(ctx context.Context, streamID string, fromVersion int64) ([]*Event, error) {
+	return s.queryEvents(ctx, s.readStreamQuery(streamID, fromVersion))
+}
+
+func (s *PostgresStore) readAllQuery(position int64, limit int) sq.SelectBuilder {
+	return s.selectEvents().
+		Where(sq.Gt{"position": position}).
+		OrderBy("position").
+		Limit(uint64(limit))
+}
+
+func (s *PostgresStore) ReadAll(ctx context.Context, position int64, limit int) ([]*Event, error) {
+	return s.queryEvents(ctx, s.readAllQuery(position, limit))
+}
+
+func (s *PostgresStore) queryEvents(ctx context.Context, builder sq.SelectBuilder) ([]*Event, error) {
+	rows, err := builder.QueryContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*Event
+	for rows.Next() {
+		var (
+			event    Event
+			data     []byte
+			metadata []byte
+		)
+		if err := rows.Scan(&event.Position, &event.ID, &event.StreamID, &event.Version, &event.Type, &data, &metadata, &event.RecordedAt); err != nil {
+			return nil, err
+		}
+		event.Data = data
+		if err := json.Unmarshal(metadata, &event.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal metadata of event %s: %w", event.ID, err)
+		}
+		events = append(events, &event)
+	}
+	return events, rows.Err()
+}
+
+func (s *PostgresStore) SaveSnapshot(ctx context.Context, snapshot *Snapshot) error {
+	createdAt := snapshot.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	_, err := s.Builder(nil).
+		Insert(s.config.SnapshotTable).
+		Columns("stream_id", "version", "data", "created_at").
+		Values(snapshot.StreamID, snapshot.Version, []byte(snapshot.Data), createdAt).
+		Suffix("ON CONFLICT (stream_id) DO UPDATE SET version = EXCLUDED.version, data = EXCLUDED.data, created_at = EXCLUDED.created_at").
+		ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to save snapshot of stream %s: %w", snapshot.StreamID, err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) LoadSnapshot(ctx context.Context, streamID string) (*Snapshot, error) {
+	var (
+		snapshot Snapshot
+		data     []byte
+	)
+	err := s.Builder(nil).
+		Select("stream_id", "version", "data", "created_at").
+		From(s.config.SnapshotTable).
+		Where(sq.Eq{"stream_id": streamID}).
+		QueryRowContext(ctx).
+		Scan(&snapshot.StreamID, &snapshot.Version, &data, &snapshot.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSnapshotNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load snapshot of stream %s: %w", streamID, err)
+	}
+	snapshot.Data = data
+	return &snapshot, nil
+}
+
+func (s *PostgresStore) Checkpoint(ctx context.Context, name string) (int64, error) {
+	var position int64
+	err := s.Builder(nil).
+		Select("position").
+		From(s.config.CheckpointTable).
+		Where(sq.Eq{"name": name}).
+		QueryRowContext(ctx).
+		Scan(&position)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get checkpoint of projection %s: %w", name, err)
+	}
+	return position, nil
+}
+
+func (s *PostgresStore) SaveCheckpoint(ctx context.Context, name string, position int64) error {
+	_, err := s.Builder(nil).
+		Insert(s.config.CheckpointTable).
+		Columns("name", "position", "updated_at").
+		Values(name, position, time.Now()).
+		Suffix("ON CONFLICT (name) DO UPDATE SET position = EXCLUDED.position, updated_at = EXCLUDED.updated_at").
+		ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint of projection %s: %w", name, err)
+	}
+	return nil
+}
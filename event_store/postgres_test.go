@@ -0,0 +1,105 @@
+package event_store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeStorage struct{}
+
+func (fakeStorage) DB() *sql.DB                    { return nil }
+func (fakeStorage) Ping(ctx context.Context) error { return nil }
+func (fakeStorage) Close() error                   { return nil }
+
+func TestPostgresStoreAppendQuery(t *testing.T) {
+	store := NewPostgresStore(fakeStorage{}, PostgresConfig{})
+	now := time.Now()
+
+	events := []*Event{
+		{ID: "event-1", Type: "account.opened", Data: json.RawMessage(`{"owner":"alice"}`)},
+		{Type: "account.deposited", Data: json.RawMessage(`{}`), Metadata: map[string]any{"user": "alice"}},
+	}
+	builder, err := store.appendQuery(nil, "account-1", 3, events, now)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, events[1].ID, "missing IDs are generated")
+
+	query, args, err := builder.ToSql()
+	assert.NoError(t, err)
+	assert.Equal(t, "INSERT INTO event_store_events (id,stream_id,version,type,data,metadata,recorded_at) VALUES ($1,$2,$3,$4,$5,$6,$7),($8,$9,$10,$11,$12,$13,$14) RETURNING position", query)
+	assert.Equal(t, []any{
+		"event-1", "account-1", int64(4), "account.opened", []byte(`{"owner":"alice"}`), []byte("null"), now,
+		events[1].ID, "account-1", int64(5), "account.deposited", []byte(`{}`), []byte(`{"user":"alice"}`), now,
+	}, args)
+}
+
+func TestPostgresStoreAppendError(t *testing.T) {
+	testCases := []struct {
+		name   string
+		table  string
+		err    error
+		target error
+	}{
+		{
+			name:   "duplicate event",
+			err:    &pq.Error{Code: uniqueViolation, Constraint: "event_store_events_id_key"},
+			target: ErrDuplicateEvent,
+		},
+		{
+			name:   "concurrent append",
+			err:    &pq.Error{Code: uniqueViolation, Constraint: "event_store_events_stream_id_version_key"},
+			target: ErrConcurrencyConflict,
+		},
+		{
+			name:   "renamed table with schema",
+			table:  "billing.events",
+			err:    &pq.Error{Code: uniqueViolation, Constraint: "events_id_key"},
+			target: ErrDuplicateEvent,
+		},
+		{
+			name:   "other constraint",
+			err:    &pq.Error{Code: uniqueViolation, Constraint: "event_store_events_custom_key"},
+			target: &pq.Error{},
+		},
+		{
+			name:   "other error",
+			err:    assert.AnError,
+			target: assert.AnError,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			store := NewPostgresStore(fakeStorage{}, PostgresConfig{EventTable: tc.table})
+
+			err := store.appendError("account-1", tc.err)
+			if pqErr, ok := tc.target.(*pq.Error); ok {
+				assert.ErrorAs(t, err, &pqErr)
+				assert.NotErrorIs(t, err, ErrDuplicateEvent)
+				assert.NotErrorIs(t, err, ErrConcurrencyConflict)
+				return
+			}
+			assert.ErrorIs(t, err, tc.target)
+		})
+	}
+}
+
+func TestPostgresStoreReadQueries(t *testing.T) {
+	store := NewPostgresStore(fakeStorage{}, PostgresConfig{EventTable: "events"})
+
+	query, args, err := store.readStreamQuery("account-1", 2).ToSql()
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT position, id, stream_id, version, type, data, metadata, recorded_at FROM events WHERE stream_id = $1 AND version >= $2 ORDER BY version", query)
+	assert.Equal(t, []any{"account-1", int64(2)}, args)
+
+	query, args, err = store.readAllQuery(10, 100).ToSql()
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT position, id, stream_id, version, type, data, metadata, recorded_at FROM events WHERE position > $1 ORDER BY position LIMIT 100", query)
+	assert.Equal(t, []any{int64(10)}, args)
+}
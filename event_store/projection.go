@@ -0,0 +1,199 @@
+package event_store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/NusaCrew/atlas-go/log"
+)
+
+var ErrNotRebuildable = errors.New("projection cannot be rebuilt")
+
+// Projection builds a read model from the events of all streams, in order of position.
+type Projection struct {
+	// Name identifies the checkpoint of the projection, renaming it projects every event again.
+	Name  string
+	Apply func(ctx context.Context, event *Event) error
+	// Reset clears the read model before Rebuild, optional.
+	Reset func(ctx context.Context) error
+	// NoRebuild makes Rebuild return ErrNotRebuildable, for projections whose effects cannot
+	// be undone by Reset, like publishing the events.
+	NoRebuild bool
+}
+
+type ProjectorConfig struct {
+	Store       Store
+	Projections []Projection
+	// BatchSize is the number of events read at once, defaults to 100.
+	BatchSize int
+	// PollInterval is the wait between reads once the projections caught up, defaults to 1s.
+	PollInterval time.Duration
+}
+
+// Projector keeps projections up to date with the store, at least once: an event applied right
+// before its checkpoint is saved is applied again after a restart.
+type Projector struct {
+	config ProjectorConfig
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+	once   sync.Once
+}
+
+func NewProjector(config ProjectorConfig) (*Projector, error) {
+	if config.Store == nil {
+		return nil, errors.New("cannot create projector without store")
+	}
+	for _, projection := range config.Projections {
+		if projection.Name == "" || projection.Apply == nil {
+			return nil, errors.New("cannot create projector with a projection without name or apply")
+		}
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = time.Second
+	}
+
+	return &Projector{
+		config: config,
+		done:   make(chan struct{}),
+	}, nil
+}
+
+// ProjectPending applies the new events to every projection, and returns how many were
+// applied. A projection failing to apply an event stops at it until the next call.
+func (p *Projector) ProjectPending(ctx context.Context) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var (
+		total int
+		errs  []error
+	)
+	for _, projection := range p.config.Projections {
+		applied, err := p.project(ctx, projection)
+		total += applied
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return total, errors.Join(errs...)
+}
+
+func (p *Projector) project(ctx context.Context, projection Projection) (int, error) {
+	position, err := p.config.Store.Checkpoint(ctx, projection.Name)
+	if err != nil {
+		return 0, err
+	}
+
+	var applied int
+	for {
+		events, err := p.config.Store.ReadAll(ctx, position, p.config.BatchSize)
+		if err != nil {
+			return applied, err
+		}
+
+		var applyErr error
+		start := position
+		for _, event := range events {
+			if applyErr = projection.Apply(ctx, event); applyErr != nil {
+				applyErr = fmt.Errorf("projection %s failed to apply event %s at position %d: %w", projection.Name, event.ID, event.Position, applyErr)
+				break
+			}
+			position = event.Position
+			applied++
+		}
+
+		if position != start {
+			if err := p.config.Store.SaveCheckpoint(ctx, projection.Name, position); err != nil {
+				return applied, err
+			}
+		}
+		if applyErr != nil || len(events) < p.config.BatchSize {
+			return applied, applyErr
+		}
+	}
+}
+
+// Rebuild resets the projection named name and applies every event again. Projections with
+// NoRebuild are refused with ErrNotRebuildable.
+func (p *Projector) Rebuild(ctx context.Context, name string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, projection := range p.config.Projections {
+		if projection.Name != name {
+			continue
+		}
+
+		if projection.NoRebuild {
+			return fmt.Errorf("failed to rebuild projection %s: %w", name, ErrNotRebuildable)
+		}
+
+		log.Info("rebuilding projection %s", name)
+		if projection.Reset != nil {
+			if err := projection.Reset(ctx); err != nil {
+				return fmt.Errorf("failed to reset projection %s: %w", name, err)
+			}
+		}
+		if err := p.config.Store.SaveCheckpoint(ctx, name, 0); err != nil {
+			return err
+		}
+		applied, err := p.project(ctx, projection)
+		if err != nil {
+			return err
+		}
+		log.Info("rebuilt projection %s from %d events", name, applied)
+		return nil
+	}
+	return fmt.Errorf("projection %s is not registered", name)
+}
+
+// Run implements webserver.WebServer, so the projector runs and stops along the servers of
+// RunServersCommand.
+func (p *Projector) Run(ctx context.Context, errorChannel chan error) {
+	ctx, p.cancel = context.WithCancel(ctx)
+	go func() {
+		defer close(p.done)
+		p.run(ctx)
+	}()
+}
+
+func (p *Projector) run(ctx context.Context) {
+	ticker := time.NewTicker(p.config.PollInterval)
+	defer ticker.Stop()
+
+	log.Info("starting event store projector with %d projections", len(p.config.Projections))
+	for {
+		if _, err := p.ProjectPending(ctx); err != nil && ctx.Err() == nil {
+			log.WithError(err).Error("failed to project events")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Projector) GetName() string {
+	return "Event Store Projector"
+}
+
+// Stop stops the projector and waits for the current projection to finish.
+func (p *Projector) Stop() {
+	p.once.Do(func() {
+		if p.cancel == nil {
+			return
+		}
+		p.cancel()
+		<-p.done
+		log.Info("stopped event store projector")
+	})
+}
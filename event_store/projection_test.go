@@ -0,0 +1,149 @@
+package event_store
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	eo "github.com/NusaCrew/atlas-go/event_observer"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func appendEvents(t *testing.T, store Store, streamID string, types ...string) {
+	for _, eventType := range types {
+		_, err := store.Append(context.Background(), streamID, AnyVersion, &Event{Type: eventType, Data: json.RawMessage(`{}`)})
+		assert.NoError(t, err)
+	}
+}
+
+func TestProjector(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+	appendEvents(t, store, "account-1", "account.opened", "account.deposited")
+	appendEvents(t, store, "account-2", "account.opened")
+
+	var (
+		mu       sync.Mutex
+		accounts = map[string]int{}
+		fail     = true
+	)
+	projector, err := NewProjector(ProjectorConfig{
+		Store:     store,
+		BatchSize: 2,
+		Projections: []Projection{{
+			Name: "accounts",
+			Apply: func(ctx context.Context, event *Event) error {
+				mu.Lock()
+				defer mu.Unlock()
+				if event.Position == 4 && fail {
+					return assert.AnError
+				}
+				accounts[event.StreamID]++
+				return nil
+			},
+			Reset: func(ctx context.Context) error {
+				mu.Lock()
+				defer mu.Unlock()
+				accounts = map[string]int{}
+				return nil
+			},
+		}},
+	})
+	assert.NoError(t, err)
+
+	applied, err := projector.ProjectPending(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, applied)
+	assert.Equal(t, map[string]int{"account-1": 2, "account-2": 1}, accounts)
+
+	appendEvents(t, store, "account-2", "account.deposited", "account.deposited")
+	applied, err = projector.ProjectPending(ctx)
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Zero(t, applied, "projection stops at the failing event")
+	position, _ := store.Checkpoint(ctx, "accounts")
+	assert.Equal(t, int64(3), position)
+
+	fail = false
+	applied, err = projector.ProjectPending(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, applied)
+	assert.Equal(t, map[string]int{"account-1": 2, "account-2": 3}, accounts)
+
+	assert.NoError(t, projector.Rebuild(ctx, "accounts"))
+	assert.Equal(t, map[string]int{"account-1": 2, "account-2": 3}, accounts, "rebuild applies every event once")
+	assert.Error(t, projector.Rebuild(ctx, "unknown"))
+}
+
+func TestProjectorRun(t *testing.T) {
+	store := NewInMemoryStore()
+	var (
+		mu      sync.Mutex
+		applied int
+	)
+	projector, err := NewProjector(ProjectorConfig{
+		Store:        store,
+		PollInterval: 10 * time.Millisecond,
+		Projections: []Projection{{
+			Name: "counter",
+			Apply: func(ctx context.Context, event *Event) error {
+				mu.Lock()
+				defer mu.Unlock()
+				applied++
+				return nil
+			},
+		}},
+	})
+	assert.NoError(t, err)
+
+	projector.Run(context.Background(), make(chan error, 1))
+	appendEvents(t, store, "account-1", "account.opened")
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return applied == 1
+	}, time.Second, 10*time.Millisecond)
+	projector.Stop()
+	projector.Stop()
+}
+
+func TestObserverProjection(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+	observer := eo.NewEventObserver("some-service-name", eo.WithDeliveryMode(eo.DeliverySync))
+
+	var received []*eo.Event
+	assert.NoError(t, observer.Subscribe("account.*", eo.Subscriber{
+		SubscriberName: "notifier",
+		HandlerFunc: func(ctx context.Context, event *eo.Event) error {
+			received = append(received, event)
+			return nil
+		},
+	}))
+
+	projector, err := NewProjector(ProjectorConfig{
+		Store:       store,
+		Projections: []Projection{ObserverProjection("observer", observer, nil)},
+	})
+	assert.NoError(t, err)
+
+	appendEvents(t, store, "account-1", "account.opened", "account.deposited")
+	_, err = projector.ProjectPending(ctx)
+	assert.NoError(t, err)
+
+	stored, _ := store.ReadStream(ctx, "account-1", 1)
+	if assert.Len(t, received, 2) {
+		assert.Equal(t, "account.opened", received[0].Topic)
+		assert.Equal(t, stored[0].ID, received[0].ID)
+		assert.Equal(t, "account-1", received[0].Key)
+		assert.Equal(t, json.RawMessage(`{}`), received[0].Data)
+	}
+
+	assert.ErrorIs(t, projector.Rebuild(ctx, "observer"), ErrNotRebuildable)
+	assert.Len(t, received, 2, "history is not published again")
+
+	_, err = NewProjector(ProjectorConfig{Store: store, Projections: []Projection{{Name: "no-apply"}}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,43 @@
+package promutil
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Config configures the Prometheus metrics of the packages of the library.
+type Config struct {
+	Namespace  string                // defaults to "atlas"
+	Registerer prometheus.Registerer // defaults to prometheus.DefaultRegisterer
+	Buckets    []float64             // defaults to prometheus.DefBuckets
+}
+
+// WithDefaults returns a copy of the config with the unset fields defaulted.
+func (c Config) WithDefaults() Config {
+	if c.Namespace == "" {
+		c.Namespace = "atlas"
+	}
+	if c.Registerer == nil {
+		c.Registerer = prometheus.DefaultRegisterer
+	}
+	if len(c.Buckets) == 0 {
+		c.Buckets = prometheus.DefBuckets
+	}
+	return c
+}
+
+// Register registers c, reusing the already registered collector when the metrics were
+// created before, e.g. by another Logger or EventObserver of the same service.
+func Register[T prometheus.Collector](registerer prometheus.Registerer, c T) (T, error) {
+	if err := registerer.Register(c); err != nil {
+		var alreadyRegistered prometheus.AlreadyRegisteredError
+		if errors.As(err, &alreadyRegistered) {
+			if existing, ok := alreadyRegistered.ExistingCollector.(T); ok {
+				return existing, nil
+			}
+		}
+		return c, err
+	}
+	return c, nil
+}
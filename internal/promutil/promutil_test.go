@@ -0,0 +1,40 @@
+package promutil
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_WithDefaults(t *testing.T) {
+	config := Config{}.WithDefaults()
+	assert.Equal(t, "atlas", config.Namespace)
+	assert.Equal(t, prometheus.DefaultRegisterer, config.Registerer)
+	assert.Equal(t, prometheus.DefBuckets, config.Buckets)
+
+	registry := prometheus.NewRegistry()
+	config = Config{Namespace: "auth", Registerer: registry, Buckets: []float64{1}}.WithDefaults()
+	assert.Equal(t, "auth", config.Namespace)
+	assert.Equal(t, registry, config.Registerer)
+	assert.Equal(t, []float64{1}, config.Buckets)
+}
+
+func TestRegister(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	newCounter := func() *prometheus.CounterVec {
+		return prometheus.NewCounterVec(prometheus.CounterOpts{Name: "requests_total", Help: "Number of requests."}, []string{"method"})
+	}
+
+	first, err := Register(registry, newCounter())
+	require.NoError(t, err)
+
+	second, err := Register(registry, newCounter())
+	require.NoError(t, err)
+	assert.Same(t, first, second, "the registered collector is reused")
+
+	conflicting := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "requests_total", Help: "Other help."}, []string{"method"})
+	_, err = Register(registry, conflicting)
+	assert.Error(t, err)
+}
@@ -0,0 +1,145 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type AlertMessage struct {
+	Severity string         `json:"severity"`
+	Service  string         `json:"service"`
+	Message  string         `json:"message"`
+	Time     time.Time      `json:"time"`
+	Fields   map[string]any `json:"fields,omitempty"`
+}
+
+// AlertSink receives ALERT, PANIC and FATAL logs, e.g. to page the on-call engineer.
+type AlertSink interface {
+	SendAlert(ctx context.Context, alert AlertMessage) error
+}
+
+type WebhookAlertSinkConfig struct {
+	URL     string
+	Timeout time.Duration // defaults to 3 seconds
+	Client  *http.Client  // defaults to a client using Timeout
+	Headers map[string]string
+}
+
+type webhookAlertSink struct {
+	url     string
+	client  *http.Client
+	headers map[string]string
+}
+
+// NewWebhookAlertSink returns an AlertSink posting a Slack-compatible JSON payload
+// ({"text": ...}) to the configured URL, with the structured alert under "alert".
+func NewWebhookAlertSink(config WebhookAlertSinkConfig) (AlertSink, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("cannot create webhook alert sink without url")
+	}
+
+	client := config.Client
+	if client == nil {
+		timeout := config.Timeout
+		if timeout == 0 {
+			timeout = 3 * time.Second
+		}
+		client = &http.Client{Timeout: timeout}
+	}
+
+	return &webhookAlertSink{
+		url:     config.URL,
+		client:  client,
+		headers: config.Headers,
+	}, nil
+}
+
+func (s *webhookAlertSink) SendAlert(ctx context.Context, alert AlertMessage) error {
+	body, err := json.Marshal(map[string]any{
+		"text":  fmt.Sprintf("[%s] %s: %s", alert.Severity, alert.Service, alert.Message),
+		"alert": alert,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create alert request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range s.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send alert: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("alert webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+type alertHook struct {
+	sink AlertSink
+}
+
+// NewAlertHook returns a logrus hook forwarding ALERT, PANIC and FATAL logs to sink.
+// Alerts are sent synchronously so they are not lost when the process exits.
+func NewAlertHook(sink AlertSink) logrus.Hook {
+	return &alertHook{sink: sink}
+}
+
+func (h *alertHook) Levels() []logrus.Level {
+	return []logrus.Level{logrus.ErrorLevel, logrus.PanicLevel, logrus.FatalLevel}
+}
+
+func (h *alertHook) Fire(entry *logrus.Entry) error {
+	severity, _ := entry.Data["severity"].(string)
+	switch severity {
+	case ALERT.String(), PANIC.String(), FATAL.String():
+	default:
+		return nil
+	}
+
+	service, _ := entry.Data["service"].(string)
+	message, _ := entry.Data["message"].(string)
+	if message == "" {
+		message = entry.Message
+	}
+
+	fields := maps.Clone(map[string]any(entry.Data))
+	delete(fields, "severity")
+	delete(fields, "service")
+	delete(fields, "message")
+
+	ctx := entry.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return h.sink.SendAlert(ctx, AlertMessage{
+		Severity: severity,
+		Service:  service,
+		Message:  message,
+		Time:     entry.Time,
+		Fields:   fields,
+	})
+}
+
+// AddAlertSink routes ALERT, PANIC and FATAL logs of the package logger to sink.
+// Call it after Initialize, or use Options.AlertSink for loggers created with New.
+func AddAlertSink(sink AlertSink) {
+	Default().logrusEntry.Logger.AddHook(NewAlertHook(sink))
+}
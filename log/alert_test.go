@@ -0,0 +1,89 @@
+package log
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLogger(t *testing.T, hooks ...logrus.Hook) *Logger {
+	t.Helper()
+
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	l.SetLevel(logrus.DebugLevel)
+	for _, hook := range hooks {
+		l.AddHook(hook)
+	}
+
+	return &Logger{
+		logrusEntry: logrus.NewEntry(l),
+		serviceName: "test-service",
+	}
+}
+
+func TestWebhookAlertSink(t *testing.T) {
+	payloads := make(chan map[string]any, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "secret", r.Header.Get("X-Token"))
+
+		var payload map[string]any
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		payloads <- payload
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	sink, err := NewWebhookAlertSink(WebhookAlertSinkConfig{
+		URL:     server.URL,
+		Headers: map[string]string{"X-Token": "secret"},
+	})
+	require.NoError(t, err)
+
+	l := newTestLogger(t, NewAlertHook(sink))
+
+	l.Error("regular error is not an alert")
+	l.WithField("order_id", "42").Alert("payment provider is down for %d minutes", 5)
+
+	require.Len(t, payloads, 1)
+	payload := <-payloads
+	assert.Equal(t, "[ALERT] test-service: payment provider is down for 5 minutes", payload["text"])
+
+	alert, ok := payload["alert"].(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, "ALERT", alert["severity"])
+	assert.Equal(t, "test-service", alert["service"])
+	assert.Equal(t, map[string]any{"order_id": "42"}, alert["fields"])
+}
+
+func TestWebhookAlertSink_MissingURL(t *testing.T) {
+	_, err := NewWebhookAlertSink(WebhookAlertSinkConfig{})
+	assert.Error(t, err)
+}
+
+func TestLogger_AlertDoesNotExit(t *testing.T) {
+	l := newTestLogger(t)
+	exited := false
+	l.logrusEntry.Logger.ExitFunc = func(int) { exited = true }
+
+	l.Alert("something is wrong")
+	assert.False(t, exited)
+
+	l.Fatal("shutting down")
+	assert.True(t, exited)
+}
+
+func TestLogger_Panic(t *testing.T) {
+	l := newTestLogger(t)
+
+	assert.PanicsWithError(t, "invariant broken: 1", func() {
+		l.Panic("invariant broken: %d", 1)
+	})
+}
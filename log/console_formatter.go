@@ -0,0 +1,161 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	colorReset   = "\x1b[0m"
+	colorGray    = "\x1b[90m"
+	colorRed     = "\x1b[31m"
+	colorYellow  = "\x1b[33m"
+	colorMagenta = "\x1b[35m"
+	colorCyan    = "\x1b[36m"
+	colorBoldRed = "\x1b[1;31m"
+)
+
+// keys rendered in the columns of a console line rather than as trailing fields
+var consoleColumnKeys = []string{"severity", "service", "method", "duration", "message", "error_chain", "stacktrace"}
+
+// ConsoleFormatter renders human-readable, optionally colorized, log lines for local development:
+//
+//	15:04:05.000 ERROR   GetUser                    12ms  failed to get user  code=SERVER_ERROR error=...
+//	    caused by *net.OpError: dial tcp: connection refused
+//	    main.getUser (/app/main.go:42)
+type ConsoleFormatter struct {
+	DisableColors   bool
+	TimestampFormat string // defaults to 15:04:05.000
+	MethodWidth     int    // defaults to 24
+}
+
+func (f *ConsoleFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	timestampFormat := f.TimestampFormat
+	if timestampFormat == "" {
+		timestampFormat = "15:04:05.000"
+	}
+	methodWidth := f.MethodWidth
+	if methodWidth == 0 {
+		methodWidth = 24
+	}
+
+	severity, _ := entry.Data["severity"].(string)
+	if severity == "" {
+		severity = strings.ToUpper(entry.Level.String())
+	}
+	message, _ := entry.Data["message"].(string)
+	if message == "" {
+		message = entry.Message
+	}
+	method, _ := entry.Data["method"].(string)
+
+	buf := &bytes.Buffer{}
+	if entry.Buffer != nil {
+		buf = entry.Buffer
+	}
+
+	fmt.Fprintf(buf, "%s %s %-*s ", f.colorize(colorGray, entry.Time.Format(timestampFormat)), f.colorize(severityColor(severity), fmt.Sprintf("%-7s", severity)), methodWidth, method)
+
+	if duration, ok := entry.Data["duration"]; ok {
+		fmt.Fprintf(buf, "%6vms ", duration)
+	} else {
+		buf.WriteString(strings.Repeat(" ", 9))
+	}
+
+	buf.WriteString(message)
+
+	keys := make([]string, 0, len(entry.Data))
+	for key := range entry.Data {
+		if !slices.Contains(consoleColumnKeys, key) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		fmt.Fprintf(buf, "  %s=%v", f.colorize(colorCyan, key), entry.Data[key])
+	}
+	buf.WriteByte('\n')
+
+	if chain, ok := entry.Data["error_chain"].([]chainLink); ok && len(chain) > 1 {
+		for _, link := range chain[1:] {
+			fmt.Fprintf(buf, "    %s %s: %s\n", f.colorize(colorRed, "caused by"), link.Type, link.Message)
+		}
+	}
+
+	for _, frame := range stackLines(entry.Data["stacktrace"]) {
+		fmt.Fprintf(buf, "    %s\n", f.colorize(colorGray, frame))
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (f *ConsoleFormatter) colorize(color, s string) string {
+	if f.DisableColors {
+		return s
+	}
+	return color + s + colorReset
+}
+
+func severityColor(severity string) string {
+	switch severity {
+	case DEBUG.String(), "TRACE":
+		return colorGray
+	case INFO.String():
+		return colorCyan
+	case WARNING.String():
+		return colorYellow
+	case ERROR.String():
+		return colorRed
+	case ALERT.String():
+		return colorMagenta
+	default:
+		return colorBoldRed
+	}
+}
+
+func stackLines(stacktrace any) []string {
+	switch s := stacktrace.(type) {
+	case []string:
+		return s
+	case string:
+		return strings.Split(strings.TrimSpace(s), "\n")
+	default:
+		return nil
+	}
+}
+
+// FormatterFor returns the formatter for the given AppConfig.Environment: a ConsoleFormatter
+// for "local" and "development" environments or when stdout is a terminal, JSONFormatter otherwise.
+func FormatterFor(environment string) logrus.Formatter {
+	return formatterFor(environment, os.Stdout)
+}
+
+func formatterFor(environment string, output io.Writer) logrus.Formatter {
+	switch strings.ToLower(environment) {
+	case "local", "development":
+		return &ConsoleFormatter{DisableColors: !isTerminal(output)}
+	}
+	if isTerminal(output) {
+		return &ConsoleFormatter{}
+	}
+	return &logrus.JSONFormatter{}
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
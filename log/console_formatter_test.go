@@ -0,0 +1,68 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsoleFormatter_Format(t *testing.T) {
+	entry := logrus.NewEntry(logrus.New())
+	entry.Time = time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)
+	entry.Level = logrus.ErrorLevel
+	entry.Data = logrus.Fields{
+		"severity":   "ERROR",
+		"code":       "SERVER_ERROR",
+		"service":    "user-service",
+		"method":     "GetUser",
+		"duration":   int64(12),
+		"message":    "failed to get user",
+		"user_id":    "42",
+		"stacktrace": []string{"main.getUser (/app/main.go:42)", "main.main (/app/main.go:10)"},
+	}
+
+	formatter := &ConsoleFormatter{DisableColors: true, MethodWidth: 10}
+	out, err := formatter.Format(entry)
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+	assert.Equal(t, []string{
+		"15:04:05.000 ERROR   GetUser        12ms failed to get user  code=SERVER_ERROR  user_id=42",
+		"    main.getUser (/app/main.go:42)",
+		"    main.main (/app/main.go:10)",
+	}, lines)
+}
+
+func TestConsoleFormatter_Colors(t *testing.T) {
+	entry := logrus.NewEntry(logrus.New())
+	entry.Data = logrus.Fields{"severity": "WARNING", "message": "disk almost full"}
+
+	out, err := (&ConsoleFormatter{}).Format(entry)
+	assert.NoError(t, err)
+	assert.Contains(t, string(out), colorYellow+"WARNING"+colorReset)
+}
+
+func TestFormatterFor(t *testing.T) {
+	tests := []struct {
+		name        string
+		environment string
+		want        logrus.Formatter
+	}{
+		{name: "local", environment: "local", want: &ConsoleFormatter{DisableColors: true}},
+		{name: "development", environment: "Development", want: &ConsoleFormatter{DisableColors: true}},
+		{name: "production", environment: "production", want: &logrus.JSONFormatter{}},
+		{name: "empty", environment: "", want: &logrus.JSONFormatter{}},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got := formatterFor(tc.environment, &bytes.Buffer{})
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
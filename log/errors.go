@@ -0,0 +1,138 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+const (
+	maxErrorChainLength = 16
+	maxStackDepth       = 32
+)
+
+// StackTracer is implemented by errors carrying the stack trace of where they were created.
+// Frames are formatted as "function (file:line)".
+type StackTracer interface {
+	StackTrace() []string
+}
+
+type stackError struct {
+	err   error
+	stack []string
+}
+
+// WithStack wraps err with the stack trace of the caller. Error logs render the stack
+// under "stacktrace". It returns nil when err is nil and err itself when it already
+// carries a stack trace.
+func WithStack(err error) error {
+	if err == nil {
+		return nil
+	}
+	var st StackTracer
+	if errors.As(err, &st) {
+		return err
+	}
+	return &stackError{err: err, stack: captureStack(2)}
+}
+
+func (e *stackError) Error() string {
+	return e.err.Error()
+}
+
+func (e *stackError) Unwrap() error {
+	return e.err
+}
+
+func (e *stackError) StackTrace() []string {
+	return e.stack
+}
+
+// captureStack returns the stack of the caller, skipping skip frames (0 being captureStack itself).
+func captureStack(skip int) []string {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(skip+1, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	stack := make([]string, 0, n)
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "runtime.") {
+			stack = append(stack, fmt.Sprintf("%s (%s:%d)", frame.Function, frame.File, frame.Line))
+		}
+		if !more {
+			break
+		}
+	}
+	return stack
+}
+
+type chainLink struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// errorChain flattens the tree of errors wrapped by err, following both
+// Unwrap() error and Unwrap() []error (errors.Join), depth first.
+func errorChain(err error) []chainLink {
+	var chain []chainLink
+
+	var walk func(err error)
+	walk = func(err error) {
+		if err == nil || len(chain) >= maxErrorChainLength {
+			return
+		}
+		if _, ok := err.(*stackError); !ok {
+			chain = append(chain, chainLink{Type: fmt.Sprintf("%T", err), Message: err.Error()})
+		}
+
+		switch e := err.(type) {
+		case interface{ Unwrap() error }:
+			walk(e.Unwrap())
+		case interface{ Unwrap() []error }:
+			for _, inner := range e.Unwrap() {
+				walk(inner)
+			}
+		}
+	}
+	walk(err)
+
+	return chain
+}
+
+// errorFields renders err as structured log fields:
+//   - error: the error message
+//   - error_type: the type of the outermost error
+//   - error_chain: the wrapped errors, when err wraps any
+//   - stacktrace: the stack trace of the innermost error carrying one
+func errorFields(err error) map[string]any {
+	fields := map[string]any{
+		"error": err.Error(),
+	}
+
+	chain := errorChain(err)
+	if len(chain) > 0 {
+		fields["error_type"] = chain[0].Type
+	}
+	if len(chain) > 1 {
+		fields["error_chain"] = chain
+	}
+
+	var stack []string
+	for e := err; e != nil; {
+		if st, ok := e.(StackTracer); ok {
+			stack = st.StackTrace()
+		}
+		u, ok := e.(interface{ Unwrap() error })
+		if !ok {
+			break
+		}
+		e = u.Unwrap()
+	}
+	if len(stack) > 0 {
+		fields["stacktrace"] = stack
+	}
+
+	return fields
+}
@@ -0,0 +1,77 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type notFoundError struct{ id string }
+
+func (e *notFoundError) Error() string { return "user " + e.id + " not found" }
+
+func TestErrorFields(t *testing.T) {
+	root := &notFoundError{id: "42"}
+	joined := errors.Join(root, assert.AnError)
+	err := fmt.Errorf("get user: %w", joined)
+
+	fields := errorFields(err)
+
+	assert.Equal(t, err.Error(), fields["error"])
+	assert.Equal(t, "*fmt.wrapError", fields["error_type"])
+	assert.Equal(t, []chainLink{
+		{Type: "*fmt.wrapError", Message: err.Error()},
+		{Type: "*errors.joinError", Message: joined.Error()},
+		{Type: "*log.notFoundError", Message: "user 42 not found"},
+		{Type: "*errors.errorString", Message: assert.AnError.Error()},
+	}, fields["error_chain"])
+	assert.NotContains(t, fields, "stacktrace")
+}
+
+func TestWithStack(t *testing.T) {
+	assert.Nil(t, WithStack(nil))
+
+	err := WithStack(assert.AnError)
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Equal(t, assert.AnError.Error(), err.Error())
+
+	// wrapping twice keeps the original stack
+	assert.Same(t, err, WithStack(err))
+
+	wrapped := fmt.Errorf("failed: %w", err)
+	fields := errorFields(wrapped)
+
+	stack, ok := fields["stacktrace"].([]string)
+	require.True(t, ok)
+	require.NotEmpty(t, stack)
+	assert.True(t, strings.HasPrefix(stack[0], "github.com/NusaCrew/atlas-go/log.TestWithStack"), stack[0])
+
+	// the stack wrapper is not part of the reported chain
+	assert.Equal(t, []chainLink{
+		{Type: "*fmt.wrapError", Message: wrapped.Error()},
+		{Type: "*errors.errorString", Message: assert.AnError.Error()},
+	}, fields["error_chain"])
+}
+
+func TestLogger_WithErrorCapturesStack(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(Options{ServiceName: "test-service", Output: &buf, StackTraces: true})
+	require.NoError(t, err)
+
+	l.WithError(assert.AnError).Error("failed to save user")
+
+	lines := decodeLines(t, &buf)
+	require.Len(t, lines, 1)
+	assert.Equal(t, assert.AnError.Error(), lines[0]["error"])
+	assert.Equal(t, "*errors.errorString", lines[0]["error_type"])
+
+	stack, ok := lines[0]["stacktrace"].([]any)
+	require.True(t, ok)
+	require.NotEmpty(t, stack)
+	assert.True(t, strings.HasPrefix(stack[0].(string), "github.com/NusaCrew/atlas-go/log.TestLogger_WithErrorCapturesStack"), stack[0])
+}
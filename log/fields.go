@@ -2,6 +2,7 @@ package log
 
 import (
 	"fmt"
+	"maps"
 	"strings"
 
 	"github.com/sirupsen/logrus"
@@ -17,6 +18,7 @@ const (
 	ERROR
 	ALERT
 	PANIC
+	FATAL
 	UNKNOWN_SEVERITY
 )
 
@@ -34,6 +36,8 @@ func (s Severity) String() string {
 		return "ALERT"
 	case PANIC:
 		return "PANIC"
+	case FATAL:
+		return "FATAL"
 	default:
 		return ""
 	}
@@ -102,9 +106,11 @@ func mapSeverityToLogrusLevel(severity Severity) logrus.Level {
 		return logrus.InfoLevel
 	case WARNING:
 		return logrus.WarnLevel
-	case ERROR:
+	case ERROR, ALERT:
 		return logrus.ErrorLevel
-	case ALERT, PANIC:
+	case PANIC:
+		return logrus.PanicLevel
+	case FATAL:
 		return logrus.FatalLevel
 	default:
 		return logrus.InfoLevel
@@ -151,11 +157,15 @@ func (f field) GetMessage() string {
 func (f field) toMap() map[string]any {
 	resultMap := map[string]any{
 		"severity": f.severity.String(),
-		"code":     f.code.String(),
 		"service":  f.serviceName,
 		"message":  f.GetMessage(),
 	}
 
+	// plain logs have no code, omitting it keeps them from overwriting the code of a Tracer log
+	if f.code > 0 && f.code < UnknownCode {
+		resultMap["code"] = f.code.String()
+	}
+
 	if f.methodName != "" {
 		resultMap["method"] = f.methodName
 	}
@@ -166,7 +176,7 @@ func (f field) toMap() map[string]any {
 		resultMap["uri"] = f.uri
 	}
 	if f.err != nil {
-		resultMap["error"] = f.err.Error()
+		maps.Copy(resultMap, errorFields(f.err))
 	}
 	if f.duration != 0 {
 		resultMap["duration"] = f.duration
@@ -19,6 +19,7 @@ func TestSeverity_String(t *testing.T) {
 		{name: "error", s: ERROR, want: "ERROR"},
 		{name: "alert", s: ALERT, want: "ALERT"},
 		{name: "panic", s: PANIC, want: "PANIC"},
+		{name: "fatal", s: FATAL, want: "FATAL"},
 		{name: "unknown in-range", s: UNKNOWN_SEVERITY, want: ""},
 		{name: "zero", s: 0, want: ""},
 		{name: "out of range", s: Severity(999), want: ""},
@@ -81,6 +82,31 @@ func TestMapToLogrusLevel(t *testing.T) {
 	}
 }
 
+func TestMapSeverityToLogrusLevel(t *testing.T) {
+	tests := []struct {
+		name     string
+		severity Severity
+		want     logrus.Level
+	}{
+		{name: "debug", severity: DEBUG, want: logrus.DebugLevel},
+		{name: "info", severity: INFO, want: logrus.InfoLevel},
+		{name: "warning", severity: WARNING, want: logrus.WarnLevel},
+		{name: "error", severity: ERROR, want: logrus.ErrorLevel},
+		{name: "alert", severity: ALERT, want: logrus.ErrorLevel},
+		{name: "panic", severity: PANIC, want: logrus.PanicLevel},
+		{name: "fatal", severity: FATAL, want: logrus.FatalLevel},
+		{name: "default unknown", severity: UNKNOWN_SEVERITY, want: logrus.InfoLevel},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got := mapSeverityToLogrusLevel(tc.severity)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
 func TestField_ToMap(t *testing.T) {
 	tests := []struct {
 		name string
@@ -97,11 +123,11 @@ func TestField_ToMap(t *testing.T) {
 				msg:         "test message",
 			},
 			want: map[string]any{
-				"severity":    "INFO",
-				"code":        "OK",
-				"service":     "test-service",
-				"method_name": "test-method",
-				"message":     "test message",
+				"severity": "INFO",
+				"code":     "OK",
+				"service":  "test-service",
+				"method":   "test-method",
+				"message":  "test message",
 			},
 		},
 		{
@@ -115,12 +141,12 @@ func TestField_ToMap(t *testing.T) {
 				msg:         "error message",
 			},
 			want: map[string]any{
-				"severity":    "ERROR",
-				"code":        "CLIENT_ERROR",
-				"service":     "test-service",
-				"method_name": "test-method",
-				"uri":         "/test",
-				"message":     "error message",
+				"severity": "ERROR",
+				"code":     "CLIENT_ERROR",
+				"service":  "test-service",
+				"method":   "test-method",
+				"uri":      "/test",
+				"message":  "error message",
 			},
 		},
 		{
@@ -134,12 +160,13 @@ func TestField_ToMap(t *testing.T) {
 				msg:         "error message",
 			},
 			want: map[string]any{
-				"severity":    "ERROR",
-				"code":        "SERVER_ERROR",
-				"service":     "test-service",
-				"method_name": "test-method",
-				"error":       assert.AnError.Error(),
-				"message":     "error message",
+				"severity":   "ERROR",
+				"code":       "SERVER_ERROR",
+				"service":    "test-service",
+				"method":     "test-method",
+				"error":      assert.AnError.Error(),
+				"error_type": "*errors.errorString",
+				"message":    "error message",
 			},
 		},
 		{
@@ -153,12 +180,12 @@ func TestField_ToMap(t *testing.T) {
 				msg:         "debug message",
 			},
 			want: map[string]any{
-				"severity":    "DEBUG",
-				"code":        "OK",
-				"service":     "test-service",
-				"method_name": "test-method",
-				"durations":   int64(100),
-				"message":     "debug message",
+				"severity": "DEBUG",
+				"code":     "OK",
+				"service":  "test-service",
+				"method":   "test-method",
+				"duration": int64(100),
+				"message":  "debug message",
 			},
 		},
 	}
@@ -1,88 +1,238 @@
 package log
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"os"
 	"sync"
 
 	"github.com/sirupsen/logrus"
 )
 
-var once sync.Once
+var (
+	loggerMu sync.RWMutex
+	logger   *Logger
+)
 
-var logger *Logger
+// fallbackLogger is used by the package level functions until a default logger is set.
+var fallbackLogger = &Logger{logrusEntry: logrus.NewEntry(logrus.StandardLogger())}
 
 type Logger struct {
 	logrusEntry *logrus.Entry
 	serviceName string
+	metrics     Metrics
+	stackTraces bool
+}
+
+type Options struct {
+	Level       Level
+	ServiceName string
+	Environment string           // AppConfig.Environment, used to pick the default formatter
+	Profile     Profile          // cloud logging schema, used when Formatter is nil
+	ProjectID   string           // GCP project of ProfileGCP trace resource names
+	Formatter   logrus.Formatter // defaults to FormatterFor(Environment)
+	Output      io.Writer        // defaults to os.Stdout
+	Hooks       []logrus.Hook
+	AlertSink   AlertSink
+	Metrics     Metrics // defaults to the Metrics set with SetMetrics
+	StackTraces bool    // capture the stack trace of error logs whose error carries none
+}
+
+// New returns a Logger independent from the package logger, with its own level,
+// formatter, output and sinks. It fails with ErrUnsupportedProfile for an unknown Profile.
+func New(opts Options) (*Logger, error) {
+	if !opts.Profile.valid() {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedProfile, opts.Profile)
+	}
+
+	log := logrus.New()
+
+	log.SetLevel(mapToLogrusLevel(opts.Level))
+
+	output := opts.Output
+	if output == nil {
+		output = os.Stdout
+	}
+	log.SetOutput(output)
+
+	formatter := opts.Formatter
+	if formatter == nil && opts.Profile != ProfileDefault {
+		formatter = &ProfileFormatter{Profile: opts.Profile, ProjectID: opts.ProjectID}
+	}
+	if formatter == nil {
+		formatter = formatterFor(opts.Environment, output)
+	}
+	log.SetFormatter(formatter)
+
+	for _, hook := range opts.Hooks {
+		log.AddHook(hook)
+	}
+	if opts.AlertSink != nil {
+		log.AddHook(NewAlertHook(opts.AlertSink))
+	}
+
+	l := NewLogger(log, opts.ServiceName)
+	l.metrics = opts.Metrics
+	l.stackTraces = opts.StackTraces
+	return l, nil
 }
 
-// Initialize initializes the package logger.
-// It accepts an optional logrus.Formatter as the third parameter. If none is provided,
-// it defaults to JSONFormatter. The variadic parameter keeps the function backward
-// compatible with existing two-argument calls.
+// Initialize initializes the package logger. The optional formatter defaults to
+// ConsoleFormatter when stdout is a terminal and JSONFormatter otherwise, being variadic for
+// the existing two-argument calls. Use InitializeWith to pick it from the environment.
+// Calling it again replaces the package logger.
 func Initialize(logLevel Level, serviceName string, formatterArgs ...logrus.Formatter) {
-	once.Do(func() {
-		log := logrus.New()
+	opts := Options{
+		Level:       logLevel,
+		ServiceName: serviceName,
+	}
+	if len(formatterArgs) > 0 {
+		opts.Formatter = formatterArgs[0]
+	}
+	_ = InitializeWith(opts) // cannot fail without Profile
+}
 
-		log.SetLevel(mapToLogrusLevel(logLevel))
+// InitializeWith initializes the package logger with New(opts), e.g. with the options of
+// config.AppConfig.LogOptions. Calling it again replaces the package logger.
+func InitializeWith(opts Options) error {
+	l, err := New(opts)
+	if err != nil {
+		return fmt.Errorf("failed to initialize logger: %w", err)
+	}
+	if previous := SetDefault(l); previous != nil {
+		Warning("log: package logger was already initialized, it has been replaced")
+	}
+	return nil
+}
 
-		// Choose formatter: use provided formatter if any, otherwise default to JSON
-		var formatter logrus.Formatter
-		if len(formatterArgs) > 0 && formatterArgs[0] != nil {
-			formatter = formatterArgs[0]
-		} else {
-			formatter = &logrus.JSONFormatter{}
-		}
-		log.SetFormatter(formatter)
-		log.SetOutput(os.Stdout)
+// NewLogger wraps an existing logrus logger, e.g. one writing to a test buffer.
+func NewLogger(logrusLogger *logrus.Logger, serviceName string) *Logger {
+	return &Logger{
+		logrusEntry: logrus.NewEntry(logrusLogger),
+		serviceName: serviceName,
+	}
+}
 
-		logger = &Logger{
-			logrusEntry: logrus.NewEntry(log),
-			serviceName: serviceName,
-		}
-	})
+// SetDefault replaces the package logger used by the package level functions and by
+// Tracer, and returns the previous one.
+func SetDefault(l *Logger) *Logger {
+	loggerMu.Lock()
+	defer loggerMu.Unlock()
+	previous := logger
+	logger = l
+	return previous
 }
 
-func newLogger(logrusEntry *logrus.Entry) *Logger {
-	serviceName := ""
-	if logger != nil {
-		serviceName = logger.serviceName
+// Default returns the package logger.
+func Default() *Logger {
+	loggerMu.RLock()
+	defer loggerMu.RUnlock()
+	if logger == nil {
+		return fallbackLogger
 	}
+	return logger
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying l, retrieved with FromContext.
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the logger carried by ctx, or the package logger when there is none.
+func FromContext(ctx context.Context) *Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(contextKey{}).(*Logger); ok && l != nil {
+			return l
+		}
+	}
+	return Default()
+}
+
+func (l *Logger) withEntry(logrusEntry *logrus.Entry) *Logger {
 	return &Logger{
 		logrusEntry: logrusEntry,
-		serviceName: serviceName,
+		serviceName: l.serviceName,
+		metrics:     l.metrics,
+		stackTraces: l.stackTraces,
 	}
 }
 
+// ServiceName returns the service name the logger was created with.
+func (l *Logger) ServiceName() string {
+	return l.serviceName
+}
+
+func (l *Logger) metricsRecorder() Metrics {
+	if l != nil && l.metrics != nil {
+		return l.metrics
+	}
+	return currentMetrics()
+}
+
 func (l *Logger) WithField(key string, value any) *Logger {
-	return newLogger(l.logrusEntry.WithField(key, value))
+	return l.withEntry(l.logrusEntry.WithField(key, value))
 }
 
 func (l *Logger) WithFields(args map[string]any) *Logger {
-	return newLogger(l.logrusEntry.WithFields(args))
+	return l.withEntry(l.logrusEntry.WithFields(args))
 }
 
+// WithError adds err as structured fields: its message, type, wrapped error chain and
+// stack trace, see WithStack and Options.StackTraces.
 func (l *Logger) WithError(err error) *Logger {
-	return newLogger(l.logrusEntry.WithError(err))
+	return l.withError(err, 3)
+}
+
+func (l *Logger) withError(err error, skip int) *Logger {
+	if err == nil {
+		return l
+	}
+	fields := errorFields(err)
+	if _, ok := fields["stacktrace"]; !ok && l.stackTraces {
+		fields["stacktrace"] = captureStack(skip)
+	}
+	return l.withEntry(l.logrusEntry.WithFields(fields))
+}
+
+// PanicError is the value passed to panic by PANIC logs, so callers up the stack can
+// recover it and tell it apart from other panics.
+type PanicError struct {
+	Message string
+}
+
+func (p *PanicError) Error() string {
+	return p.Message
 }
 
 func (l *Logger) logF(severity Severity, msg string, args ...any) {
-	if l == nil || l.logrusEntry == nil {
-		switch severity {
-		case DEBUG:
-			logrus.Debugf(msg, args...)
-		case INFO:
-			logrus.Infof(msg, args...)
-		case WARNING:
-			logrus.Warnf(msg, args...)
-		case ERROR:
-			logrus.Errorf(msg, args...)
-		default:
-			logrus.Fatalf(msg, args...)
-		}
-		return
+	entry := logrus.NewEntry(logrus.StandardLogger())
+	serviceName := ""
+	if l != nil && l.logrusEntry != nil {
+		entry = l.logrusEntry
+		serviceName = l.serviceName
+	}
+
+	f := field{severity: severity, serviceName: serviceName, msg: msg, args: args}
+	entry = entry.WithFields(f.toMap())
+
+	switch severity {
+	case PANIC:
+		// logrus panics with its own *Entry after writing a panic level log, replace it
+		// with a PanicError so the panic value is meaningful to whoever recovers it.
+		func() {
+			defer func() { _ = recover() }()
+			entry.Log(logrus.PanicLevel)
+		}()
+		panic(&PanicError{Message: f.GetMessage()})
+	case FATAL:
+		entry.Log(logrus.FatalLevel)
+		entry.Logger.Exit(1)
+	default:
+		entry.Log(mapSeverityToLogrusLevel(severity))
 	}
-	l.logrusEntry.WithFields(field{severity: severity, serviceName: l.serviceName, msg: msg, args: args}.toMap()).Log(mapSeverityToLogrusLevel(severity))
 }
 
 func (l *Logger) Debug(msg string, args ...any) {
@@ -105,53 +255,54 @@ func (l *Logger) Alert(msg string, args ...any) {
 	l.logF(ALERT, msg, args...)
 }
 
+// Panic logs the message and panics with a *PanicError.
 func (l *Logger) Panic(msg string, args ...any) {
 	l.logF(PANIC, msg, args...)
 }
 
+// Fatal logs the message and exits the process, use it only for intentional exits.
+func (l *Logger) Fatal(msg string, args ...any) {
+	l.logF(FATAL, msg, args...)
+}
+
 // ------------ General Log Provider  ------------
 
 func Debug(msg string, args ...any) {
-	logger.Debug(msg, args...)
+	Default().Debug(msg, args...)
 }
 
 func Info(msg string, args ...any) {
-	logger.Info(msg, args...)
+	Default().Info(msg, args...)
 }
 
 func Warning(msg string, args ...any) {
-	logger.Warning(msg, args...)
+	Default().Warning(msg, args...)
 }
 
 func Error(msg string, args ...any) {
-	logger.Error(msg, args...)
+	Default().Error(msg, args...)
 }
 
 func Alert(msg string, args ...any) {
-	logger.Alert(msg, args...)
+	Default().Alert(msg, args...)
 }
 
 func Panic(msg string, args ...any) {
-	logger.Panic(msg, args...)
+	Default().Panic(msg, args...)
+}
+
+func Fatal(msg string, args ...any) {
+	Default().Fatal(msg, args...)
 }
 
 func WithField(key string, value any) *Logger {
-	if logger == nil {
-		return newLogger(logrus.NewEntry(logrus.StandardLogger()).WithField(key, value))
-	}
-	return logger.WithField(key, value)
+	return Default().WithField(key, value)
 }
 
 func WithFields(args map[string]any) *Logger {
-	if logger == nil {
-		return newLogger(logrus.NewEntry(logrus.StandardLogger()).WithFields(args))
-	}
-	return logger.WithFields(args)
+	return Default().WithFields(args)
 }
 
 func WithError(err error) *Logger {
-	if logger == nil {
-		return newLogger(logrus.NewEntry(logrus.StandardLogger()).WithError(err))
-	}
-	return logger.WithError(err)
+	return Default().withError(err, 3)
 }
@@ -0,0 +1,101 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var decoded map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &decoded))
+		lines = append(lines, decoded)
+	}
+	return lines
+}
+
+func TestNew_IndependentLoggers(t *testing.T) {
+	var debugBuf, errorBuf bytes.Buffer
+	debugLogger, err := New(Options{Level: LevelDebug, ServiceName: "service-a", Output: &debugBuf})
+	require.NoError(t, err)
+	errorLogger, err := New(Options{Level: LevelError, ServiceName: "service-b", Output: &errorBuf})
+	require.NoError(t, err)
+
+	debugLogger.Debug("debug from %s", "a")
+	errorLogger.Debug("debug from %s", "b")
+	errorLogger.WithField("user_id", "42").Error("error from %s", "b")
+
+	debugLines := decodeLines(t, &debugBuf)
+	require.Len(t, debugLines, 1)
+	assert.Equal(t, "service-a", debugLines[0]["service"])
+	assert.Equal(t, "debug from a", debugLines[0]["message"])
+
+	errorLines := decodeLines(t, &errorBuf)
+	require.Len(t, errorLines, 1)
+	assert.Equal(t, "service-b", errorLines[0]["service"])
+	assert.Equal(t, "42", errorLines[0]["user_id"])
+}
+
+func TestFromContext(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(Options{ServiceName: "ctx-service", Output: &buf})
+	require.NoError(t, err)
+
+	assert.Same(t, Default(), FromContext(context.Background()))
+
+	ctx := NewContext(context.Background(), l)
+	assert.Same(t, l, FromContext(ctx))
+
+	NewTracer(ctx, "GetUser", "ctx-service").TraceResponse(nil)
+
+	lines := decodeLines(t, &buf)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "GetUser", lines[0]["method"])
+	assert.Equal(t, "OK", lines[0]["code"])
+}
+
+func TestInitialize_ReplacesDefault(t *testing.T) {
+	previous := SetDefault(nil)
+	defer SetDefault(previous)
+
+	Initialize(LevelInfo, "first")
+	assert.Equal(t, "first", Default().ServiceName())
+
+	Initialize(LevelInfo, "second")
+	assert.Equal(t, "second", Default().ServiceName())
+}
+
+func TestInitializeWith(t *testing.T) {
+	previous := SetDefault(nil)
+	defer SetDefault(previous)
+
+	var buf bytes.Buffer
+	require.NoError(t, InitializeWith(Options{Level: LevelInfo, ServiceName: "local-service", Environment: "local", Output: &buf}))
+	assert.Equal(t, "local-service", Default().ServiceName())
+
+	Info("hello")
+	assert.Contains(t, buf.String(), "hello")
+	assert.NotContains(t, buf.String(), "{", "the local environment logs with the console formatter")
+}
+
+func TestNew_UnsupportedProfile(t *testing.T) {
+	_, err := New(Options{Profile: "splunk"})
+	assert.ErrorIs(t, err, ErrUnsupportedProfile)
+
+	previous := SetDefault(nil)
+	defer SetDefault(previous)
+	assert.ErrorIs(t, InitializeWith(Options{Profile: "splunk"}), ErrUnsupportedProfile)
+	assert.Same(t, fallbackLogger, Default(), "the package logger is kept")
+}
@@ -0,0 +1,74 @@
+package logtest
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/NusaCrew/atlas-go/log"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Logged asserts that an entry with the given severity and a message containing substr was captured.
+func Logged(t assert.TestingT, r *Recorder, severity log.Severity, substr string, msgAndArgs ...any) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+
+	entries := r.Entries()
+	if len(entries.BySeverity(severity).Containing(substr)) > 0 {
+		return true
+	}
+	return assert.Fail(t, fmt.Sprintf("no %s log containing %q, captured:\n%s", severity, substr, describe(entries)), msgAndArgs...)
+}
+
+// NotLogged asserts that no entry with the given severity and a message containing substr was captured.
+func NotLogged(t assert.TestingT, r *Recorder, severity log.Severity, substr string, msgAndArgs ...any) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+
+	found := r.Entries().BySeverity(severity).Containing(substr)
+	if len(found) == 0 {
+		return true
+	}
+	return assert.Fail(t, fmt.Sprintf("unexpected %s log containing %q, captured:\n%s", severity, substr, describe(found)), msgAndArgs...)
+}
+
+// LoggedWithField asserts that an entry with the given field value was captured.
+func LoggedWithField(t assert.TestingT, r *Recorder, key string, value any, msgAndArgs ...any) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+
+	entries := r.Entries()
+	if len(entries.ByField(key, value)) > 0 {
+		return true
+	}
+	return assert.Fail(t, fmt.Sprintf("no log with field %s=%v, captured:\n%s", key, value, describe(entries)), msgAndArgs...)
+}
+
+// Traced asserts that a Tracer log for method with the given code was captured.
+func Traced(t assert.TestingT, r *Recorder, method string, code log.Code, msgAndArgs ...any) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+
+	entries := r.Entries()
+	if len(entries.ByMethod(method).ByCode(code)) > 0 {
+		return true
+	}
+	return assert.Fail(t, fmt.Sprintf("no %s trace for method %s, captured:\n%s", code, method, describe(entries)), msgAndArgs...)
+}
+
+func describe(entries Entries) string {
+	if len(entries) == 0 {
+		return "\t(none)"
+	}
+
+	var sb strings.Builder
+	for _, entry := range entries {
+		fmt.Fprintf(&sb, "\t[%s] %s %s\n", entry.Severity, entry.Method, entry.Message)
+	}
+	return sb.String()
+}
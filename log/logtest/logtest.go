@@ -0,0 +1,209 @@
+package logtest
+
+import (
+	"io"
+	"maps"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NusaCrew/atlas-go/log"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// Entry is a captured log entry.
+type Entry struct {
+	Level    logrus.Level
+	Severity string
+	Code     string
+	Service  string
+	Method   string
+	Message  string
+	Time     time.Time
+	Fields   map[string]any
+}
+
+// Recorder captures every entry written to the loggers it is attached to. It is safe
+// for concurrent use.
+type Recorder struct {
+	mu      sync.RWMutex
+	entries []Entry
+}
+
+func (r *Recorder) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (r *Recorder) Fire(e *logrus.Entry) error {
+	fields := maps.Clone(map[string]any(e.Data))
+
+	entry := Entry{
+		Level:   e.Level,
+		Message: e.Message,
+		Time:    e.Time,
+		Fields:  fields,
+	}
+	entry.Severity, _ = fields["severity"].(string)
+	entry.Code, _ = fields["code"].(string)
+	entry.Service, _ = fields["service"].(string)
+	entry.Method, _ = fields["method"].(string)
+	if message, ok := fields["message"].(string); ok {
+		entry.Message = message
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+// Entries returns a copy of the captured entries.
+func (r *Recorder) Entries() Entries {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append(Entries(nil), r.entries...)
+}
+
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = nil
+}
+
+// NewLogger returns an independent logger capturing every level into the returned
+// Recorder. It does not touch the package logger, so it is safe for parallel tests
+// as long as the logger is injected into the code under test, e.g. with log.NewContext.
+func NewLogger(serviceName string) (*log.Logger, *Recorder) {
+	recorder := &Recorder{}
+
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	l.SetLevel(logrus.TraceLevel)
+	l.AddHook(recorder)
+	l.ExitFunc = func(int) {}
+
+	return log.NewLogger(l, serviceName), recorder
+}
+
+// capture is the package logger installed while Capture is active, firing every entry into
+// the recorders of the active Capture calls.
+var capture struct {
+	mu        sync.Mutex
+	recorders map[*Recorder]struct{}
+	previous  *log.Logger
+}
+
+type captureHook struct{}
+
+func (captureHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (captureHook) Fire(e *logrus.Entry) error {
+	capture.mu.Lock()
+	defer capture.mu.Unlock()
+	for recorder := range capture.recorders {
+		_ = recorder.Fire(e)
+	}
+	return nil
+}
+
+// Capture replaces the package logger with a capturing one for the duration of the test.
+// Nested and parallel calls share the capturing logger, named after the service of the first
+// call, and each Recorder captures every entry logged while it is active: use NewLogger to
+// only capture the logs of a test.
+func Capture(t testing.TB, serviceName string) *Recorder {
+	t.Helper()
+
+	recorder := &Recorder{}
+
+	capture.mu.Lock()
+	defer capture.mu.Unlock()
+	if len(capture.recorders) == 0 {
+		l := logrus.New()
+		l.SetOutput(io.Discard)
+		l.SetLevel(logrus.TraceLevel)
+		l.AddHook(captureHook{})
+		l.ExitFunc = func(int) {}
+
+		capture.recorders = make(map[*Recorder]struct{})
+		capture.previous = log.SetDefault(log.NewLogger(l, serviceName))
+	}
+	capture.recorders[recorder] = struct{}{}
+
+	t.Cleanup(func() {
+		capture.mu.Lock()
+		defer capture.mu.Unlock()
+		delete(capture.recorders, recorder)
+		if len(capture.recorders) == 0 {
+			log.SetDefault(capture.previous)
+			capture.previous = nil
+		}
+	})
+
+	return recorder
+}
+
+// --------------- Queries ---------------
+
+type Entries []Entry
+
+func (e Entries) Filter(fn func(Entry) bool) Entries {
+	var result Entries
+	for _, entry := range e {
+		if fn(entry) {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+func (e Entries) BySeverity(severity log.Severity) Entries {
+	return e.Filter(func(entry Entry) bool {
+		return entry.Severity == severity.String()
+	})
+}
+
+func (e Entries) ByCode(code log.Code) Entries {
+	return e.Filter(func(entry Entry) bool {
+		return entry.Code == code.String()
+	})
+}
+
+func (e Entries) ByMethod(method string) Entries {
+	return e.Filter(func(entry Entry) bool {
+		return entry.Method == method
+	})
+}
+
+func (e Entries) ByField(key string, value any) Entries {
+	return e.Filter(func(entry Entry) bool {
+		got, ok := entry.Fields[key]
+		return ok && assert.ObjectsAreEqual(value, got)
+	})
+}
+
+func (e Entries) Containing(substr string) Entries {
+	return e.Filter(func(entry Entry) bool {
+		return strings.Contains(entry.Message, substr)
+	})
+}
+
+func (e Entries) Messages() []string {
+	messages := make([]string, 0, len(e))
+	for _, entry := range e {
+		messages = append(messages, entry.Message)
+	}
+	return messages
+}
+
+// Last returns the last entry, or false when there is none.
+func (e Entries) Last() (Entry, bool) {
+	if len(e) == 0 {
+		return Entry{}, false
+	}
+	return e[len(e)-1], true
+}
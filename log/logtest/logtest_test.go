@@ -0,0 +1,100 @@
+package logtest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/NusaCrew/atlas-go/log"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestNewLogger(t *testing.T) {
+	t.Parallel()
+
+	logger, recorder := NewLogger("test-service")
+
+	logger.Info("user %s logged in", "john")
+	logger.WithField("order_id", "42").Warning("order is late")
+
+	entries := recorder.Entries()
+	assert.Len(t, entries, 2)
+	assert.Equal(t, []string{"user john logged in"}, entries.BySeverity(log.INFO).Messages())
+	assert.Len(t, entries.ByField("order_id", "42"), 1)
+
+	last, ok := entries.Last()
+	assert.True(t, ok)
+	assert.Equal(t, "test-service", last.Service)
+
+	Logged(t, recorder, log.WARNING, "late")
+	NotLogged(t, recorder, log.ERROR, "late")
+	LoggedWithField(t, recorder, "order_id", "42")
+
+	recorder.Reset()
+	assert.Empty(t, recorder.Entries())
+}
+
+func TestNewLogger_Tracer(t *testing.T) {
+	t.Parallel()
+
+	logger, recorder := NewLogger("test-service")
+
+	log.NewTracer(context.Background(), "GetUser", "test-service").
+		WithLogger(logger).
+		TraceResponse(status.Error(codes.Internal, "database is down"))
+
+	Traced(t, recorder, "GetUser", log.ServerError)
+	Logged(t, recorder, log.ERROR, "database is down")
+}
+
+func TestCapture(t *testing.T) {
+	recorder := Capture(t, "test-service")
+
+	log.Error("failed to connect: %s", "timeout")
+
+	Logged(t, recorder, log.ERROR, "failed to connect: timeout")
+}
+
+func TestCapture_Nested(t *testing.T) {
+	previous := log.Default()
+	outer := Capture(t, "test-service")
+
+	t.Run("inner", func(t *testing.T) {
+		inner := Capture(t, "other-service")
+		log.Info("from inner")
+
+		Logged(t, inner, log.INFO, "from inner")
+	})
+	log.Info("from outer")
+
+	Logged(t, outer, log.INFO, "from inner")
+	Logged(t, outer, log.INFO, "from outer")
+	assert.NotSame(t, previous, log.Default(), "the outer capture is still active")
+}
+
+func TestCapture_Parallel(t *testing.T) {
+	for _, name := range []string{"a", "b", "c"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			recorder := Capture(t, "test-service")
+			log.Info("from %s", name)
+
+			Logged(t, recorder, log.INFO, "from "+name)
+		})
+	}
+}
+
+func TestAssertions_Fail(t *testing.T) {
+	t.Parallel()
+
+	_, recorder := NewLogger("test-service")
+	mockT := &testing.T{}
+
+	assert.False(t, Logged(mockT, recorder, log.ERROR, "anything"))
+	assert.False(t, LoggedWithField(mockT, recorder, "key", "value"))
+	assert.False(t, Traced(mockT, recorder, "GetUser", log.OK))
+}
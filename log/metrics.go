@@ -0,0 +1,104 @@
+package log
+
+import (
+	"sync"
+	"time"
+
+	"github.com/NusaCrew/atlas-go/internal/promutil"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MetricRecord describes a single Tracer log.
+type MetricRecord struct {
+	Service  string
+	Method   string
+	Code     Code
+	Event    Event
+	Severity Severity
+	Duration time.Duration
+}
+
+// Metrics records Tracer logs as metrics, so dashboards don't depend on parsing logs.
+type Metrics interface {
+	Record(record MetricRecord)
+}
+
+type noopMetrics struct{}
+
+func (noopMetrics) Record(MetricRecord) {}
+
+var (
+	metricsMu sync.RWMutex
+	metrics   Metrics = noopMetrics{}
+)
+
+// SetMetrics replaces the Metrics used by every Tracer whose logger has no Metrics of
+// its own. Passing nil disables metrics.
+func SetMetrics(m Metrics) {
+	if m == nil {
+		m = noopMetrics{}
+	}
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	metrics = m
+}
+
+func currentMetrics() Metrics {
+	metricsMu.RLock()
+	defer metricsMu.RUnlock()
+	return metrics
+}
+
+type PrometheusMetricsConfig = promutil.Config
+
+type prometheusMetrics struct {
+	duration *prometheus.HistogramVec
+	total    *prometheus.CounterVec
+}
+
+// NewPrometheusMetrics registers the tracer metrics:
+//   - <namespace>_tracer_duration_seconds, a histogram of response durations by service, method, code and event
+//   - <namespace>_tracer_logs_total, a counter of tracer logs by service, method, code, event and severity
+func NewPrometheusMetrics(config PrometheusMetricsConfig) (Metrics, error) {
+	config = config.WithDefaults()
+	namespace, registerer, buckets := config.Namespace, config.Registerer, config.Buckets
+
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "tracer",
+		Name:      "duration_seconds",
+		Help:      "Duration of traced methods until their response.",
+		Buckets:   buckets,
+	}, []string{"service", "method", "code", "event"})
+
+	total := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "tracer",
+		Name:      "logs_total",
+		Help:      "Number of tracer logs.",
+	}, []string{"service", "method", "code", "event", "severity"})
+
+	var err error
+	if duration, err = promutil.Register(registerer, duration); err != nil {
+		return nil, err
+	}
+	if total, err = promutil.Register(registerer, total); err != nil {
+		return nil, err
+	}
+
+	return &prometheusMetrics{
+		duration: duration,
+		total:    total,
+	}, nil
+}
+
+func (m *prometheusMetrics) Record(record MetricRecord) {
+	code := record.Code.String()
+	event := record.Event.String()
+
+	m.total.WithLabelValues(record.Service, record.Method, code, event, record.Severity.String()).Inc()
+	if record.Event == Response {
+		m.duration.WithLabelValues(record.Service, record.Method, code, event).Observe(record.Duration.Seconds())
+	}
+}
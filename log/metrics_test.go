@@ -0,0 +1,55 @@
+package log
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type recordingMetrics struct {
+	records []MetricRecord
+}
+
+func (m *recordingMetrics) Record(record MetricRecord) {
+	m.records = append(m.records, record)
+}
+
+func TestTracer_RecordsMetrics(t *testing.T) {
+	recorder := &recordingMetrics{}
+	SetMetrics(recorder)
+	defer SetMetrics(nil)
+
+	tracer := NewTracer(context.Background(), "GetUser", "user-service")
+	tracer.TraceResponse(status.Error(codes.InvalidArgument, "invalid argument"))
+
+	require.Len(t, recorder.records, 1)
+	record := recorder.records[0]
+	assert.Equal(t, "user-service", record.Service)
+	assert.Equal(t, "GetUser", record.Method)
+	assert.Equal(t, ClientError, record.Code)
+	assert.Equal(t, Response, record.Event)
+	assert.Equal(t, ERROR, record.Severity)
+}
+
+func TestPrometheusMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	m, err := NewPrometheusMetrics(PrometheusMetricsConfig{Registerer: registry})
+	require.NoError(t, err)
+
+	m.Record(MetricRecord{Service: "user-service", Method: "GetUser", Code: OK, Event: Response, Severity: INFO})
+	m.Record(MetricRecord{Service: "user-service", Method: "GetUser", Code: ServerError, Event: Response, Severity: ERROR})
+	m.Record(MetricRecord{Service: "user-service", Method: "GetUser", Code: OK, Event: Request, Severity: DEBUG})
+
+	assert.Equal(t, 3, testutil.CollectAndCount(registry, "atlas_tracer_logs_total"))
+	assert.Equal(t, 2, testutil.CollectAndCount(registry, "atlas_tracer_duration_seconds"))
+
+	// creating the metrics twice reuses the registered collectors
+	_, err = NewPrometheusMetrics(PrometheusMetricsConfig{Registerer: registry})
+	assert.NoError(t, err)
+}
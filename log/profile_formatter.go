@@ -0,0 +1,255 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Profile selects the JSON schema logs are written with, so they are understood by the log
+// backend without extra parsing rules.
+type Profile string
+
+const (
+	ProfileDefault    Profile = ""           // the atlas schema, same as logrus.JSONFormatter
+	ProfileGCP        Profile = "gcp"        // GCP Cloud Logging structured logging
+	ProfileCloudWatch Profile = "cloudwatch" // AWS CloudWatch Logs Insights friendly flat JSON
+	ProfileECS        Profile = "ecs"        // Elastic Common Schema
+)
+
+const ecsVersion = "8.11.0"
+
+var ErrUnsupportedProfile = errors.New("unsupported log profile")
+
+func (p Profile) valid() bool {
+	switch p {
+	case ProfileDefault, ProfileGCP, ProfileCloudWatch, ProfileECS:
+		return true
+	default:
+		return false
+	}
+}
+
+// ProfileFormatter writes entries in the JSON schema of its Profile.
+type ProfileFormatter struct {
+	Profile Profile
+	// ProjectID is the GCP project used to build the logging.googleapis.com/trace resource name.
+	ProjectID string
+}
+
+func (f *ProfileFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	if f.Profile == ProfileDefault {
+		return (&logrus.JSONFormatter{}).Format(entry)
+	}
+
+	data := make(map[string]any, len(entry.Data))
+	for key, value := range entry.Data {
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+		data[key] = value
+	}
+
+	if _, ok := data["severity"]; !ok {
+		data["severity"] = strings.ToUpper(entry.Level.String())
+	}
+	if _, ok := data["message"]; !ok {
+		data["message"] = entry.Message
+	}
+
+	var out map[string]any
+	switch f.Profile {
+	case ProfileGCP:
+		out = f.gcp(entry, data)
+	case ProfileCloudWatch:
+		out = f.cloudWatch(entry, data)
+	case ProfileECS:
+		out = f.ecs(entry, data)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedProfile, f.Profile)
+	}
+
+	buf := &bytes.Buffer{}
+	if entry.Buffer != nil {
+		buf = entry.Buffer
+	}
+	if err := json.NewEncoder(buf).Encode(out); err != nil {
+		return nil, fmt.Errorf("failed to marshal fields to JSON, %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// take removes key from data and returns its value.
+func take(data map[string]any, key string) (any, bool) {
+	value, ok := data[key]
+	delete(data, key)
+	return value, ok
+}
+
+func takeString(data map[string]any, key string) string {
+	value, _ := take(data, key)
+	s, _ := value.(string)
+	return s
+}
+
+func takeDuration(data map[string]any) (time.Duration, bool) {
+	value, ok := take(data, "duration")
+	if !ok {
+		return 0, false
+	}
+	ms, ok := value.(int64)
+	return time.Duration(ms) * time.Millisecond, ok
+}
+
+func gcpSeverity(severity string) string {
+	switch severity {
+	case PANIC.String():
+		return "CRITICAL"
+	case FATAL.String():
+		return "EMERGENCY"
+	case "WARN":
+		return "WARNING"
+	case "TRACE":
+		return "DEBUG"
+	default:
+		return severity
+	}
+}
+
+func (f *ProfileFormatter) gcp(entry *logrus.Entry, data map[string]any) map[string]any {
+	out := map[string]any{
+		"severity": gcpSeverity(takeString(data, "severity")),
+		"message":  takeString(data, "message"),
+		"time":     entry.Time.Format(time.RFC3339Nano),
+	}
+
+	service := takeString(data, "service")
+	if service != "" {
+		out["serviceContext"] = map[string]string{"service": service}
+	}
+
+	labels := map[string]string{}
+	for _, key := range []string{"code", "method", "event_type"} {
+		if value := takeString(data, key); value != "" {
+			labels[key] = value
+		}
+	}
+	if service != "" {
+		labels["service"] = service
+	}
+	if len(labels) > 0 {
+		out["logging.googleapis.com/labels"] = labels
+	}
+
+	httpRequest := map[string]string{}
+	if uri := takeString(data, "uri"); uri != "" {
+		httpRequest["requestUrl"] = uri
+	}
+	if duration, ok := takeDuration(data); ok {
+		httpRequest["latency"] = fmt.Sprintf("%.3fs", duration.Seconds())
+	}
+	if len(httpRequest) > 0 {
+		out["httpRequest"] = httpRequest
+	}
+
+	if traceID := takeString(data, "trace_id"); traceID != "" {
+		if f.ProjectID != "" {
+			traceID = fmt.Sprintf("projects/%s/traces/%s", f.ProjectID, traceID)
+		}
+		out["logging.googleapis.com/trace"] = traceID
+	}
+	if spanID := takeString(data, "span_id"); spanID != "" {
+		out["logging.googleapis.com/spanId"] = spanID
+	}
+
+	// Error Reporting picks up stack traces from the stack_trace field
+	if stack := stackLines(data["stacktrace"]); len(stack) > 0 {
+		delete(data, "stacktrace")
+		out["stack_trace"] = strings.Join(stack, "\n")
+	}
+
+	for key, value := range data {
+		out[key] = value
+	}
+	return out
+}
+
+func (f *ProfileFormatter) cloudWatch(entry *logrus.Entry, data map[string]any) map[string]any {
+	out := map[string]any{
+		"timestamp": entry.Time.Format(time.RFC3339Nano),
+		"level":     takeString(data, "severity"),
+		"message":   takeString(data, "message"),
+	}
+
+	if eventType := takeString(data, "event_type"); eventType != "" {
+		out["event"] = eventType
+	}
+	if duration, ok := takeDuration(data); ok {
+		out["duration_ms"] = duration.Milliseconds()
+	}
+	if stack := stackLines(data["stacktrace"]); len(stack) > 0 {
+		delete(data, "stacktrace")
+		out["stack_trace"] = stack
+	}
+
+	for key, value := range data {
+		out[key] = value
+	}
+	return out
+}
+
+func (f *ProfileFormatter) ecs(entry *logrus.Entry, data map[string]any) map[string]any {
+	out := map[string]any{
+		"@timestamp":  entry.Time.UTC().Format("2006-01-02T15:04:05.000Z"),
+		"log.level":   strings.ToLower(takeString(data, "severity")),
+		"message":     takeString(data, "message"),
+		"ecs.version": ecsVersion,
+	}
+
+	mapping := map[string]string{
+		"service":    "service.name",
+		"method":     "event.action",
+		"uri":        "url.path",
+		"trace_id":   "trace.id",
+		"span_id":    "span.id",
+		"error":      "error.message",
+		"error_type": "error.type",
+	}
+	for from, to := range mapping {
+		if value := takeString(data, from); value != "" {
+			out[to] = value
+		}
+	}
+
+	if duration, ok := takeDuration(data); ok {
+		out["event.duration"] = duration.Nanoseconds()
+	}
+	labels := map[string]string{}
+	if eventType := takeString(data, "event_type"); eventType != "" {
+		labels["event_type"] = eventType
+	}
+	if code := takeString(data, "code"); code != "" {
+		out["event.outcome"] = "failure"
+		if code == OK.String() {
+			out["event.outcome"] = "success"
+		}
+		labels["code"] = code
+	}
+	if len(labels) > 0 {
+		out["labels"] = labels
+	}
+	if stack := stackLines(data["stacktrace"]); len(stack) > 0 {
+		delete(data, "stacktrace")
+		out["error.stack_trace"] = strings.Join(stack, "\n")
+	}
+
+	for key, value := range data {
+		out[key] = value
+	}
+	return out
+}
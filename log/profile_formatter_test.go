@@ -0,0 +1,166 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+func newProfileEntry() *logrus.Entry {
+	entry := logrus.NewEntry(logrus.New())
+	entry.Time = time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)
+	entry.Level = logrus.ErrorLevel
+	entry.Data = logrus.Fields{
+		"severity":   "PANIC",
+		"code":       "SERVER_ERROR",
+		"service":    "user-service",
+		"method":     "GetUser",
+		"event_type": "response",
+		"uri":        "/api.v1.UserService/GetUser",
+		"duration":   int64(1500),
+		"trace_id":   "4bf92f3577b34da6a3ce929d0e0e4736",
+		"span_id":    "00f067aa0ba902b7",
+		"error":      "database is down",
+		"stacktrace": []string{"main.getUser (/app/main.go:42)"},
+		"message":    "failed to get user",
+		"user_id":    "42",
+	}
+	return entry
+}
+
+func formatProfile(t *testing.T, formatter *ProfileFormatter, entry *logrus.Entry) map[string]any {
+	t.Helper()
+
+	out, err := formatter.Format(entry)
+	require.NoError(t, err)
+
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(out, &decoded))
+	return decoded
+}
+
+func TestProfileFormatter_GCP(t *testing.T) {
+	got := formatProfile(t, &ProfileFormatter{Profile: ProfileGCP, ProjectID: "my-project"}, newProfileEntry())
+
+	assert.Equal(t, map[string]any{
+		"severity":       "CRITICAL",
+		"message":        "failed to get user",
+		"time":           "2025-01-02T15:04:05Z",
+		"serviceContext": map[string]any{"service": "user-service"},
+		"logging.googleapis.com/labels": map[string]any{
+			"code":       "SERVER_ERROR",
+			"method":     "GetUser",
+			"event_type": "response",
+			"service":    "user-service",
+		},
+		"httpRequest": map[string]any{
+			"requestUrl": "/api.v1.UserService/GetUser",
+			"latency":    "1.500s",
+		},
+		"logging.googleapis.com/trace":  "projects/my-project/traces/4bf92f3577b34da6a3ce929d0e0e4736",
+		"logging.googleapis.com/spanId": "00f067aa0ba902b7",
+		"stack_trace":                   "main.getUser (/app/main.go:42)",
+		"error":                         "database is down",
+		"user_id":                       "42",
+	}, got)
+}
+
+func TestProfileFormatter_CloudWatch(t *testing.T) {
+	got := formatProfile(t, &ProfileFormatter{Profile: ProfileCloudWatch}, newProfileEntry())
+
+	assert.Equal(t, map[string]any{
+		"timestamp":   "2025-01-02T15:04:05Z",
+		"level":       "PANIC",
+		"message":     "failed to get user",
+		"event":       "response",
+		"duration_ms": float64(1500),
+		"stack_trace": []any{"main.getUser (/app/main.go:42)"},
+		"code":        "SERVER_ERROR",
+		"service":     "user-service",
+		"method":      "GetUser",
+		"uri":         "/api.v1.UserService/GetUser",
+		"trace_id":    "4bf92f3577b34da6a3ce929d0e0e4736",
+		"span_id":     "00f067aa0ba902b7",
+		"error":       "database is down",
+		"user_id":     "42",
+	}, got)
+}
+
+func TestProfileFormatter_ECS(t *testing.T) {
+	got := formatProfile(t, &ProfileFormatter{Profile: ProfileECS}, newProfileEntry())
+
+	assert.Equal(t, map[string]any{
+		"@timestamp":        "2025-01-02T15:04:05.000Z",
+		"log.level":         "panic",
+		"message":           "failed to get user",
+		"ecs.version":       ecsVersion,
+		"service.name":      "user-service",
+		"event.action":      "GetUser",
+		"url.path":          "/api.v1.UserService/GetUser",
+		"trace.id":          "4bf92f3577b34da6a3ce929d0e0e4736",
+		"span.id":           "00f067aa0ba902b7",
+		"error.message":     "database is down",
+		"event.duration":    float64(1500 * time.Millisecond),
+		"event.outcome":     "failure",
+		"labels":            map[string]any{"code": "SERVER_ERROR", "event_type": "response"},
+		"error.stack_trace": "main.getUser (/app/main.go:42)",
+		"user_id":           "42",
+	}, got)
+}
+
+func TestProfileFormatter_Unsupported(t *testing.T) {
+	_, err := (&ProfileFormatter{Profile: "splunk"}).Format(newProfileEntry())
+	assert.Error(t, err)
+}
+
+func TestTraceFromContext(t *testing.T) {
+	tests := []struct {
+		name      string
+		md        metadata.MD
+		wantTrace string
+		wantSpan  string
+	}{
+		{
+			name:      "w3c traceparent",
+			md:        metadata.Pairs("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"),
+			wantTrace: "4bf92f3577b34da6a3ce929d0e0e4736",
+			wantSpan:  "00f067aa0ba902b7",
+		},
+		{
+			name:      "gcp cloud trace",
+			md:        metadata.Pairs("x-cloud-trace-context", "105445aa7843bc8bf206b12000100000/1;o=1"),
+			wantTrace: "105445aa7843bc8bf206b12000100000",
+			wantSpan:  "1",
+		},
+		{
+			name:      "aws x-ray",
+			md:        metadata.Pairs("x-amzn-trace-id", "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1"),
+			wantTrace: "1-5759e988-bd862e3fe1be46a994272793",
+			wantSpan:  "53995c3f42cd8ad8",
+		},
+		{
+			name:      "correlation id",
+			md:        metadata.Pairs("correlation-id", "abc-123"),
+			wantTrace: "abc-123",
+		},
+		{
+			name: "none",
+			md:   metadata.MD{},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			traceID, spanID := traceFromContext(metadata.NewIncomingContext(context.Background(), tc.md))
+			assert.Equal(t, tc.wantTrace, traceID)
+			assert.Equal(t, tc.wantSpan, spanID)
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package log
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// headers carrying the trace context, in order of preference
+const (
+	TraceParentHeader    = "traceparent"           // W3C Trace Context
+	CloudTraceHeader     = "x-cloud-trace-context" // GCP
+	AmazonTraceHeader    = "x-amzn-trace-id"       // AWS X-Ray
+	CorrelationIDHeader  = "correlation-id"
+	XCorrelationIDHeader = "x-correlation-id"
+)
+
+// TraceHeaders are the headers forwarded by the HTTP gateway so Tracer can correlate logs.
+var TraceHeaders = []string{TraceParentHeader, CloudTraceHeader, AmazonTraceHeader, CorrelationIDHeader, XCorrelationIDHeader}
+
+// traceFromContext returns the trace and span IDs found in the incoming gRPC metadata of ctx.
+func traceFromContext(ctx context.Context) (traceID, spanID string) {
+	if ctx == nil {
+		return "", ""
+	}
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", ""
+	}
+
+	get := func(key string) string {
+		if values := md.Get(key); len(values) > 0 {
+			return strings.TrimSpace(values[0])
+		}
+		return ""
+	}
+
+	// 00-<trace-id>-<span-id>-<flags>
+	if value := get(TraceParentHeader); value != "" {
+		parts := strings.Split(value, "-")
+		if len(parts) == 4 {
+			return parts[1], parts[2]
+		}
+	}
+
+	// <trace-id>/<span-id>;o=<options>
+	if value := get(CloudTraceHeader); value != "" {
+		traceID, rest, _ := strings.Cut(value, "/")
+		spanID, _, _ = strings.Cut(rest, ";")
+		return traceID, spanID
+	}
+
+	// Root=<trace-id>;Parent=<span-id>;Sampled=<flag>
+	if value := get(AmazonTraceHeader); value != "" {
+		for _, part := range strings.Split(value, ";") {
+			key, val, _ := strings.Cut(part, "=")
+			switch key {
+			case "Root":
+				traceID = val
+			case "Parent":
+				spanID = val
+			}
+		}
+		return traceID, spanID
+	}
+
+	if value := get(CorrelationIDHeader); value != "" {
+		return value, ""
+	}
+	return get(XCorrelationIDHeader), ""
+}
+
+// TraceIDFromContext returns the trace ID found in the incoming gRPC metadata of ctx, e.g. to
+// correlate the events published while handling a request.
+func TraceIDFromContext(ctx context.Context) string {
+	traceID, _ := traceFromContext(ctx)
+	return traceID
+}
@@ -7,6 +7,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/NusaCrew/atlas-go/apperror"
+
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -17,17 +19,38 @@ type Tracer struct {
 	methodName  string
 	serviceName string
 	uri         string
+	traceID     string
+	spanID      string
 	fields      map[string]any
+	logger      *Logger
 }
 
 func NewTracer(ctx context.Context, methodName, serviceName string) *Tracer {
+	traceID, spanID := traceFromContext(ctx)
 	return &Tracer{
 		ctx:         ctx,
 		startTime:   time.Now(),
 		methodName:  methodName,
 		serviceName: serviceName,
+		traceID:     traceID,
+		spanID:      spanID,
 		fields:      make(map[string]any),
+		logger:      FromContext(ctx),
+	}
+}
+
+// WithURI sets the uri of the traced request, e.g. the full gRPC method or HTTP path.
+func (t *Tracer) WithURI(uri string) *Tracer {
+	t.uri = uri
+	return t
+}
+
+// WithLogger makes the tracer write to l instead of the logger carried by its context.
+func (t *Tracer) WithLogger(l *Logger) *Tracer {
+	if l != nil {
+		t.logger = l
 	}
+	return t
 }
 
 func (t *Tracer) WithField(key string, value any) *Tracer {
@@ -73,7 +96,17 @@ func (t *Tracer) Panic(code Code, event Event, err error, msg string, args ...an
 }
 
 func (t *Tracer) logWithLevel(severity Severity, code Code, event Event, err error, msg string, args ...any) {
-	duration := time.Since(t.startTime).Milliseconds()
+	elapsed := time.Since(t.startTime)
+	duration := elapsed.Milliseconds()
+
+	t.logger.metricsRecorder().Record(MetricRecord{
+		Service:  t.serviceName,
+		Method:   t.methodName,
+		Code:     code,
+		Event:    event,
+		Severity: severity,
+		Duration: elapsed,
+	})
 
 	if len(args) > 0 {
 		msg = fmt.Sprintf(msg, args...)
@@ -92,37 +125,33 @@ func (t *Tracer) logWithLevel(severity Severity, code Code, event Event, err err
 	}
 
 	logFields := f.toMap()
+	if t.traceID != "" {
+		logFields["trace_id"] = t.traceID
+	}
+	if t.spanID != "" {
+		logFields["span_id"] = t.spanID
+	}
 	maps.Copy(logFields, t.fields)
 
-	if logger == nil {
-		entry := WithFields(logFields)
-		switch severity {
-		case DEBUG:
-			entry.Debug(msg, args...)
-		case INFO:
-			entry.Info(msg, args...)
-		case WARNING:
-			entry.Warning(msg, args...)
-		case ERROR:
-			entry.Error(msg, args...)
-		case ALERT, PANIC:
-			entry.Alert(msg, args...)
-		}
-		return
+	if _, ok := logFields["stacktrace"]; !ok && err != nil && severity >= ERROR && t.logger.stackTraces {
+		logFields["stacktrace"] = captureStack(3)
 	}
 
-	entry := logger.WithFields(logFields)
+	// msg is already formatted, it must not be formatted again by the logger
+	entry := t.logger.WithFields(logFields)
 	switch severity {
 	case DEBUG:
-		entry.Debug(msg, args...)
+		entry.Debug("%s", msg)
 	case INFO:
-		entry.Info(msg, args...)
+		entry.Info("%s", msg)
 	case WARNING:
-		entry.Warning(msg, args...)
+		entry.Warning("%s", msg)
 	case ERROR:
-		entry.Error(msg, args...)
-	case ALERT, PANIC:
-		entry.Alert(msg, args...)
+		entry.Error("%s", msg)
+	case ALERT:
+		entry.Alert("%s", msg)
+	case PANIC:
+		entry.Panic("%s", msg)
 	}
 }
 
@@ -132,9 +161,23 @@ func (t *Tracer) trace(event Event, err error) {
 		return
 	}
 
-	code := determineErrorCode(err)
 	errMsg := err.Error()
 
+	// typed application errors carry their own classification
+	if appErr, ok := apperror.As(err); ok {
+		switch {
+		case appErr.Kind() == apperror.KindNotFound:
+			t.Info(OK, event, errMsg)
+		case appErr.Kind().IsClientError():
+			t.Error(ClientError, event, err, errMsg)
+		default:
+			t.Error(ServerError, event, err, errMsg)
+		}
+		return
+	}
+
+	code := determineErrorCode(err)
+
 	// check for common error patterns
 	errLower := strings.ToLower(errMsg)
 
@@ -0,0 +1,529 @@
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	eo "github.com/NusaCrew/atlas-go/event_observer"
+	"github.com/NusaCrew/atlas-go/log"
+
+	"github.com/google/uuid"
+)
+
+// SagaIDMetadataKey is the event metadata naming the saga instance an event is for, set by
+// Instance.NewEvent on the events of steps and by Reply on the replies to them. Events
+// without it are ignored by the CompleteOn and FailOn subscriptions. It is a valid
+// CloudEvents extension name, so it survives transports using CloudEventsCodec.
+const SagaIDMetadataKey = "sagaid"
+
+var ErrNotWaiting = errors.New("saga instance is not waiting for an event")
+
+// eventRetryPolicy retries the events completing a step received before the step was saved
+// as waiting.
+var eventRetryPolicy = eo.RetryPolicy{MaxAttempts: 5, InitialBackoff: 100 * time.Millisecond}
+
+type CoordinatorConfig struct {
+	Store Store
+	// Observer the StartOn, CompleteOn and FailOn topics are subscribed to, optional when
+	// sagas are only driven by direct calls.
+	Observer *eo.EventObserver
+	// StuckAfter is how long an instance may run or compensate a step before the recovery
+	// worker resumes it, defaults to 5m. It must exceed the time steps take with retries, and
+	// is the Timeout of the subscriptions running steps on StartOn, CompleteOn and FailOn.
+	StuckAfter time.Duration
+	// PollInterval is the wait between recoveries, defaults to 10s.
+	PollInterval time.Duration
+	// BatchSize is the number of stuck instances recovered at once, defaults to 100.
+	BatchSize int
+}
+
+// Coordinator runs the steps of saga instances and their compensations, persisting the
+// instance after every step. Steps run at least once: a step interrupted by a crash is run
+// again by the recovery worker, so actions and compensations must be idempotent.
+type Coordinator struct {
+	config      CoordinatorConfig
+	mu          sync.RWMutex
+	definitions map[string]Definition
+	cancel      context.CancelFunc
+	done        chan struct{}
+	once        sync.Once
+}
+
+func NewCoordinator(config CoordinatorConfig) (*Coordinator, error) {
+	if config.Store == nil {
+		return nil, errors.New("cannot create saga coordinator without store")
+	}
+	if config.StuckAfter <= 0 {
+		config.StuckAfter = 5 * time.Minute
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = 10 * time.Second
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+
+	return &Coordinator{
+		config:      config,
+		definitions: make(map[string]Definition),
+		done:        make(chan struct{}),
+	}, nil
+}
+
+// Register declares a saga, subscribing to its topics when the coordinator has an observer.
+func (c *Coordinator) Register(definition Definition) error {
+	if definition.Name == "" || len(definition.Steps) == 0 {
+		return errors.New("cannot register saga without name or steps")
+	}
+	for _, step := range definition.Steps {
+		if step.Action == nil {
+			return fmt.Errorf("cannot register saga %s: step %s has no action", definition.Name, step.Name)
+		}
+		if step.FailOn != "" && step.CompleteOn == "" {
+			return fmt.Errorf("cannot register saga %s: step %s fails on an event but does not complete on one", definition.Name, step.Name)
+		}
+	}
+
+	c.mu.Lock()
+	if _, ok := c.definitions[definition.Name]; ok {
+		c.mu.Unlock()
+		return fmt.Errorf("saga %s is already registered", definition.Name)
+	}
+	c.definitions[definition.Name] = definition
+	c.mu.Unlock()
+
+	if c.config.Observer == nil {
+		return nil
+	}
+	if definition.StartOn != "" {
+		if err := c.subscribe(definition.StartOn, definition.Name+".start", c.startFromEvent(definition)); err != nil {
+			return err
+		}
+	}
+	topics := make(map[string]bool)
+	for _, step := range definition.Steps {
+		for _, topic := range []string{step.CompleteOn, step.FailOn} {
+			if topic == "" || topics[topic] {
+				continue
+			}
+			topics[topic] = true
+			if err := c.subscribe(topic, definition.Name, c.handleEvent(definition, topic)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Coordinator) subscribe(topic, name string, handler eo.HandlerFunc) error {
+	err := c.config.Observer.Subscribe(topic, eo.Subscriber{
+		TopicName:      topic,
+		SubscriberName: "saga." + name,
+		HandlerFunc:    handler,
+		RetryPolicy:    &eventRetryPolicy,
+		Timeout:        c.config.StuckAfter,
+		// steps publish from within the coordinator, their replies must not be handled inline
+		DeliveryMode: eo.DeliveryAsync,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to subscribe saga %s to %s: %w", name, topic, err)
+	}
+	return nil
+}
+
+func (c *Coordinator) definition(name string) (Definition, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	definition, ok := c.definitions[name]
+	if !ok {
+		return Definition{}, fmt.Errorf("saga %s is not registered", name)
+	}
+	return definition, nil
+}
+
+// Start creates an instance of the saga with data marshalled to JSON, and runs its steps
+// until one waits for an event or the saga ends.
+func (c *Coordinator) Start(ctx context.Context, sagaName string, data any) (*Instance, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal data of saga %s: %w", sagaName, err)
+	}
+	return c.start(ctx, sagaName, uuid.NewString(), raw)
+}
+
+func (c *Coordinator) start(ctx context.Context, sagaName, id string, data json.RawMessage) (*Instance, error) {
+	definition, err := c.definition(sagaName)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	instance := &Instance{
+		ID:          id,
+		SagaName:    sagaName,
+		Status:      StatusRunning,
+		CurrentStep: 0,
+		Data:        data,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := c.config.Store.Create(ctx, instance); err != nil {
+		return nil, err
+	}
+	log.Info("started saga %s %s", sagaName, id)
+	return instance, c.advance(ctx, definition, instance)
+}
+
+func (c *Coordinator) startFromEvent(definition Definition) eo.HandlerFunc {
+	return func(ctx context.Context, event *eo.Event) error {
+		data, err := eventData(event)
+		if err != nil {
+			return err
+		}
+		_, err = c.start(ctx, definition.Name, definition.Name+":"+event.ID, data)
+		if errors.Is(err, ErrAlreadyExists) {
+			return nil
+		}
+		return err
+	}
+}
+
+// IDOf returns the ID of the saga instance event is for, empty when it has none.
+func IDOf(event *eo.Event) string {
+	id, _ := event.Metadata[SagaIDMetadataKey].(string)
+	return id
+}
+
+// Reply returns an event of topic with data, for the saga instance request is for. Services
+// reply with it to the events of steps, so the reply completes or fails the step.
+func Reply(request *eo.Event, topic string, data any) *eo.Event {
+	return newEvent(IDOf(request), topic, data)
+}
+
+func newEvent(id, topic string, data any) *eo.Event {
+	event := &eo.Event{Topic: topic, Data: data}
+	if id != "" {
+		event.Metadata = map[string]any{SagaIDMetadataKey: id}
+	}
+	return event
+}
+
+func eventData(event *eo.Event) (json.RawMessage, error) {
+	switch data := event.Data.(type) {
+	case nil:
+		return nil, nil
+	case json.RawMessage:
+		return data, nil
+	default:
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal data of event %s: %w", event.Topic, err)
+		}
+		return raw, nil
+	}
+}
+
+// Get returns the instance with the given ID.
+func (c *Coordinator) Get(ctx context.Context, id string) (*Instance, error) {
+	return c.config.Store.Get(ctx, id)
+}
+
+// Complete completes the step the instance waits for, with the optional event passed to the
+// step's OnEvent, and runs the next steps.
+func (c *Coordinator) Complete(ctx context.Context, id string, event *eo.Event) error {
+	definition, instance, err := c.waiting(ctx, id)
+	if err != nil {
+		return err
+	}
+	return c.complete(ctx, definition, instance, event)
+}
+
+// Fail fails the step the instance waits for and compensates the steps before it.
+func (c *Coordinator) Fail(ctx context.Context, id string, reason error) error {
+	definition, instance, err := c.waiting(ctx, id)
+	if err != nil {
+		return err
+	}
+	return c.fail(ctx, definition, instance, nil, reason)
+}
+
+func (c *Coordinator) waiting(ctx context.Context, id string) (Definition, *Instance, error) {
+	instance, err := c.config.Store.Get(ctx, id)
+	if err != nil {
+		return Definition{}, nil, err
+	}
+	definition, err := c.definition(instance.SagaName)
+	if err != nil {
+		return Definition{}, nil, err
+	}
+	if instance.Status != StatusWaiting {
+		return Definition{}, nil, fmt.Errorf("saga %s %s is %s: %w", instance.SagaName, id, instance.Status, ErrNotWaiting)
+	}
+	return definition, instance, nil
+}
+
+func (c *Coordinator) handleEvent(definition Definition, topic string) eo.HandlerFunc {
+	return func(ctx context.Context, event *eo.Event) error {
+		id := IDOf(event)
+		if id == "" {
+			return nil
+		}
+
+		instance, err := c.config.Store.Get(ctx, id)
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if instance.SagaName != definition.Name || instance.Status.Terminal() || instance.CurrentStep >= len(definition.Steps) {
+			return nil
+		}
+
+		step := definition.Steps[instance.CurrentStep]
+		if topic != step.CompleteOn && topic != step.FailOn {
+			return nil
+		}
+		if instance.Status == StatusRunning {
+			// the reply arrived before the step was saved as waiting
+			return fmt.Errorf("saga %s %s is still running step %s: %w", definition.Name, id, step.Name, ErrNotWaiting)
+		}
+		if instance.Status != StatusWaiting {
+			return nil
+		}
+
+		if topic == step.CompleteOn {
+			err = c.complete(ctx, definition, instance, event)
+		} else {
+			err = c.fail(ctx, definition, instance, event, fmt.Errorf("received %s", event.Topic))
+		}
+		if errors.Is(err, ErrConcurrentUpdate) {
+			return nil
+		}
+		return err
+	}
+}
+
+func (c *Coordinator) complete(ctx context.Context, definition Definition, instance *Instance, event *eo.Event) error {
+	step := definition.Steps[instance.CurrentStep]
+	if step.OnEvent != nil && event != nil {
+		if err := step.OnEvent(ctx, instance, event); err != nil {
+			return fmt.Errorf("failed to apply event %s to saga %s %s: %w", event.Topic, definition.Name, instance.ID, err)
+		}
+	}
+
+	instance.Status = StatusRunning
+	instance.CurrentStep++
+	instance.Deadline = time.Time{}
+	if err := c.save(ctx, instance); err != nil {
+		return err
+	}
+	return c.advance(ctx, definition, instance)
+}
+
+// fail compensates the steps before the waiting one, whose action failed remotely.
+func (c *Coordinator) fail(ctx context.Context, definition Definition, instance *Instance, event *eo.Event, reason error) error {
+	step := definition.Steps[instance.CurrentStep]
+	if step.OnEvent != nil && event != nil {
+		if err := step.OnEvent(ctx, instance, event); err != nil {
+			return fmt.Errorf("failed to apply event %s to saga %s %s: %w", event.Topic, definition.Name, instance.ID, err)
+		}
+	}
+
+	instance.Status = StatusCompensating
+	instance.Error = fmt.Sprintf("step %s failed: %s", step.Name, reason)
+	instance.CurrentStep--
+	instance.Deadline = time.Time{}
+	if err := c.save(ctx, instance); err != nil {
+		return err
+	}
+	return c.advance(ctx, definition, instance)
+}
+
+// advance runs the steps, or the compensations, of instance from its current step until it
+// waits for an event or ends.
+func (c *Coordinator) advance(ctx context.Context, definition Definition, instance *Instance) error {
+	for {
+		switch instance.Status {
+		case StatusRunning:
+			if instance.CurrentStep >= len(definition.Steps) {
+				instance.Status = StatusCompleted
+				log.Info("completed saga %s %s", definition.Name, instance.ID)
+				return c.save(ctx, instance)
+			}
+
+			step := definition.Steps[instance.CurrentStep]
+			if err := c.runStep(ctx, instance, step, step.Action); err != nil {
+				log.WithError(err).Warning("step %s of saga %s %s failed, compensating", step.Name, definition.Name, instance.ID)
+				instance.Status = StatusCompensating
+				instance.Error = fmt.Sprintf("step %s failed: %s", step.Name, err)
+				instance.CurrentStep--
+			} else if step.CompleteOn != "" {
+				instance.Status = StatusWaiting
+				if step.Timeout > 0 {
+					instance.Deadline = time.Now().Add(step.Timeout)
+				}
+			} else {
+				instance.CurrentStep++
+			}
+		case StatusCompensating:
+			if instance.CurrentStep < 0 {
+				instance.Status = StatusCompensated
+				log.Info("compensated saga %s %s: %s", definition.Name, instance.ID, instance.Error)
+				return c.save(ctx, instance)
+			}
+
+			step := definition.Steps[instance.CurrentStep]
+			if step.Compensate != nil {
+				if err := c.runStep(ctx, instance, step, step.Compensate); err != nil {
+					log.WithError(err).Error("compensation of step %s of saga %s %s failed", step.Name, definition.Name, instance.ID)
+					instance.Status = StatusFailed
+					instance.Error = fmt.Sprintf("%s; compensation of step %s failed: %s", instance.Error, step.Name, err)
+					return c.save(ctx, instance)
+				}
+			}
+			instance.CurrentStep--
+		default:
+			return nil
+		}
+
+		if err := c.save(ctx, instance); err != nil {
+			return err
+		}
+	}
+}
+
+// runStep runs fn with the retry policy and timeout of step.
+func (c *Coordinator) runStep(ctx context.Context, instance *Instance, step Step, fn func(ctx context.Context, saga *Instance) error) error {
+	policy := eo.NoRetry
+	if step.RetryPolicy != nil {
+		policy = *step.RetryPolicy
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := attemptStep(ctx, instance, step, fn)
+		if err == nil || attempt >= max(policy.MaxAttempts, 1) {
+			return err
+		}
+
+		timer := time.NewTimer(policy.Backoff(attempt))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+func attemptStep(ctx context.Context, instance *Instance, step Step, fn func(ctx context.Context, saga *Instance) error) error {
+	if step.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, step.Timeout)
+		defer cancel()
+	}
+	return fn(ctx, instance)
+}
+
+func (c *Coordinator) save(ctx context.Context, instance *Instance) error {
+	instance.UpdatedAt = time.Now()
+	if err := c.config.Store.Update(ctx, instance); err != nil {
+		return fmt.Errorf("failed to save saga %s %s: %w", instance.SagaName, instance.ID, err)
+	}
+	return nil
+}
+
+// Recover resumes the instances stuck in a step, e.g. after a crash, and fails the steps
+// waiting past their timeout with ErrStepTimeout. The outcome of a timed out step is unknown,
+// so it is compensated along the steps before it. It returns how many instances it resumed.
+func (c *Coordinator) Recover(ctx context.Context) (int, error) {
+	now := time.Now()
+	instances, err := c.config.Store.ListStuck(ctx, now, now.Add(-c.config.StuckAfter), c.config.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list stuck sagas: %w", err)
+	}
+
+	var (
+		recovered int
+		errs      []error
+	)
+	for _, instance := range instances {
+		definition, err := c.definition(instance.SagaName)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if instance.Status == StatusWaiting {
+			step := definition.Steps[instance.CurrentStep]
+			log.Warning("step %s of saga %s %s timed out, compensating", step.Name, definition.Name, instance.ID)
+			instance.Status = StatusCompensating
+			instance.Error = fmt.Sprintf("step %s failed: %s", step.Name, ErrStepTimeout)
+			instance.Deadline = time.Time{}
+		} else {
+			log.Warning("resuming saga %s %s stuck at step %d", definition.Name, instance.ID, instance.CurrentStep)
+		}
+
+		// saving first claims the instance, another worker recovering it gets a conflict
+		if err := c.save(ctx, instance); err != nil {
+			if !errors.Is(err, ErrConcurrentUpdate) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if err := c.advance(ctx, definition, instance); err != nil {
+			errs = append(errs, err)
+		}
+		recovered++
+	}
+	return recovered, errors.Join(errs...)
+}
+
+// Run implements webserver.WebServer, running Recover every poll interval along the servers
+// of RunServersCommand.
+func (c *Coordinator) Run(ctx context.Context, errorChannel chan error) {
+	ctx, c.cancel = context.WithCancel(ctx)
+	go func() {
+		defer close(c.done)
+		c.recoverLoop(ctx)
+	}()
+}
+
+func (c *Coordinator) recoverLoop(ctx context.Context) {
+	ticker := time.NewTicker(c.config.PollInterval)
+	defer ticker.Stop()
+
+	log.Info("starting saga recovery worker")
+	for {
+		if _, err := c.Recover(ctx); err != nil && ctx.Err() == nil {
+			log.WithError(err).Error("failed to recover sagas")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Coordinator) GetName() string {
+	return "Saga Coordinator"
+}
+
+// Stop stops the recovery worker and waits for the current recovery to finish.
+func (c *Coordinator) Stop() {
+	c.once.Do(func() {
+		if c.cancel == nil {
+			return
+		}
+		c.cancel()
+		<-c.done
+		log.Info("stopped saga recovery worker")
+	})
+}
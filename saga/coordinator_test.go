@@ -0,0 +1,370 @@
+package saga
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	eo "github.com/NusaCrew/atlas-go/event_observer"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type order struct {
+	ID       string `json:"id"`
+	Reserved bool   `json:"reserved"`
+	Charged  bool   `json:"charged"`
+}
+
+// recorder records the actions and compensations run by steps.
+type recorder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *recorder) step(name string, err error) func(ctx context.Context, saga *Instance) error {
+	return func(ctx context.Context, saga *Instance) error {
+		r.mu.Lock()
+		r.calls = append(r.calls, name)
+		r.mu.Unlock()
+		return err
+	}
+}
+
+func (r *recorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.calls...)
+}
+
+func newTestCoordinator(t *testing.T, observer *eo.EventObserver, definition Definition) *Coordinator {
+	coordinator, err := NewCoordinator(CoordinatorConfig{
+		Store:    NewInMemoryStore(),
+		Observer: observer,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, coordinator.Register(definition))
+	return coordinator
+}
+
+func waitForStatus(t *testing.T, coordinator *Coordinator, id string, status Status) *Instance {
+	var instance *Instance
+	assert.Eventually(t, func() bool {
+		var err error
+		instance, err = coordinator.Get(context.Background(), id)
+		return err == nil && instance.Status == status
+	}, time.Second, 5*time.Millisecond)
+	return instance
+}
+
+func TestCoordinatorRunSteps(t *testing.T) {
+	failure := errors.New("card declined")
+	testCases := []struct {
+		name      string
+		chargeErr error
+		status    Status
+		calls     []string
+	}{
+		{
+			name:   "completed",
+			status: StatusCompleted,
+			calls:  []string{"reserve", "charge", "confirm"},
+		},
+		{
+			name:      "compensated",
+			chargeErr: failure,
+			status:    StatusCompensated,
+			calls:     []string{"reserve", "charge", "charge", "release"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			r := &recorder{}
+			coordinator := newTestCoordinator(t, nil, Definition{
+				Name: "order",
+				Steps: []Step{
+					{
+						Name: "reserve",
+						Action: func(ctx context.Context, saga *Instance) error {
+							var o order
+							assert.NoError(t, saga.Decode(&o))
+							o.Reserved = true
+							assert.NoError(t, saga.Encode(o))
+							return r.step("reserve", nil)(ctx, saga)
+						},
+						Compensate: r.step("release", nil),
+					},
+					{
+						Name:        "charge",
+						Action:      r.step("charge", tc.chargeErr),
+						Compensate:  r.step("refund", nil),
+						RetryPolicy: &eo.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond},
+					},
+					{Name: "confirm", Action: r.step("confirm", nil)},
+				},
+			})
+
+			instance, err := coordinator.Start(context.Background(), "order", order{ID: "order-1"})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.calls, r.get())
+
+			stored, err := coordinator.Get(context.Background(), instance.ID)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.status, stored.Status)
+			var o order
+			assert.NoError(t, stored.Decode(&o))
+			assert.Equal(t, order{ID: "order-1", Reserved: true}, o)
+			if tc.chargeErr != nil {
+				assert.Contains(t, stored.Error, "step charge failed: card declined")
+			}
+		})
+	}
+}
+
+func TestCoordinatorFailedCompensation(t *testing.T) {
+	r := &recorder{}
+	coordinator := newTestCoordinator(t, nil, Definition{
+		Name: "order",
+		Steps: []Step{
+			{Name: "reserve", Action: r.step("reserve", nil), Compensate: r.step("release", errors.New("unavailable"))},
+			{Name: "charge", Action: r.step("charge", errors.New("card declined"))},
+		},
+	})
+
+	instance, err := coordinator.Start(context.Background(), "order", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusFailed, instance.Status)
+	assert.Equal(t, 0, instance.CurrentStep, "the instance stays at the step whose compensation failed")
+	assert.Equal(t, []string{"reserve", "charge", "release"}, r.get())
+}
+
+func TestCoordinatorCompleteOnEvent(t *testing.T) {
+	testCases := []struct {
+		name   string
+		reply  string
+		status Status
+		calls  []string
+	}{
+		{name: "completed", reply: "payment.completed", status: StatusCompleted, calls: []string{"reserve", "confirm"}},
+		{name: "failed", reply: "payment.failed", status: StatusCompensated, calls: []string{"reserve", "release"}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			observer := eo.NewEventObserver("some-service-name")
+			// the payment service replies to the requests of the saga
+			var correlationID string
+			assert.NoError(t, observer.Subscribe("payment.requested", eo.Subscriber{
+				SubscriberName: "payment",
+				HandlerFunc: func(ctx context.Context, event *eo.Event) error {
+					correlationID = event.CorrelationID
+					return observer.Publish(ctx, Reply(event, tc.reply, map[string]any{"payment_id": "payment-1"}))
+				},
+			}))
+
+			r := &recorder{}
+			var paymentID string
+			coordinator := newTestCoordinator(t, observer, Definition{
+				Name: "order",
+				Steps: []Step{
+					{Name: "reserve", Action: r.step("reserve", nil), Compensate: r.step("release", nil)},
+					{
+						Name: "charge",
+						Action: func(ctx context.Context, saga *Instance) error {
+							return observer.Publish(ctx, saga.NewEvent("payment.requested", nil))
+						},
+						CompleteOn: "payment.completed",
+						FailOn:     "payment.failed",
+						OnEvent: func(ctx context.Context, saga *Instance, event *eo.Event) error {
+							paymentID = event.Data.(map[string]any)["payment_id"].(string)
+							return nil
+						},
+					},
+					{Name: "confirm", Action: r.step("confirm", nil)},
+				},
+			})
+
+			instance, err := coordinator.Start(eo.ContextWithCorrelationID(context.Background(), "request-1"), "order", nil)
+			assert.NoError(t, err)
+			waitForStatus(t, coordinator, instance.ID, tc.status)
+			assert.Equal(t, tc.calls, r.get())
+			assert.Equal(t, "payment-1", paymentID)
+			assert.Equal(t, "request-1", correlationID, "steps keep the correlation ID of the request")
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			assert.NoError(t, observer.Close(ctx))
+		})
+	}
+}
+
+func TestCoordinatorComplete(t *testing.T) {
+	r := &recorder{}
+	coordinator := newTestCoordinator(t, nil, Definition{
+		Name: "order",
+		Steps: []Step{
+			{Name: "approve", Action: r.step("request approval", nil), CompleteOn: "order.approved"},
+			{Name: "confirm", Action: r.step("confirm", nil)},
+		},
+	})
+
+	instance, err := coordinator.Start(context.Background(), "order", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusWaiting, instance.Status)
+
+	assert.NoError(t, coordinator.Complete(context.Background(), instance.ID, nil))
+	assert.ErrorIs(t, coordinator.Complete(context.Background(), instance.ID, nil), ErrNotWaiting)
+	assert.ErrorIs(t, coordinator.Complete(context.Background(), "unknown", nil), ErrNotFound)
+
+	stored, err := coordinator.Get(context.Background(), instance.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusCompleted, stored.Status)
+	assert.Equal(t, []string{"request approval", "confirm"}, r.get())
+}
+
+func TestCoordinatorRecover(t *testing.T) {
+	r := &recorder{}
+	coordinator := newTestCoordinator(t, nil, Definition{
+		Name: "order",
+		Steps: []Step{
+			{Name: "reserve", Action: r.step("reserve", nil), Compensate: r.step("release", nil)},
+			{Name: "charge", Action: r.step("charge", nil), Compensate: r.step("refund", nil), CompleteOn: "payment.completed", Timeout: time.Millisecond},
+			{Name: "confirm", Action: r.step("confirm", nil)},
+		},
+	})
+
+	coordinator.config.StuckAfter = time.Millisecond
+
+	timedOut, err := coordinator.Start(context.Background(), "order", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusWaiting, timedOut.Status)
+
+	// an instance left running by a crash
+	now := time.Now()
+	crashed := &Instance{ID: "crashed", SagaName: "order", Status: StatusRunning, CurrentStep: 2, CreatedAt: now, UpdatedAt: now}
+	assert.NoError(t, coordinator.config.Store.Create(context.Background(), crashed))
+
+	time.Sleep(5 * time.Millisecond)
+	recovered, err := coordinator.Recover(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, recovered)
+
+	stored, err := coordinator.Get(context.Background(), timedOut.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusCompensated, stored.Status)
+	assert.Contains(t, stored.Error, ErrStepTimeout.Error())
+
+	stored, err = coordinator.Get(context.Background(), crashed.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusCompleted, stored.Status)
+
+	assert.ElementsMatch(t, []string{"reserve", "charge", "refund", "release", "confirm"}, r.get())
+
+	recovered, err = coordinator.Recover(context.Background())
+	assert.NoError(t, err)
+	assert.Zero(t, recovered)
+}
+
+func TestCoordinatorStartOn(t *testing.T) {
+	observer := eo.NewEventObserver("some-service-name")
+	r := &recorder{}
+	var deadline time.Time
+	coordinator := newTestCoordinator(t, observer, Definition{
+		Name:    "order",
+		StartOn: "order.placed",
+		Steps: []Step{
+			{
+				Name: "reserve",
+				Action: func(ctx context.Context, saga *Instance) error {
+					deadline, _ = ctx.Deadline()
+					var o order
+					if err := saga.Decode(&o); err != nil {
+						return err
+					}
+					return r.step(o.ID, nil)(ctx, saga)
+				},
+			},
+		},
+	})
+
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, observer.Publish(context.Background(), &eo.Event{ID: "event-1", Topic: "order.placed", Data: order{ID: "order-1"}}))
+	}
+	instance := waitForStatus(t, coordinator, "order:event-1", StatusCompleted)
+	assert.Equal(t, "order", instance.SagaName)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, observer.Close(ctx))
+	assert.Equal(t, []string{"order-1"}, r.get(), "the duplicate event starts no instance")
+	assert.WithinDuration(t, time.Now().Add(coordinator.config.StuckAfter), deadline, time.Minute, "steps run within StuckAfter rather than the default handler timeout")
+}
+
+func TestCoordinatorIgnoresUncorrelatedEvents(t *testing.T) {
+	observer := eo.NewEventObserver("some-service-name")
+	coordinator := newTestCoordinator(t, observer, Definition{
+		Name: "order",
+		Steps: []Step{
+			{Name: "approve", Action: func(ctx context.Context, saga *Instance) error { return nil }, CompleteOn: "order.approved"},
+		},
+	})
+
+	instance, err := coordinator.Start(context.Background(), "order", nil)
+	assert.NoError(t, err)
+	// the correlation ID no longer names the saga, only its metadata does
+	assert.NoError(t, observer.Publish(context.Background(), &eo.Event{Topic: "order.approved", CorrelationID: instance.ID}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, observer.Close(ctx))
+
+	stored, err := coordinator.Get(context.Background(), instance.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusWaiting, stored.Status)
+	assert.Equal(t, instance.ID, IDOf(instance.NewEvent("order.shipped", nil)))
+}
+
+func TestCoordinatorRegister(t *testing.T) {
+	coordinator, err := NewCoordinator(CoordinatorConfig{Store: NewInMemoryStore()})
+	assert.NoError(t, err)
+	action := func(ctx context.Context, saga *Instance) error { return nil }
+
+	assert.Error(t, coordinator.Register(Definition{Name: "order"}))
+	assert.Error(t, coordinator.Register(Definition{Name: "order", Steps: []Step{{Name: "reserve"}}}))
+	assert.Error(t, coordinator.Register(Definition{Name: "order", Steps: []Step{{Name: "reserve", Action: action, FailOn: "reserve.failed"}}}))
+	assert.NoError(t, coordinator.Register(Definition{Name: "order", Steps: []Step{{Name: "reserve", Action: action}}}))
+	assert.Error(t, coordinator.Register(Definition{Name: "order", Steps: []Step{{Name: "reserve", Action: action}}}))
+
+	_, err = coordinator.Start(context.Background(), "unknown", nil)
+	assert.Error(t, err)
+}
+
+func TestInMemoryStoreUpdate(t *testing.T) {
+	store := NewInMemoryStore()
+	instance := &Instance{ID: "saga-1", SagaName: "order", Status: StatusRunning}
+	assert.NoError(t, store.Create(context.Background(), instance))
+	assert.ErrorIs(t, store.Create(context.Background(), instance), ErrAlreadyExists)
+
+	first, err := store.Get(context.Background(), "saga-1")
+	assert.NoError(t, err)
+	second, err := store.Get(context.Background(), "saga-1")
+	assert.NoError(t, err)
+
+	first.Status = StatusCompleted
+	assert.NoError(t, store.Update(context.Background(), first))
+	assert.Equal(t, int64(2), first.Version)
+	second.Status = StatusCompensating
+	assert.ErrorIs(t, store.Update(context.Background(), second), ErrConcurrentUpdate)
+
+	stored, err := store.Get(context.Background(), "saga-1")
+	assert.NoError(t, err)
+	assert.Equal(t, StatusCompleted, stored.Status)
+
+	_, err = store.Get(context.Background(), "unknown")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
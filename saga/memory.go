@@ -0,0 +1,84 @@
+package saga
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+type inMemoryStore struct {
+	mu        sync.Mutex
+	instances map[string]*Instance
+}
+
+// NewInMemoryStore returns a Store losing its content on restart, meant for tests.
+func NewInMemoryStore() Store {
+	return &inMemoryStore{
+		instances: make(map[string]*Instance),
+	}
+}
+
+func (s *inMemoryStore) Create(ctx context.Context, instance *Instance) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.instances[instance.ID]; ok {
+		return ErrAlreadyExists
+	}
+	instance.Version = 1
+	stored := *instance
+	s.instances[instance.ID] = &stored
+	return nil
+}
+
+func (s *inMemoryStore) Get(ctx context.Context, id string) (*Instance, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	instance, ok := s.instances[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *instance
+	return &copied, nil
+}
+
+func (s *inMemoryStore) Update(ctx context.Context, instance *Instance) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.instances[instance.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if stored.Version != instance.Version {
+		return ErrConcurrentUpdate
+	}
+	instance.Version++
+	updated := *instance
+	s.instances[instance.ID] = &updated
+	return nil
+}
+
+func (s *inMemoryStore) ListStuck(ctx context.Context, now, stuckBefore time.Time, limit int) ([]*Instance, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var stuck []*Instance
+	for _, instance := range s.instances {
+		running := (instance.Status == StatusRunning || instance.Status == StatusCompensating) && instance.UpdatedAt.Before(stuckBefore)
+		timedOut := instance.Status == StatusWaiting && !instance.Deadline.IsZero() && instance.Deadline.Before(now)
+		if running || timedOut {
+			copied := *instance
+			stuck = append(stuck, &copied)
+		}
+	}
+	slices.SortFunc(stuck, func(a, b *Instance) int {
+		return a.UpdatedAt.Compare(b.UpdatedAt)
+	})
+	if len(stuck) > limit {
+		stuck = stuck[:limit]
+	}
+	return stuck, nil
+}
@@ -0,0 +1,16 @@
+package saga
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// MigrationsTable records the versions of Migrations, apart from the migrations of the service.
+const MigrationsTable = "saga_schema_migrations"
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// Migrations create the tables of the PostgreSQL store, to run with postgres.RunMigrations and
+// MigrationsTable.
+var Migrations, _ = fs.Sub(migrations, "migrations")
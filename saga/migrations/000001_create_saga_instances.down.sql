@@ -0,0 +1 @@
+DROP TABLE IF EXISTS saga_instances;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS saga_instances (
+    id           TEXT PRIMARY KEY,
+    saga_name    TEXT        NOT NULL,
+    status       TEXT        NOT NULL,
+    current_step INTEGER     NOT NULL,
+    data         JSONB,
+    error        TEXT        NOT NULL DEFAULT '',
+    deadline     TIMESTAMPTZ,
+    version      BIGINT      NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL,
+    updated_at   TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_saga_instances_status_updated_at ON saga_instances (status, updated_at);
@@ -0,0 +1,166 @@
+package saga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/NusaCrew/atlas-go/storage/mongo"
+
+	"go.mongodb.org/mongo-driver/bson"
+	mongodriver "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const DefaultCollection = "saga_instances"
+
+type mongoInstance struct {
+	ID          string    `bson:"_id"`
+	SagaName    string    `bson:"saga_name"`
+	Status      string    `bson:"status"`
+	CurrentStep int       `bson:"current_step"`
+	Data        []byte    `bson:"data"`
+	Error       string    `bson:"error,omitempty"`
+	Deadline    time.Time `bson:"deadline,omitempty"`
+	Version     int64     `bson:"version"`
+	CreatedAt   time.Time `bson:"created_at"`
+	UpdatedAt   time.Time `bson:"updated_at"`
+}
+
+func (m mongoInstance) instance() *Instance {
+	return &Instance{
+		ID:          m.ID,
+		SagaName:    m.SagaName,
+		Status:      parseStatus(m.Status),
+		CurrentStep: m.CurrentStep,
+		Data:        m.Data,
+		Error:       m.Error,
+		Deadline:    m.Deadline,
+		Version:     m.Version,
+		CreatedAt:   m.CreatedAt,
+		UpdatedAt:   m.UpdatedAt,
+	}
+}
+
+func newMongoInstance(instance *Instance) mongoInstance {
+	return mongoInstance{
+		ID:          instance.ID,
+		SagaName:    instance.SagaName,
+		Status:      instance.Status.String(),
+		CurrentStep: instance.CurrentStep,
+		Data:        instance.Data,
+		Error:       instance.Error,
+		Deadline:    instance.Deadline,
+		Version:     instance.Version,
+		CreatedAt:   instance.CreatedAt,
+		UpdatedAt:   instance.UpdatedAt,
+	}
+}
+
+type mongoStore struct {
+	mongo.CommonRepository
+	collection string
+}
+
+// NewMongoStore returns a Store backed by the given collection.
+func NewMongoStore(storage mongo.Storage, collection string) Store {
+	if collection == "" {
+		collection = DefaultCollection
+	}
+	return &mongoStore{
+		CommonRepository: mongo.CommonRepository{Storage: storage},
+		collection:       collection,
+	}
+}
+
+func (s *mongoStore) Create(ctx context.Context, instance *Instance) error {
+	doc := newMongoInstance(instance)
+	doc.Version = 1
+	_, err := s.GetCollection(s.collection).InsertOne(ctx, doc)
+	if mongodriver.IsDuplicateKeyError(err) {
+		return ErrAlreadyExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create saga %s %s: %w", instance.SagaName, instance.ID, err)
+	}
+	instance.Version = 1
+	return nil
+}
+
+func (s *mongoStore) Get(ctx context.Context, id string) (*Instance, error) {
+	var doc mongoInstance
+	err := s.GetCollection(s.collection).FindOne(ctx, bson.M{"_id": id}).Decode(&doc)
+	if errors.Is(err, mongodriver.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saga %s: %w", id, err)
+	}
+	return doc.instance(), nil
+}
+
+// updateFilter matches instance when it still has the version it was read with.
+func updateFilter(instance *Instance) bson.M {
+	return bson.M{"_id": instance.ID, "version": instance.Version}
+}
+
+func updateDocument(instance *Instance) bson.M {
+	return bson.M{"$set": bson.M{
+		"status":       instance.Status.String(),
+		"current_step": instance.CurrentStep,
+		"data":         []byte(instance.Data),
+		"error":        instance.Error,
+		"deadline":     instance.Deadline,
+		"version":      instance.Version + 1,
+		"updated_at":   instance.UpdatedAt,
+	}}
+}
+
+func (s *mongoStore) Update(ctx context.Context, instance *Instance) error {
+	result, err := s.GetCollection(s.collection).UpdateOne(ctx, updateFilter(instance), updateDocument(instance))
+	if err != nil {
+		return fmt.Errorf("failed to update saga %s %s: %w", instance.SagaName, instance.ID, err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrConcurrentUpdate
+	}
+	instance.Version++
+	return nil
+}
+
+// stuckFilter matches the instances running or compensating since before stuckBefore, or
+// waiting past their deadline at now.
+func stuckFilter(now, stuckBefore time.Time) bson.M {
+	return bson.M{"$or": bson.A{
+		bson.M{
+			"status":     bson.M{"$in": bson.A{StatusRunning.String(), StatusCompensating.String()}},
+			"updated_at": bson.M{"$lt": stuckBefore},
+		},
+		bson.M{
+			"status":   StatusWaiting.String(),
+			"deadline": bson.M{"$gt": time.Time{}, "$lt": now},
+		},
+	}}
+}
+
+func (s *mongoStore) ListStuck(ctx context.Context, now, stuckBefore time.Time, limit int) ([]*Instance, error) {
+	filter := stuckFilter(now, stuckBefore)
+	opts := options.Find().SetSort(bson.D{{Key: "updated_at", Value: 1}}).SetLimit(int64(limit))
+
+	cursor, err := s.GetCollection(s.collection).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stuck sagas: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var instances []*Instance
+	for cursor.Next(ctx) {
+		var doc mongoInstance
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		instances = append(instances, doc.instance())
+	}
+	return instances, cursor.Err()
+}
@@ -0,0 +1,71 @@
+package saga
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestMongoInstance(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	testCases := []struct {
+		name     string
+		instance *Instance
+	}{
+		{
+			name:     "running",
+			instance: &Instance{ID: "saga-1", SagaName: "order", Status: StatusRunning, Data: json.RawMessage(`{"id":"order-1"}`), Version: 1, CreatedAt: now, UpdatedAt: now},
+		},
+		{
+			name:     "waiting",
+			instance: &Instance{ID: "saga-2", SagaName: "order", Status: StatusWaiting, CurrentStep: 1, Deadline: now.Add(time.Minute), Version: 2, CreatedAt: now, UpdatedAt: now},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			raw, err := bson.Marshal(newMongoInstance(tc.instance))
+			assert.NoError(t, err)
+
+			var doc mongoInstance
+			assert.NoError(t, bson.Unmarshal(raw, &doc))
+			assert.Equal(t, tc.instance, doc.instance())
+		})
+	}
+}
+
+func TestMongoStoreUpdate(t *testing.T) {
+	now := time.Now()
+	instance := &Instance{ID: "saga-1", Status: StatusCompleted, CurrentStep: 2, Version: 3, UpdatedAt: now}
+
+	assert.Equal(t, bson.M{"_id": "saga-1", "version": int64(3)}, updateFilter(instance))
+	assert.Equal(t, bson.M{"$set": bson.M{
+		"status":       "COMPLETED",
+		"current_step": 2,
+		"data":         []byte(nil),
+		"error":        "",
+		"deadline":     time.Time{},
+		"version":      int64(4),
+		"updated_at":   now,
+	}}, updateDocument(instance))
+}
+
+func TestMongoStoreStuckFilter(t *testing.T) {
+	now := time.Now()
+	stuckBefore := now.Add(-time.Minute)
+
+	assert.Equal(t, bson.M{"$or": bson.A{
+		bson.M{
+			"status":     bson.M{"$in": bson.A{"RUNNING", "COMPENSATING"}},
+			"updated_at": bson.M{"$lt": stuckBefore},
+		},
+		bson.M{
+			"status":   "WAITING",
+			"deadline": bson.M{"$gt": time.Time{}, "$lt": now},
+		},
+	}}, stuckFilter(now, stuckBefore))
+}
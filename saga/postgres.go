@@ -0,0 +1,155 @@
+package saga
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/NusaCrew/atlas-go/storage/postgres"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+)
+
+const (
+	DefaultTable = "saga_instances"
+
+	uniqueViolation = "23505"
+)
+
+type postgresStore struct {
+	postgres.CommonRepository
+	table string
+}
+
+// NewPostgresStore returns a Store backed by the given table, created by
+// saga/migrations/000001_create_saga_instances.up.sql.
+func NewPostgresStore(storage postgres.Storage, table string) Store {
+	if table == "" {
+		table = DefaultTable
+	}
+	return &postgresStore{
+		CommonRepository: postgres.CommonRepository{Storage: storage},
+		table:            table,
+	}
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+func (s *postgresStore) createQuery(instance *Instance) sq.InsertBuilder {
+	return s.Builder(nil).
+		Insert(s.table).
+		Columns("id", "saga_name", "status", "current_step", "data", "error", "deadline", "version", "created_at", "updated_at").
+		Values(instance.ID, instance.SagaName, instance.Status.String(), instance.CurrentStep, []byte(instance.Data), instance.Error, nullTime(instance.Deadline), 1, instance.CreatedAt, instance.UpdatedAt)
+}
+
+func (s *postgresStore) Create(ctx context.Context, instance *Instance) error {
+	_, err := s.createQuery(instance).ExecContext(ctx)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return ErrAlreadyExists
+		}
+		return fmt.Errorf("failed to create saga %s %s: %w", instance.SagaName, instance.ID, err)
+	}
+	instance.Version = 1
+	return nil
+}
+
+func (s *postgresStore) selectBuilder() sq.SelectBuilder {
+	return s.Builder(nil).
+		Select("id", "saga_name", "status", "current_step", "data", "error", "deadline", "version", "created_at", "updated_at").
+		From(s.table)
+}
+
+func (s *postgresStore) Get(ctx context.Context, id string) (*Instance, error) {
+	instance, err := scanInstance(s.selectBuilder().Where(sq.Eq{"id": id}).QueryRowContext(ctx))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saga %s: %w", id, err)
+	}
+	return instance, nil
+}
+
+// updateQuery updates instance when it still has the version it was read with.
+func (s *postgresStore) updateQuery(instance *Instance) sq.UpdateBuilder {
+	return s.Builder(nil).
+		Update(s.table).
+		Set("status", instance.Status.String()).
+		Set("current_step", instance.CurrentStep).
+		Set("data", []byte(instance.Data)).
+		Set("error", instance.Error).
+		Set("deadline", nullTime(instance.Deadline)).
+		Set("version", instance.Version+1).
+		Set("updated_at", instance.UpdatedAt).
+		Where(sq.Eq{"id": instance.ID, "version": instance.Version})
+}
+
+func (s *postgresStore) Update(ctx context.Context, instance *Instance) error {
+	result, err := s.updateQuery(instance).ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to update saga %s %s: %w", instance.SagaName, instance.ID, err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrConcurrentUpdate
+	}
+	instance.Version++
+	return nil
+}
+
+func (s *postgresStore) listStuckQuery(now, stuckBefore time.Time, limit int) sq.SelectBuilder {
+	return s.selectBuilder().
+		Where(sq.Or{
+			sq.And{
+				sq.Eq{"status": []string{StatusRunning.String(), StatusCompensating.String()}},
+				sq.Lt{"updated_at": stuckBefore},
+			},
+			sq.And{
+				sq.Eq{"status": StatusWaiting.String()},
+				sq.Lt{"deadline": now},
+			},
+		}).
+		OrderBy("updated_at").
+		Limit(uint64(limit))
+}
+
+func (s *postgresStore) ListStuck(ctx context.Context, now, stuckBefore time.Time, limit int) ([]*Instance, error) {
+	rows, err := s.listStuckQuery(now, stuckBefore, limit).QueryContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stuck sagas: %w", err)
+	}
+	defer rows.Close()
+
+	var instances []*Instance
+	for rows.Next() {
+		instance, err := scanInstance(rows)
+		if err != nil {
+			return nil, err
+		}
+		instances = append(instances, instance)
+	}
+	return instances, rows.Err()
+}
+
+func scanInstance(row sq.RowScanner) (*Instance, error) {
+	var (
+		instance Instance
+		status   string
+		data     []byte
+		deadline sql.NullTime
+	)
+	err := row.Scan(&instance.ID, &instance.SagaName, &status, &instance.CurrentStep, &data, &instance.Error, &deadline, &instance.Version, &instance.CreatedAt, &instance.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	instance.Status = parseStatus(status)
+	instance.Data = data
+	instance.Deadline = deadline.Time
+	return &instance, nil
+}
@@ -0,0 +1,90 @@
+package saga
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeStorage struct{}
+
+func (fakeStorage) DB() *sql.DB                    { return nil }
+func (fakeStorage) Ping(ctx context.Context) error { return nil }
+func (fakeStorage) Close() error                   { return nil }
+
+func TestPostgresStoreCreateQuery(t *testing.T) {
+	store := NewPostgresStore(fakeStorage{}, "").(*postgresStore)
+	now := time.Now()
+	instance := &Instance{ID: "saga-1", SagaName: "order", Status: StatusRunning, Data: json.RawMessage(`{}`), CreatedAt: now, UpdatedAt: now}
+
+	query, args, err := store.createQuery(instance).ToSql()
+	assert.NoError(t, err)
+	assert.Equal(t, "INSERT INTO saga_instances (id,saga_name,status,current_step,data,error,deadline,version,created_at,updated_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)", query)
+	assert.Equal(t, []any{"saga-1", "order", "RUNNING", 0, []byte(`{}`), "", sql.NullTime{}, 1, now, now}, args)
+}
+
+func TestPostgresStoreUpdateQuery(t *testing.T) {
+	store := NewPostgresStore(fakeStorage{}, "sagas").(*postgresStore)
+	now := time.Now()
+	deadline := now.Add(time.Minute)
+	instance := &Instance{ID: "saga-1", Status: StatusWaiting, CurrentStep: 1, Error: "", Deadline: deadline, Version: 3, UpdatedAt: now}
+
+	query, args, err := store.updateQuery(instance).ToSql()
+	assert.NoError(t, err)
+	assert.Equal(t, "UPDATE sagas SET status = $1, current_step = $2, data = $3, error = $4, deadline = $5, version = $6, updated_at = $7 WHERE id = $8 AND version = $9", query)
+	assert.Equal(t, []any{"WAITING", 1, []byte(nil), "", sql.NullTime{Time: deadline, Valid: true}, int64(4), now, "saga-1", int64(3)}, args)
+}
+
+func TestPostgresStoreListStuckQuery(t *testing.T) {
+	store := NewPostgresStore(fakeStorage{}, "").(*postgresStore)
+	now := time.Now()
+	stuckBefore := now.Add(-time.Minute)
+
+	query, args, err := store.listStuckQuery(now, stuckBefore, 10).ToSql()
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT id, saga_name, status, current_step, data, error, deadline, version, created_at, updated_at FROM saga_instances WHERE ((status IN ($1,$2) AND updated_at < $3) OR (status = $4 AND deadline < $5)) ORDER BY updated_at LIMIT 10", query)
+	assert.Equal(t, []any{"RUNNING", "COMPENSATING", stuckBefore, "WAITING", now}, args)
+}
+
+type fakeRow []any
+
+func (r fakeRow) Scan(dest ...any) error {
+	for i, value := range r {
+		switch d := dest[i].(type) {
+		case *sql.NullTime:
+			*d = value.(sql.NullTime)
+		case *string:
+			*d = value.(string)
+		case *int:
+			*d = value.(int)
+		case *int64:
+			*d = value.(int64)
+		case *[]byte:
+			*d = value.([]byte)
+		case *time.Time:
+			*d = value.(time.Time)
+		}
+	}
+	return nil
+}
+
+func TestScanInstance(t *testing.T) {
+	now := time.Now()
+	instance, err := scanInstance(fakeRow{"saga-1", "order", "COMPENSATING", 2, []byte(`{"id":"order-1"}`), "step charge failed", sql.NullTime{}, int64(5), now, now})
+	assert.NoError(t, err)
+	assert.Equal(t, &Instance{
+		ID:          "saga-1",
+		SagaName:    "order",
+		Status:      StatusCompensating,
+		CurrentStep: 2,
+		Data:        json.RawMessage(`{"id":"order-1"}`),
+		Error:       "step charge failed",
+		Version:     5,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}, instance)
+}
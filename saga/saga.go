@@ -0,0 +1,146 @@
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	eo "github.com/NusaCrew/atlas-go/event_observer"
+)
+
+// --------------- ENUMERATIONS ---------------
+
+// Status is the state of a saga instance.
+type Status int
+
+const (
+	StatusRunning      Status = iota + 1 // running the action of the current step
+	StatusWaiting                        // waiting for the event completing the current step
+	StatusCompleted                      // every step succeeded
+	StatusCompensating                   // running the compensations of the succeeded steps, last first
+	StatusCompensated                    // a step failed and every compensation succeeded
+	StatusFailed                         // a compensation failed, the instance needs a manual fix
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusRunning:
+		return "RUNNING"
+	case StatusWaiting:
+		return "WAITING"
+	case StatusCompleted:
+		return "COMPLETED"
+	case StatusCompensating:
+		return "COMPENSATING"
+	case StatusCompensated:
+		return "COMPENSATED"
+	case StatusFailed:
+		return "FAILED"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+func parseStatus(s string) Status {
+	for status := StatusRunning; status <= StatusFailed; status++ {
+		if status.String() == s {
+			return status
+		}
+	}
+	return 0
+}
+
+// Terminal reports whether the instance is done.
+func (s Status) Terminal() bool {
+	return s == StatusCompleted || s == StatusCompensated || s == StatusFailed
+}
+
+// --------------- SAGA ---------------
+
+var (
+	ErrNotFound         = errors.New("saga instance not found")
+	ErrAlreadyExists    = errors.New("saga instance already exists")
+	ErrConcurrentUpdate = errors.New("saga instance was updated concurrently")
+	ErrStepTimeout      = errors.New("saga step timed out")
+)
+
+// Step is an action of a saga and the compensation undoing it once a later step failed.
+type Step struct {
+	Name   string
+	Action func(ctx context.Context, saga *Instance) error
+	// Compensate undoes Action, optional for steps with nothing to undo.
+	Compensate func(ctx context.Context, saga *Instance) error
+	// CompleteOn is the topic of the event completing the step, e.g. the reply to a command
+	// published by Action with Instance.NewEvent and replied to with Reply. The step then
+	// waits for it, or for Complete, instead of completing when Action returns.
+	CompleteOn string
+	// FailOn is the topic of the event failing a step waiting for CompleteOn.
+	FailOn string
+	// OnEvent merges the event completing or failing the step into the saga data, optional.
+	OnEvent func(ctx context.Context, saga *Instance, event *eo.Event) error
+	// Timeout bounds every attempt of Action and Compensate, and the wait for CompleteOn.
+	Timeout time.Duration
+	// RetryPolicy of Action and Compensate, defaults to no retry.
+	RetryPolicy *eo.RetryPolicy
+}
+
+// Definition declares the steps of a saga, run in order.
+type Definition struct {
+	Name  string
+	Steps []Step
+	// StartOn is the topic of the events starting an instance with their data, optional.
+	// Instances started by the same event share an ID, so duplicates start a single one.
+	StartOn string
+}
+
+// Instance is the persisted state of a run of a saga.
+type Instance struct {
+	ID          string
+	SagaName    string
+	Status      Status
+	CurrentStep int
+	Data        json.RawMessage
+	Error       string
+	Deadline    time.Time // of the wait for the event completing the current step
+	Version     int64     // incremented by every update, to detect concurrent ones
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Decode unmarshals the data of the saga into v.
+func (i *Instance) Decode(v any) error {
+	if err := json.Unmarshal(i.Data, v); err != nil {
+		return fmt.Errorf("failed to unmarshal data of saga %s: %w", i.ID, err)
+	}
+	return nil
+}
+
+// Encode replaces the data of the saga with v marshalled to JSON, persisted with the step.
+func (i *Instance) Encode(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal data of saga %s: %w", i.ID, err)
+	}
+	i.Data = data
+	return nil
+}
+
+// NewEvent returns an event of topic with data for the saga, to be published by a step whose
+// reply completes it. The event keeps the correlation ID of the context it is published with.
+func (i *Instance) NewEvent(topic string, data any) *eo.Event {
+	return newEvent(i.ID, topic, data)
+}
+
+// Store persists saga instances.
+type Store interface {
+	Create(ctx context.Context, instance *Instance) error
+	Get(ctx context.Context, id string) (*Instance, error)
+	// Update saves instance when its Version is the stored one, and increments it, or returns
+	// ErrConcurrentUpdate.
+	Update(ctx context.Context, instance *Instance) error
+	// ListStuck returns up to limit instances running or compensating since before
+	// stuckBefore, or waiting past their deadline at now.
+	ListStuck(ctx context.Context, now, stuckBefore time.Time, limit int) ([]*Instance, error)
+}
@@ -0,0 +1,60 @@
+package postgres_test
+
+import (
+	"io/fs"
+	"testing"
+
+	eo "github.com/NusaCrew/atlas-go/event_observer"
+	"github.com/NusaCrew/atlas-go/event_observer/webhook"
+	es "github.com/NusaCrew/atlas-go/event_store"
+	"github.com/NusaCrew/atlas-go/saga"
+
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLibraryMigrations(t *testing.T) {
+	testCases := []struct {
+		name       string
+		table      string
+		migrations fs.FS
+		versions   int
+	}{
+		{name: "event_observer", table: eo.MigrationsTable, migrations: eo.Migrations, versions: 4},
+		{name: "webhook", table: webhook.MigrationsTable, migrations: webhook.Migrations, versions: 1},
+		{name: "event_store", table: es.MigrationsTable, migrations: es.Migrations, versions: 1},
+		{name: "saga", table: saga.MigrationsTable, migrations: saga.Migrations, versions: 1},
+	}
+
+	tables := map[string]bool{}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			assert.False(t, tables[tc.table], "every package has its own migrations table")
+			tables[tc.table] = true
+
+			source, err := iofs.New(tc.migrations, ".")
+			assert.NoError(t, err)
+			version, err := source.First()
+			assert.NoError(t, err)
+			assert.Equal(t, uint(1), version, "versions start at 1 in their own table")
+
+			for i := 1; i <= tc.versions; i++ {
+				up, _, err := source.ReadUp(version)
+				if assert.NoError(t, err) {
+					up.Close()
+				}
+				down, _, err := source.ReadDown(version)
+				if assert.NoError(t, err) {
+					down.Close()
+				}
+				if i < tc.versions {
+					version, err = source.Next(version)
+					assert.NoError(t, err)
+				}
+			}
+			_, err = source.Next(version)
+			assert.ErrorIs(t, err, fs.ErrNotExist, "no more than %d versions", tc.versions)
+		})
+	}
+}
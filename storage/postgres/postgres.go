@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"io/fs"
 	"os"
 	"strconv"
 	"strings"
@@ -14,6 +15,7 @@ import (
 	"github.com/golang-migrate/migrate/v4"
 	psqlMigrator "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
 	_ "github.com/lib/pq"
 )
 
@@ -110,6 +112,32 @@ func (c *client) migrateDatabase(path string) error {
 	return nil
 }
 
+// RunMigrations applies the migrations of a package of this library, e.g. saga.Migrations,
+// recording their versions in table rather than in the schema_migrations of the service, so
+// both version sequences start at 1 without colliding.
+func RunMigrations(storage Storage, table string, migrations fs.FS) error {
+	source, err := iofs.New(migrations, ".")
+	if err != nil {
+		return fmt.Errorf("migration source creation failed: %w", err)
+	}
+
+	driver, err := psqlMigrator.WithInstance(storage.DB(), &psqlMigrator.Config{MigrationsTable: table})
+	if err != nil {
+		return fmt.Errorf("migration driver creation failed: %w", err)
+	}
+
+	migrator, err := migrate.NewWithInstance("iofs", source, table, driver)
+	if err != nil {
+		return fmt.Errorf("migration instance creation failed: %w", err)
+	}
+
+	if err = migrator.Up(); err != nil && err != migrate.ErrNoChange {
+		return fmt.Errorf("migration of %s failed: %w", table, err)
+	}
+
+	return nil
+}
+
 func getLatestMigrationVersion(path string) uint {
 	files, err := os.ReadDir(path)
 	if err != nil {
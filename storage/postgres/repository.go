@@ -0,0 +1,428 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+const (
+	createdAtColumn = "created_at"
+	updatedAtColumn = "updated_at"
+	deletedAtColumn = "deleted_at"
+
+	generatedTagOption = "generated"
+)
+
+var (
+	ErrNotFound       = errors.New("record not found")
+	ErrInvalidOrderBy = errors.New("invalid order by")
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	timePtrType  = reflect.TypeOf(&time.Time{})
+	nullTimeType = reflect.TypeOf(sql.NullTime{})
+)
+
+type RepositoryConfig struct {
+	Table string
+	// PrimaryKey is the column identifying rows, defaults to "id".
+	PrimaryKey string
+	// SoftDelete makes Delete set the deleted_at column, a *time.Time or sql.NullTime field,
+	// instead of deleting the row. Deleted rows are then ignored but by FindMany WithDeleted.
+	SoftDelete bool
+}
+
+// FindOptions narrows the rows returned by FindMany.
+type FindOptions struct {
+	OrderBy     []string // mapped columns optionally followed by ASC or DESC, e.g. "created_at DESC"
+	Limit       uint64
+	Offset      uint64
+	WithDeleted bool // includes soft deleted rows
+}
+
+type column struct {
+	name      string
+	index     []int
+	generated bool // omitted from inserts when zero, for the database default to apply, and from updates
+}
+
+// Repository implements the CRUD operations of the table of T, whose fields are mapped to
+// columns by their db tag, e.g. `db:"id,generated"`. Fields without tag are ignored, and
+// untagged embedded structs are flattened. The created_at and updated_at columns, when
+// mapped to a time.Time, *time.Time or sql.NullTime, are set on write, generated columns
+// are only written by inserts.
+// Methods take the transaction of RunInSQLTransaction, or nil to run outside of one.
+type Repository[T any] struct {
+	CommonRepository
+	config  RepositoryConfig
+	columns []column
+	byName  map[string]column
+}
+
+func NewRepository[T any](storage Storage, config RepositoryConfig) (*Repository[T], error) {
+	if config.Table == "" {
+		return nil, errors.New("cannot create repository without table")
+	}
+	if config.PrimaryKey == "" {
+		config.PrimaryKey = "id"
+	}
+
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot create repository of %s: not a struct", t)
+	}
+	r := &Repository[T]{
+		CommonRepository: CommonRepository{Storage: storage},
+		config:           config,
+		byName:           make(map[string]column),
+	}
+	if err := r.mapColumns(t, nil); err != nil {
+		return nil, fmt.Errorf("cannot create repository of %s: %w", t, err)
+	}
+
+	if _, ok := r.byName[config.PrimaryKey]; !ok {
+		return nil, fmt.Errorf("cannot create repository of %s: no field mapped to primary key %s", t, config.PrimaryKey)
+	}
+	if config.SoftDelete {
+		deletedAt, ok := r.byName[deletedAtColumn]
+		if !ok {
+			return nil, fmt.Errorf("cannot create repository of %s: soft delete requires a %s field", t, deletedAtColumn)
+		}
+		if fieldType := t.FieldByIndex(deletedAt.index).Type; fieldType != timePtrType && fieldType != nullTimeType {
+			return nil, fmt.Errorf("cannot create repository of %s: %s must be a *time.Time or sql.NullTime", t, deletedAtColumn)
+		}
+	}
+	return r, nil
+}
+
+func (r *Repository[T]) mapColumns(t reflect.Type, index []int) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldIndex := append(append([]int{}, index...), i)
+
+		tag, ok := field.Tag.Lookup("db")
+		if !ok {
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				if err := r.mapColumns(field.Type, fieldIndex); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			return fmt.Errorf("field %s has an empty db tag", field.Name)
+		}
+		if _, ok := r.byName[name]; ok {
+			return fmt.Errorf("column %s is mapped twice", name)
+		}
+
+		c := column{name: name, index: fieldIndex, generated: options == generatedTagOption}
+		r.columns = append(r.columns, c)
+		r.byName[name] = c
+	}
+	return nil
+}
+
+func (r *Repository[T]) columnNames() []string {
+	names := make([]string, len(r.columns))
+	for i, c := range r.columns {
+		names[i] = c.name
+	}
+	return names
+}
+
+// fields returns pointers to the fields of entity in the order of the columns, to scan rows.
+func (r *Repository[T]) fields(entity *T) []any {
+	value := reflect.ValueOf(entity).Elem()
+	fields := make([]any, len(r.columns))
+	for i, c := range r.columns {
+		fields[i] = value.FieldByIndex(c.index).Addr().Interface()
+	}
+	return fields
+}
+
+func (r *Repository[T]) field(entity *T, name string) (reflect.Value, bool) {
+	c, ok := r.byName[name]
+	if !ok {
+		return reflect.Value{}, false
+	}
+	return reflect.ValueOf(entity).Elem().FieldByIndex(c.index), true
+}
+
+// setTime sets the timestamp column name of entity to t, if mapped to a time field and,
+// unless overwrite, zero.
+func (r *Repository[T]) setTime(entity *T, name string, t time.Time, overwrite bool) {
+	field, ok := r.field(entity, name)
+	if !ok || (!overwrite && !field.IsZero()) {
+		return
+	}
+	switch field.Type() {
+	case timeType:
+		field.Set(reflect.ValueOf(t))
+	case timePtrType:
+		field.Set(reflect.ValueOf(&t))
+	case nullTimeType:
+		field.Set(reflect.ValueOf(sql.NullTime{Time: t, Valid: true}))
+	}
+}
+
+// values returns the columns and values inserted for entity.
+func (r *Repository[T]) values(entity *T) ([]string, []any) {
+	value := reflect.ValueOf(entity).Elem()
+	var (
+		columns []string
+		values  []any
+	)
+	for _, c := range r.columns {
+		field := value.FieldByIndex(c.index)
+		if c.generated && field.IsZero() {
+			continue
+		}
+		columns = append(columns, c.name)
+		values = append(values, field.Interface())
+	}
+	return columns, values
+}
+
+// updatable reports whether Update and Upsert write c.
+func (r *Repository[T]) updatable(c column) bool {
+	return !c.generated && c.name != r.config.PrimaryKey && c.name != createdAtColumn && c.name != deletedAtColumn
+}
+
+// notDeleted returns the condition excluding soft deleted rows, nil without soft delete.
+func (r *Repository[T]) notDeleted() sq.Sqlizer {
+	if !r.config.SoftDelete {
+		return nil
+	}
+	return sq.Eq{deletedAtColumn: nil}
+}
+
+func (r *Repository[T]) returning() string {
+	return "RETURNING " + strings.Join(r.columnNames(), ", ")
+}
+
+func (r *Repository[T]) insertQuery(tx *sql.Tx, entity *T) sq.InsertBuilder {
+	now := time.Now()
+	r.setTime(entity, createdAtColumn, now, false)
+	r.setTime(entity, updatedAtColumn, now, true)
+
+	columns, values := r.values(entity)
+	return r.Builder(tx).
+		Insert(r.config.Table).
+		Columns(columns...).
+		Values(values...)
+}
+
+// Insert inserts entity, then sets its fields to the inserted row, e.g. its generated ID.
+func (r *Repository[T]) Insert(ctx context.Context, tx *sql.Tx, entity *T) error {
+	err := r.insertQuery(tx, entity).
+		Suffix(r.returning()).
+		QueryRowContext(ctx).
+		Scan(r.fields(entity)...)
+	if err != nil {
+		return fmt.Errorf("failed to insert into %s: %w", r.config.Table, err)
+	}
+	return nil
+}
+
+func (r *Repository[T]) updateQuery(tx *sql.Tx, entity *T) sq.UpdateBuilder {
+	r.setTime(entity, updatedAtColumn, time.Now(), true)
+
+	id, _ := r.field(entity, r.config.PrimaryKey)
+	builder := r.Builder(tx).
+		Update(r.config.Table).
+		Where(sq.Eq{r.config.PrimaryKey: id.Interface()}).
+		Where(r.notDeleted())
+	value := reflect.ValueOf(entity).Elem()
+	for _, c := range r.columns {
+		if r.updatable(c) {
+			builder = builder.Set(c.name, value.FieldByIndex(c.index).Interface())
+		}
+	}
+	return builder
+}
+
+// Update writes entity over the row with its primary key, except for created_at, or returns
+// ErrNotFound.
+func (r *Repository[T]) Update(ctx context.Context, tx *sql.Tx, entity *T) error {
+	err := r.updateQuery(tx, entity).Suffix(r.returning()).QueryRowContext(ctx).Scan(r.fields(entity)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", r.config.Table, err)
+	}
+	return nil
+}
+
+func (r *Repository[T]) upsertQuery(tx *sql.Tx, entity *T) sq.InsertBuilder {
+	builder := r.insertQuery(tx, entity)
+
+	columns, _ := r.values(entity)
+	var updates []string
+	for _, column := range columns {
+		if r.updatable(r.byName[column]) {
+			updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", column, column))
+		}
+	}
+	if len(updates) == 0 {
+		// DO NOTHING would not return the existing row, so it is updated without change
+		updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", r.config.PrimaryKey, r.config.PrimaryKey))
+	}
+	action := "DO UPDATE SET " + strings.Join(updates, ", ")
+	// a soft deleted row stays deleted, and is not returned
+	if r.config.SoftDelete {
+		action += fmt.Sprintf(" WHERE %s.%s IS NULL", r.config.Table, deletedAtColumn)
+	}
+	return builder.Suffix(fmt.Sprintf("ON CONFLICT (%s) %s %s", r.config.PrimaryKey, action, r.returning()))
+}
+
+// Upsert inserts entity, or updates the row with its primary key as Update does, then sets
+// its fields to the written row. It returns ErrNotFound when the row is soft deleted.
+func (r *Repository[T]) Upsert(ctx context.Context, tx *sql.Tx, entity *T) error {
+	err := r.upsertQuery(tx, entity).QueryRowContext(ctx).Scan(r.fields(entity)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to upsert into %s: %w", r.config.Table, err)
+	}
+	return nil
+}
+
+// execSqlizer is the UpdateBuilder or DeleteBuilder of Delete.
+type execSqlizer interface {
+	sq.Sqlizer
+	ExecContext(ctx context.Context) (sql.Result, error)
+}
+
+func (r *Repository[T]) deleteQuery(tx *sql.Tx, id any) execSqlizer {
+	where := sq.Eq{r.config.PrimaryKey: id}
+	if r.config.SoftDelete {
+		now := time.Now()
+		builder := r.Builder(tx).
+			Update(r.config.Table).
+			Set(deletedAtColumn, now)
+		if _, ok := r.byName[updatedAtColumn]; ok {
+			builder = builder.Set(updatedAtColumn, now)
+		}
+		return builder.Where(where).Where(r.notDeleted())
+	}
+	return r.Builder(tx).Delete(r.config.Table).Where(where)
+}
+
+// Delete deletes the row with the given primary key, or soft deletes it, or returns
+// ErrNotFound.
+func (r *Repository[T]) Delete(ctx context.Context, tx *sql.Tx, id any) error {
+	result, err := r.deleteQuery(tx, id).ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete %v from %s: %w", id, r.config.Table, err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *Repository[T]) selectQuery(tx *sql.Tx, filter sq.Sqlizer, options FindOptions) sq.SelectBuilder {
+	builder := r.Builder(tx).
+		Select(r.columnNames()...).
+		From(r.config.Table)
+	if filter != nil {
+		builder = builder.Where(filter)
+	}
+	if !options.WithDeleted {
+		builder = builder.Where(r.notDeleted())
+	}
+	if len(options.OrderBy) > 0 {
+		builder = builder.OrderBy(options.OrderBy...)
+	}
+	if options.Limit > 0 {
+		builder = builder.Limit(options.Limit)
+	}
+	if options.Offset > 0 {
+		builder = builder.Offset(options.Offset)
+	}
+	return builder
+}
+
+// FindByID returns the row with the given primary key, or ErrNotFound.
+func (r *Repository[T]) FindByID(ctx context.Context, tx *sql.Tx, id any) (*T, error) {
+	entity := new(T)
+	err := r.selectQuery(tx, sq.Eq{r.config.PrimaryKey: id}, FindOptions{}).
+		QueryRowContext(ctx).
+		Scan(r.fields(entity)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find %v in %s: %w", id, r.config.Table, err)
+	}
+	return entity, nil
+}
+
+// validateOrderBy checks that orderBy only names mapped columns, so it cannot inject SQL.
+func (r *Repository[T]) validateOrderBy(orderBy []string) error {
+	for _, order := range orderBy {
+		column, direction, _ := strings.Cut(strings.TrimSpace(order), " ")
+		direction = strings.TrimSpace(direction)
+		_, mapped := r.byName[column]
+		if !mapped || (direction != "" && !strings.EqualFold(direction, "ASC") && !strings.EqualFold(direction, "DESC")) {
+			return fmt.Errorf("%w: %q is not a column of %s optionally followed by ASC or DESC", ErrInvalidOrderBy, order, r.config.Table)
+		}
+	}
+	return nil
+}
+
+// FindMany returns the rows matching filter, e.g. sq.Eq{"status": "active"}, all of them
+// when nil. It returns ErrInvalidOrderBy when options.OrderBy is not made of mapped columns.
+func (r *Repository[T]) FindMany(ctx context.Context, tx *sql.Tx, filter sq.Sqlizer, options FindOptions) ([]*T, error) {
+	if err := r.validateOrderBy(options.OrderBy); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.selectQuery(tx, filter, options).QueryContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find in %s: %w", r.config.Table, err)
+	}
+	defer rows.Close()
+
+	var result []*T
+	for rows.Next() {
+		entity := new(T)
+		if err := rows.Scan(r.fields(entity)...); err != nil {
+			return nil, fmt.Errorf("failed to scan row of %s: %w", r.config.Table, err)
+		}
+		result = append(result, entity)
+	}
+	return result, rows.Err()
+}
+
+// Count returns the number of rows matching filter, ignoring soft deleted ones.
+func (r *Repository[T]) Count(ctx context.Context, tx *sql.Tx, filter sq.Sqlizer) (uint64, error) {
+	builder := r.Builder(tx).
+		Select("COUNT(*)").
+		From(r.config.Table).
+		Where(r.notDeleted())
+	if filter != nil {
+		builder = builder.Where(filter)
+	}
+
+	var count uint64
+	if err := builder.QueryRowContext(ctx).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count rows of %s: %w", r.config.Table, err)
+	}
+	return count, nil
+}
@@ -0,0 +1,260 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeStorage struct{}
+
+func (fakeStorage) DB() *sql.DB                    { return nil }
+func (fakeStorage) Ping(ctx context.Context) error { return nil }
+func (fakeStorage) Close() error                   { return nil }
+
+type timestamps struct {
+	CreatedAt time.Time  `db:"created_at"`
+	UpdatedAt time.Time  `db:"updated_at"`
+	DeletedAt *time.Time `db:"deleted_at"`
+}
+
+type user struct {
+	ID    int64  `db:"id,generated"`
+	Email string `db:"email"`
+	Name  string `db:"name"`
+	Notes string
+	timestamps
+}
+
+func newUserRepository(t *testing.T, softDelete bool) *Repository[user] {
+	repo, err := NewRepository[user](fakeStorage{}, RepositoryConfig{Table: "users", SoftDelete: softDelete})
+	assert.NoError(t, err)
+	return repo
+}
+
+func TestNewRepository(t *testing.T) {
+	type noKey struct {
+		Email string `db:"email"`
+	}
+	type nonNullableDeletedAt struct {
+		ID        string    `db:"id"`
+		DeletedAt time.Time `db:"deleted_at"`
+	}
+	type duplicate struct {
+		ID    string `db:"id"`
+		Email string `db:"id"`
+	}
+
+	_, err := NewRepository[user](fakeStorage{}, RepositoryConfig{})
+	assert.Error(t, err)
+	_, err = NewRepository[noKey](fakeStorage{}, RepositoryConfig{Table: "users"})
+	assert.Error(t, err)
+	_, err = NewRepository[nonNullableDeletedAt](fakeStorage{}, RepositoryConfig{Table: "users", SoftDelete: true})
+	assert.Error(t, err)
+	_, err = NewRepository[duplicate](fakeStorage{}, RepositoryConfig{Table: "users"})
+	assert.Error(t, err)
+	_, err = NewRepository[noKey](fakeStorage{}, RepositoryConfig{Table: "users", PrimaryKey: "email"})
+	assert.NoError(t, err)
+
+	repo := newUserRepository(t, true)
+	assert.Equal(t, []string{"id", "email", "name", "created_at", "updated_at", "deleted_at"}, repo.columnNames())
+}
+
+func TestRepositoryInsertQuery(t *testing.T) {
+	repo := newUserRepository(t, false)
+	createdAt := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	entity := &user{Email: "john@example.com", Name: "John"}
+	query, args, err := repo.insertQuery(nil, entity).ToSql()
+	assert.NoError(t, err)
+	assert.Equal(t, "INSERT INTO users (email,name,created_at,updated_at,deleted_at) VALUES ($1,$2,$3,$4,$5)", query)
+	assert.Len(t, args, 5)
+	assert.False(t, entity.CreatedAt.IsZero())
+	assert.Equal(t, entity.CreatedAt, entity.UpdatedAt)
+
+	entity = &user{ID: 42, Email: "john@example.com", timestamps: timestamps{CreatedAt: createdAt}}
+	query, args, err = repo.insertQuery(nil, entity).ToSql()
+	assert.NoError(t, err)
+	assert.Equal(t, "INSERT INTO users (id,email,name,created_at,updated_at,deleted_at) VALUES ($1,$2,$3,$4,$5,$6)", query)
+	assert.Equal(t, int64(42), args[0])
+	assert.Equal(t, createdAt, entity.CreatedAt, "a set created_at is kept")
+	assert.True(t, entity.UpdatedAt.After(createdAt))
+}
+
+func TestRepositoryUpdateQuery(t *testing.T) {
+	testCases := []struct {
+		name       string
+		softDelete bool
+		query      string
+	}{
+		{
+			name:  "hard delete",
+			query: "UPDATE users SET email = $1, name = $2, updated_at = $3 WHERE id = $4",
+		},
+		{
+			name:       "soft delete",
+			softDelete: true,
+			query:      "UPDATE users SET email = $1, name = $2, updated_at = $3 WHERE id = $4 AND deleted_at IS NULL",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newUserRepository(t, tc.softDelete)
+			query, args, err := repo.updateQuery(nil, &user{ID: 42, Email: "john@example.com", Name: "John"}).ToSql()
+			assert.NoError(t, err)
+			assert.Equal(t, tc.query, query)
+			assert.Equal(t, []any{"john@example.com", "John", args[2], int64(42)}, args)
+		})
+	}
+}
+
+type article struct {
+	ID        string    `db:"id"`
+	Title     string    `db:"title"`
+	Slug      string    `db:"slug,generated"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+func TestRepositoryGeneratedColumnsNotUpdated(t *testing.T) {
+	repo, err := NewRepository[article](fakeStorage{}, RepositoryConfig{Table: "articles"})
+	assert.NoError(t, err)
+	entity := &article{ID: "article-1", Title: "Hello", Slug: "hello"}
+
+	query, _, err := repo.updateQuery(nil, entity).ToSql()
+	assert.NoError(t, err)
+	assert.Equal(t, "UPDATE articles SET title = $1, updated_at = $2 WHERE id = $3", query)
+
+	query, _, err = repo.upsertQuery(nil, entity).ToSql()
+	assert.NoError(t, err)
+	assert.Equal(t, "INSERT INTO articles (id,title,slug,updated_at) VALUES ($1,$2,$3,$4) "+
+		"ON CONFLICT (id) DO UPDATE SET title = EXCLUDED.title, updated_at = EXCLUDED.updated_at "+
+		"RETURNING id, title, slug, updated_at", query)
+}
+
+func TestRepositoryUpsertQuery(t *testing.T) {
+	repo := newUserRepository(t, true)
+	query, _, err := repo.upsertQuery(nil, &user{ID: 42, Email: "john@example.com"}).ToSql()
+	assert.NoError(t, err)
+	assert.Equal(t, "INSERT INTO users (id,email,name,created_at,updated_at,deleted_at) VALUES ($1,$2,$3,$4,$5,$6) "+
+		"ON CONFLICT (id) DO UPDATE SET email = EXCLUDED.email, name = EXCLUDED.name, updated_at = EXCLUDED.updated_at WHERE users.deleted_at IS NULL "+
+		"RETURNING id, email, name, created_at, updated_at, deleted_at", query)
+}
+
+func TestRepositoryUpsertQueryWithoutUpdatableColumns(t *testing.T) {
+	type membership struct {
+		ID        string     `db:"id"`
+		Number    int64      `db:"number,generated"`
+		CreatedAt time.Time  `db:"created_at"`
+		DeletedAt *time.Time `db:"deleted_at"`
+	}
+
+	testCases := []struct {
+		name       string
+		softDelete bool
+		query      string
+	}{
+		{
+			name: "hard delete",
+			query: "INSERT INTO memberships (id,created_at,deleted_at) VALUES ($1,$2,$3) " +
+				"ON CONFLICT (id) DO UPDATE SET id = EXCLUDED.id RETURNING id, number, created_at, deleted_at",
+		},
+		{
+			name:       "soft delete",
+			softDelete: true,
+			query: "INSERT INTO memberships (id,created_at,deleted_at) VALUES ($1,$2,$3) " +
+				"ON CONFLICT (id) DO UPDATE SET id = EXCLUDED.id WHERE memberships.deleted_at IS NULL RETURNING id, number, created_at, deleted_at",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			repo, err := NewRepository[membership](fakeStorage{}, RepositoryConfig{Table: "memberships", SoftDelete: tc.softDelete})
+			assert.NoError(t, err)
+			query, _, err := repo.upsertQuery(nil, &membership{ID: "membership-1"}).ToSql()
+			assert.NoError(t, err)
+			assert.Equal(t, tc.query, query, "an existing row is returned rather than skipped")
+		})
+	}
+}
+
+func TestRepositoryDeleteQuery(t *testing.T) {
+	query, args, err := newUserRepository(t, false).deleteQuery(nil, 42).ToSql()
+	assert.NoError(t, err)
+	assert.Equal(t, "DELETE FROM users WHERE id = $1", query)
+	assert.Equal(t, []any{42}, args)
+
+	query, args, err = newUserRepository(t, true).deleteQuery(nil, 42).ToSql()
+	assert.NoError(t, err)
+	assert.Equal(t, "UPDATE users SET deleted_at = $1, updated_at = $2 WHERE id = $3 AND deleted_at IS NULL", query)
+	assert.Equal(t, args[0], args[1], "updated_at is the deletion time")
+	assert.Equal(t, 42, args[2])
+}
+
+func TestRepositorySelectQuery(t *testing.T) {
+	testCases := []struct {
+		name    string
+		filter  sq.Sqlizer
+		options FindOptions
+		query   string
+	}{
+		{
+			name:  "all",
+			query: "SELECT id, email, name, created_at, updated_at, deleted_at FROM users WHERE deleted_at IS NULL",
+		},
+		{
+			name:    "filtered and paginated",
+			filter:  sq.And{sq.Eq{"name": "John"}, sq.Like{"email": "%@example.com"}},
+			options: FindOptions{OrderBy: []string{"created_at DESC"}, Limit: 20, Offset: 40},
+			query:   "SELECT id, email, name, created_at, updated_at, deleted_at FROM users WHERE (name = $1 AND email LIKE $2) AND deleted_at IS NULL ORDER BY created_at DESC LIMIT 20 OFFSET 40",
+		},
+		{
+			name:    "with deleted",
+			filter:  sq.Eq{"name": "John"},
+			options: FindOptions{WithDeleted: true},
+			query:   "SELECT id, email, name, created_at, updated_at, deleted_at FROM users WHERE name = $1",
+		},
+	}
+
+	repo := newUserRepository(t, true)
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			query, _, err := repo.selectQuery(nil, tc.filter, tc.options).ToSql()
+			assert.NoError(t, err)
+			assert.Equal(t, tc.query, query)
+		})
+	}
+}
+
+func TestRepositoryValidateOrderBy(t *testing.T) {
+	repo := newUserRepository(t, true)
+
+	assert.NoError(t, repo.validateOrderBy(nil))
+	assert.NoError(t, repo.validateOrderBy([]string{"created_at DESC", "name", "id asc"}))
+	for _, orderBy := range []string{"notes", "name DESC NULLS LAST", "name; DROP TABLE users", "(SELECT 1)", ""} {
+		assert.ErrorIs(t, repo.validateOrderBy([]string{orderBy}), ErrInvalidOrderBy, orderBy)
+	}
+
+	_, err := repo.FindMany(context.Background(), nil, nil, FindOptions{OrderBy: []string{"email; --"}})
+	assert.ErrorIs(t, err, ErrInvalidOrderBy)
+}
+
+func TestRepositoryFields(t *testing.T) {
+	repo := newUserRepository(t, true)
+	entity := &user{}
+	fields := repo.fields(entity)
+	assert.Len(t, fields, 6)
+
+	*fields[1].(*string) = "john@example.com"
+	deletedAt := time.Now()
+	*fields[5].(**time.Time) = &deletedAt
+	assert.Equal(t, "john@example.com", entity.Email)
+	assert.Equal(t, &deletedAt, entity.DeletedAt)
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"slices"
+	"strings"
 
+	"github.com/NusaCrew/atlas-go/apperror"
 	api_v1 "github.com/NusaCrew/atlas-go/example/protos/api/v1"
 	"github.com/NusaCrew/atlas-go/log"
 
@@ -19,9 +22,11 @@ type httpServer struct {
 	mux      *http.ServeMux
 }
 
+// AllowCorrelationID forwards the correlation id and trace context headers to the gRPC
+// server, so logs of both servers can be correlated.
 func AllowCorrelationID(key string) (string, bool) {
-	if key == "correlation-id" {
-		return key, true
+	if slices.Contains(log.TraceHeaders, strings.ToLower(key)) {
+		return strings.ToLower(key), true
 	}
 	return runtime.DefaultHeaderMatcher(key)
 }
@@ -92,6 +97,7 @@ func corsMiddleware(config HTTPWebServerConfig) func(http.Handler) http.Handler
 func NewHTTPWebServer(ctx context.Context, config HTTPWebServerConfig) (WebServer, error) {
 	sMux := runtime.NewServeMux(
 		runtime.WithIncomingHeaderMatcher(AllowCorrelationID),
+		runtime.WithErrorHandler(apperror.GatewayErrorHandler),
 		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
 			MarshalOptions: protojson.MarshalOptions{
 				UseProtoNames: true,
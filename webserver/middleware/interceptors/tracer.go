@@ -17,7 +17,10 @@ func (i *GRPCInterceptorWithTracer) Intercept(ctx context.Context, req any, info
 	methods := strings.Split(info.FullMethod, "/")
 	methodName := methods[len(methods)-1]
 
-	tracer := log.NewTracer(ctx, methodName, i.ServiceName)
+	// handlers get a request scoped logger through log.FromContext
+	ctx = log.NewContext(ctx, log.FromContext(ctx).WithField("method", methodName))
+
+	tracer := log.NewTracer(ctx, methodName, i.ServiceName).WithURI(info.FullMethod)
 	resp, err := handler(ctx, req)
 
 	tracer.TraceResponse(err)
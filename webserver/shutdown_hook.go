@@ -0,0 +1,47 @@
+package webserver
+
+import (
+	"context"
+	"time"
+
+	"github.com/NusaCrew/atlas-go/log"
+)
+
+// Closer is implemented by components draining their work on shutdown, like the EventObserver.
+type Closer interface {
+	Close(ctx context.Context) error
+}
+
+type shutdownHook struct {
+	name    string
+	timeout time.Duration
+	closer  Closer
+}
+
+// NewShutdownHook returns a WebServer closing closer, with up to timeout to drain, when
+// RunServersCommand stops its servers. Servers are stopped in order, so pass the hook after
+// the servers whose requests may still use the closer.
+func NewShutdownHook(name string, timeout time.Duration, closer Closer) WebServer {
+	return &shutdownHook{
+		name:    name,
+		timeout: timeout,
+		closer:  closer,
+	}
+}
+
+func (h *shutdownHook) Run(ctx context.Context, errorChannel chan error) {}
+
+func (h *shutdownHook) GetName() string {
+	return h.name
+}
+
+func (h *shutdownHook) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+
+	if err := h.closer.Close(ctx); err != nil {
+		log.WithError(err).Error("failed to close %s", h.name)
+		return
+	}
+	log.Info("closed %s", h.name)
+}